@@ -4,11 +4,15 @@ import "github.com/ear7h/lang/ast/parser"
 
 type Node interface {
 	FileInfo() parser.FileInfo
+	End() parser.FileInfo
 	setFi(parser.FileInfo)
 }
 
+// BaseNode holds the span of a node, from the position of its first
+// rune to the position just after its last
 type BaseNode struct {
-	Fi parser.FileInfo
+	Fi    parser.FileInfo
+	EndFi parser.FileInfo
 }
 
 type fileInfoSetter struct {
@@ -20,6 +24,10 @@ func (bn *BaseNode) FileInfo() parser.FileInfo {
 	return bn.Fi
 }
 
+func (bn *BaseNode) End() parser.FileInfo {
+	return bn.EndFi
+}
+
 func (bn *BaseNode) setFi(fi parser.FileInfo) {
 	bn.Fi = fi
 }
@@ -28,6 +36,11 @@ func (bn *BaseNode) setFileInfo(c *parser.Cursor) {
 	bn.Fi = c.FileInfo()
 }
 
+// setEnd sets the end of the node to the end of the input c has read
+func (bn *BaseNode) setEnd(c *parser.Cursor) {
+	bn.EndFi = c.End()
+}
+
 /*
 func (bn *BaseNode) Parse(c *Cursor) {
 	bn.Fi = c.FileInfo()
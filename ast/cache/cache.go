@@ -0,0 +1,87 @@
+// Package cache keeps the trees of parsed files on disk, so files which
+// haven't changed don't have to be parsed again.
+package cache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/ear7h/lang/ast"
+)
+
+// Cache is a directory of trees in the binary encoding of ast.Encode,
+// keyed by the hash of the file name and source they were parsed from.
+// Entries are never invalidated, a changed file has a different key.
+type Cache struct {
+	Dir string
+}
+
+// New returns a cache in the directory dir, creating it if needed
+func New(dir string) (*Cache, error) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Cache{Dir: dir}, nil
+}
+
+// Path returns the file the tree of src, the source of the file name, is
+// cached in
+func (c *Cache) Path(name, src string) string {
+	h := sha256.New()
+	// the positions in the tree have the name
+	h.Write([]byte(strconv.Quote(name)))
+	h.Write([]byte(src))
+
+	return filepath.Join(c.Dir, hex.EncodeToString(h.Sum(nil))+".ast")
+}
+
+// ParseFile is like ast.ParseFile, but returns the cached tree if there
+// is one. Files which parse without errors are added to the cache,
+// failing to write them is not an error.
+func (c *Cache) ParseFile(name, src string) (*ast.File, error) {
+	path := c.Path(name, src)
+
+	if b, err := os.ReadFile(path); err == nil {
+		n, err := ast.Decode(bytes.NewReader(b))
+		if f, ok := n.(*ast.File); ok && err == nil {
+			return f, nil
+		}
+	}
+
+	f, err := ast.ParseFile(name, src)
+	if err != nil {
+		return f, err
+	}
+
+	c.write(path, f)
+
+	return f, nil
+}
+
+// write writes the tree f to path, through a temporary file so readers
+// never see a partial tree
+func (c *Cache) write(path string, f *ast.File) error {
+	tmp, err := os.CreateTemp(c.Dir, "tmp-*")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(tmp.Name())
+
+	err = ast.Encode(tmp, f)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
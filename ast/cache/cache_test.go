@@ -0,0 +1,77 @@
+package cache_test
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/ear7h/lang/ast"
+	"github.com/ear7h/lang/ast/cache"
+	"github.com/ear7h/lang/internal/assert"
+)
+
+func TestParseFile(t *testing.T) {
+	c, err := cache.New(t.TempDir())
+	assert.Eq(t, nil, err)
+
+	src := "a + b\nreturn 1"
+
+	f, err := c.ParseFile("test", src)
+	assert.Eq(t, nil, err)
+
+	_, err = os.Stat(c.Path("test", src))
+	assert.Eq(t, nil, err)
+
+	// the same tree from the cache
+	f1, err := c.ParseFile("test", src)
+	assert.Eq(t, nil, err)
+	assert.Eq(t, f, f1)
+
+	// the tree is read from the cache, not parsed
+	other, _ := ast.ParseFile("test", "c")
+	var b bytes.Buffer
+	err = ast.Encode(&b, other)
+	assert.Eq(t, nil, err)
+	err = os.WriteFile(c.Path("test", src), b.Bytes(), 0644)
+	assert.Eq(t, nil, err)
+
+	f1, err = c.ParseFile("test", src)
+	assert.Eq(t, nil, err)
+	assert.Eq(t, other, f1)
+
+	// broken entries are parsed again
+	err = os.WriteFile(c.Path("test", src), []byte("last"), 0644)
+	assert.Eq(t, nil, err)
+
+	f1, err = c.ParseFile("test", src)
+	assert.Eq(t, nil, err)
+	assert.Eq(t, f, f1)
+}
+
+func TestParseFileErrors(t *testing.T) {
+	c, err := cache.New(t.TempDir())
+	assert.Eq(t, nil, err)
+
+	src := "a +"
+
+	expect, expectErr := ast.ParseFile("test", src)
+
+	f, err := c.ParseFile("test", src)
+	assert.Eq(t, expectErr.Error(), err.Error())
+	assert.Eq(t, expect, f)
+
+	_, err = os.Stat(c.Path("test", src))
+	assert.Eq(t, true, os.IsNotExist(err))
+}
+
+func TestPath(t *testing.T) {
+	c := &cache.Cache{Dir: "dir"}
+
+	if c.Path("a", "b") == c.Path("a", "c") ||
+		c.Path("a", "b") == c.Path("b", "b") ||
+		c.Path("a", "bc") == c.Path("ab", "c") {
+		t.Fatal("paths collide")
+	}
+
+	assert.Eq(t, c.Path("a", "b"), c.Path("a", "b"))
+}
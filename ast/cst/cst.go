@@ -0,0 +1,159 @@
+// Package cst is a lossless concrete syntax tree. It pairs the nodes of
+// an ast.File with the tokens they were parsed from, trivia included, so
+// the source can be printed back byte for byte and the text of any node
+// can be found, or edited in place.
+package cst
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/ear7h/lang/ast"
+	"github.com/ear7h/lang/ast/parser"
+	"github.com/ear7h/lang/ast/token"
+)
+
+// Tree is the concrete syntax tree of a file
+type Tree struct {
+	Root   *Node
+	Tokens []token.Token
+
+	offs  []int64 // the offset of each token's value
+	nodes map[ast.Node]*Node
+}
+
+// Node is a node of the tree and the tokens it spans. The trivia
+// before its first token and after its last belong to the node too,
+// but are left out of its Text.
+type Node struct {
+	Node     ast.Node
+	Tokens   []token.Token
+	Children []*Node
+
+	start, end int // the span of Tokens in the tree
+}
+
+// Parse parses the source of a file into a tree. The error is like the
+// one from token.ParseFile, the tree is still returned with the bad
+// nodes in it.
+func Parse(name, src string) (*Tree, error) {
+	f, toks, err := token.ParseFileTrivia(name, src)
+	if f == nil {
+		return nil, err
+	}
+
+	t := &Tree{
+		Tokens: toks,
+		offs:   make([]int64, len(toks)),
+		nodes:  map[ast.Node]*Node{},
+	}
+
+	off := int64(0)
+	for i, v := range toks {
+		t.offs[i] = off + int64(len(v.Leading))
+		off += int64(len(v.Source()))
+	}
+
+	t.Root = t.node(f, 0, len(toks))
+	t.Root.Children = t.children(f)
+
+	return t, err
+}
+
+// node returns the node for n with the tokens from start to end
+func (t *Tree) node(n ast.Node, start, end int) *Node {
+	ret := &Node{
+		Node:   n,
+		Tokens: t.Tokens[start:end:end],
+		start:  start,
+		end:    end,
+	}
+
+	t.nodes[n] = ret
+
+	return ret
+}
+
+// children returns the nodes for the children of n
+func (t *Tree) children(n ast.Node) []*Node {
+	var ret []*Node
+
+	ast.Inspect(n, func(m ast.Node) bool {
+		if m == n {
+			return true
+		}
+
+		child := t.node(m, t.search(m.FileInfo()), t.search(m.End()))
+		child.Children = t.children(m)
+		ret = append(ret, child)
+
+		return false
+	})
+
+	return ret
+}
+
+// search returns the index of the first token at or after fi
+func (t *Tree) search(fi parser.FileInfo) int {
+	return sort.Search(len(t.Tokens), func(i int) bool {
+		tfi := t.Tokens[i].Fi
+		return tfi.Line > fi.Line || tfi.Line == fi.Line && tfi.Col >= fi.Col
+	})
+}
+
+// Lookup returns the tree node of n, or nil if n isn't in the tree
+func (t *Tree) Lookup(n ast.Node) *Node {
+	return t.nodes[n]
+}
+
+// String returns the source of the tree
+func (t *Tree) String() string {
+	return source(t.Tokens)
+}
+
+// Edit returns the edit replacing the text of n with text, the result
+// can be given to ast.Reparse along with the source of the tree
+func (t *Tree) Edit(n *Node, text string) ast.Edit {
+	off := t.offs[len(t.offs)-1]
+	if n.start < len(t.offs) {
+		off = t.offs[n.start]
+	}
+
+	return ast.Edit{
+		Off: off,
+		Del: int64(len(n.Text())),
+		Ins: text,
+	}
+}
+
+// Source returns the text of the node with its trivia
+func (n *Node) Source() string {
+	return source(n.Tokens)
+}
+
+// Text returns the text of the node, from the start of its first token
+// to the end of its last
+func (n *Node) Text() string {
+	toks := n.Tokens
+	if len(toks) > 0 && toks[len(toks)-1].Kind == token.EOF {
+		toks = toks[:len(toks)-1]
+	}
+
+	if len(toks) == 0 {
+		return ""
+	}
+
+	s := source(toks)
+	s = s[len(toks[0].Leading):]
+
+	return s[:len(s)-len(toks[len(toks)-1].Trailing)]
+}
+
+func source(toks []token.Token) string {
+	var b strings.Builder
+	for _, v := range toks {
+		b.WriteString(v.Source())
+	}
+
+	return b.String()
+}
@@ -0,0 +1,127 @@
+package cst_test
+
+import (
+	"testing"
+
+	"github.com/ear7h/lang/ast"
+	"github.com/ear7h/lang/ast/cst"
+	"github.com/ear7h/lang/internal/assert"
+)
+
+func TestString(t *testing.T) {
+	tcases := map[string]string{
+		"empty":      "",
+		"spaces":     " \n\t \n",
+		"stmts":      "a + b;c\n\n  return  1 \n",
+		"crlf":       "a\r\n\r\nb +\r\n c\r\n",
+		"strings":    `"a  b" + "\n"` + "\n",
+		"comments":   "a $ b\n",
+		"errors":     "1 + ;\n(2 + ) * 3\n",
+		"no newline": "x.y.z",
+	}
+
+	for k, v := range tcases {
+		src := v
+		t.Run(k, func(t *testing.T) {
+			tree, _ := cst.Parse("test", src)
+			assert.Eq(t, src, tree.String())
+		})
+	}
+}
+
+func TestText(t *testing.T) {
+	type tcase struct {
+		str  string
+		text []string // the text of the nodes, in preorder
+	}
+
+	fn := func(tc tcase) func(t *testing.T) {
+		return func(t *testing.T) {
+			tree, _ := cst.Parse("test", tc.str)
+
+			var (
+				text []string
+				walk func(n *cst.Node)
+			)
+			walk = func(n *cst.Node) {
+				text = append(text, n.Text())
+				for _, v := range n.Children {
+					walk(v)
+				}
+			}
+			walk(tree.Root)
+
+			assert.Eq(t, tc.text, text)
+		}
+	}
+
+	tcases := map[string]tcase{
+		"binary": tcase{
+			str: " a +  b \n",
+			text: []string{
+				"a +  b \n",
+				"a +  b",
+				"a +  b",
+				"a",
+				"b",
+			},
+		},
+		"parens": tcase{
+			str: "(a) * b",
+			text: []string{
+				"(a) * b",
+				"(a) * b",
+				"(a) * b",
+				"a",
+				"b",
+			},
+		},
+		"lines": tcase{
+			str: "(1 +\n 2) ; return x\n",
+			text: []string{
+				"(1 +\n 2) ; return x\n",
+				"(1 +\n 2)",
+				"1 +\n 2",
+				"1",
+				"2",
+				"return x",
+				"x",
+			},
+		},
+		"bad": tcase{
+			str: "a +; b",
+			text: []string{
+				"a +; b",
+				"a +",
+				"b",
+				"b",
+			},
+		},
+	}
+
+	for k, v := range tcases {
+		t.Run(k, fn(v))
+	}
+}
+
+func TestEdit(t *testing.T) {
+	src := "a + b\nreturn  c * d \n"
+
+	tree, err := cst.Parse("test", src)
+	assert.Eq(t, nil, err)
+
+	f := tree.Root.Node.(*ast.File)
+	ret := f.Stmts[1].(*ast.ReturnStmt)
+	n := tree.Lookup(ret.X.(ast.Node))
+	assert.Eq(t, "c * d", n.Text())
+
+	e := tree.Edit(n, "(c)")
+	assert.Eq(t, "a + b\nreturn  (c) \n", e.Apply(src))
+
+	f, _, err = ast.Reparse(f, nil, src, e)
+	assert.Eq(t, nil, err)
+
+	expect, err := ast.ParseFile("test", e.Apply(src))
+	assert.Eq(t, nil, err)
+	assert.Eq(t, expect, f)
+}
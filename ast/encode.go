@@ -0,0 +1,377 @@
+package ast
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// EncodingVersion is the version of the binary encoding of trees,
+// Decode fails on other versions
+const EncodingVersion = 3
+
+// encodingMagic starts every encoded tree
+const encodingMagic = "last"
+
+// kindTags are the tags of the node kinds in the binary encoding, tag 0
+// is a nil node. Kinds must only be appended to keep the tags stable
+// within a version.
+var kindTags = []string{
+	"",
+	"File",
+	"ExprStmt",
+	"ReturnStmt",
+	"BadStmt",
+	"UnaryExpr",
+	"BinaryExpr",
+	"ObjExpr",
+	"Ident",
+	"NumberLiteral",
+	"StringLiteral",
+	"BadExpr",
+	"LetStmt",
+	"BlockStmt",
+	"FuncLit",
+}
+
+// Encode writes the binary encoding of the tree rooted at n to w. The
+// encoding starts with a header and the version, then all the strings
+// of the tree, each once, and then the nodes in preorder: a tag for the
+// kind of the node followed by its fields, with varint integers and
+// positions, and strings as indices into the table.
+func Encode(w io.Writer, n Node) error {
+	e := &encoder{strings: map[string]uint64{}}
+	err := e.node(n)
+	if err != nil {
+		return err
+	}
+
+	var b bytes.Buffer
+	b.WriteString(encodingMagic)
+	writeUvarint(&b, EncodingVersion)
+
+	writeUvarint(&b, uint64(len(e.table)))
+	for _, v := range e.table {
+		writeUvarint(&b, uint64(len(v)))
+		b.WriteString(v)
+	}
+
+	_, err = b.WriteTo(w)
+	if err != nil {
+		return err
+	}
+
+	_, err = e.body.WriteTo(w)
+	return err
+}
+
+type encoder struct {
+	body    bytes.Buffer
+	table   []string
+	strings map[string]uint64
+}
+
+func (e *encoder) string(s string) {
+	i, ok := e.strings[s]
+	if !ok {
+		i = uint64(len(e.table))
+		e.strings[s] = i
+		e.table = append(e.table, s)
+	}
+
+	writeUvarint(&e.body, i)
+}
+
+func (e *encoder) node(n interface{}) error {
+	if n == nil || isNil(n.(Node)) {
+		writeUvarint(&e.body, 0)
+		return nil
+	}
+
+	tag := kindTag(n.(Node))
+	if tag == 0 {
+		return fmt.Errorf("ast: cannot encode %T", n)
+	}
+
+	writeUvarint(&e.body, tag)
+
+	return e.fields(reflect.ValueOf(n).Elem())
+}
+
+func (e *encoder) fields(v reflect.Value) error {
+	return eachField(v, func(name string, f reflect.Value) error {
+		return e.value(f)
+	})
+}
+
+func (e *encoder) value(v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			return e.node(nil)
+		}
+
+		if _, ok := v.Interface().(Node); !ok {
+			return fmt.Errorf("ast: cannot encode %v", v.Elem().Type())
+		}
+
+		return e.node(v.Interface())
+
+	case reflect.Slice:
+		// 0 is a nil slice
+		if v.IsNil() {
+			writeUvarint(&e.body, 0)
+			return nil
+		}
+
+		writeUvarint(&e.body, uint64(v.Len())+1)
+		for i := 0; i < v.Len(); i++ {
+			err := e.value(v.Index(i))
+			if err != nil {
+				return err
+			}
+		}
+
+	case reflect.Ptr:
+		if v.IsNil() {
+			e.body.WriteByte(0)
+			return nil
+		}
+
+		e.body.WriteByte(1)
+		return e.value(v.Elem())
+
+	case reflect.Struct:
+		return e.fields(v)
+
+	case reflect.String:
+		e.string(v.String())
+
+	case reflect.Bool:
+		if v.Bool() {
+			e.body.WriteByte(1)
+		} else {
+			e.body.WriteByte(0)
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		writeVarint(&e.body, v.Int())
+
+	default:
+		return fmt.Errorf("ast: cannot encode %v", v.Type())
+	}
+
+	return nil
+}
+
+func kindTag(n Node) uint64 {
+	kind := Kind(n)
+	for i, v := range kindTags {
+		if v == kind && i > 0 {
+			return uint64(i)
+		}
+	}
+
+	return 0
+}
+
+func writeUvarint(b *bytes.Buffer, x uint64) {
+	var buf [binary.MaxVarintLen64]byte
+	b.Write(buf[:binary.PutUvarint(buf[:], x)])
+}
+
+func writeVarint(b *bytes.Buffer, x int64) {
+	var buf [binary.MaxVarintLen64]byte
+	b.Write(buf[:binary.PutVarint(buf[:], x)])
+}
+
+// ErrEncoding is returned by Decode for input which is not an encoded
+// tree of the current version
+var ErrEncoding = errors.New("ast: invalid encoding")
+
+// Decode reads a tree written by Encode
+func Decode(r io.Reader) (Node, error) {
+	d := &decoder{r: bufio.NewReader(r)}
+
+	magic := make([]byte, len(encodingMagic))
+	_, err := io.ReadFull(d.r, magic)
+	if err != nil || string(magic) != encodingMagic {
+		return nil, ErrEncoding
+	}
+
+	if version := d.uvarint(); d.err == nil && version != EncodingVersion {
+		return nil, fmt.Errorf("%w: version %d", ErrEncoding, version)
+	}
+
+	n := d.uvarint()
+	for i := uint64(0); i < n && d.err == nil; i++ {
+		d.table = append(d.table, d.bytes())
+	}
+
+	v := d.node()
+	if d.err != nil {
+		return nil, d.err
+	}
+
+	if v == nil {
+		return nil, fmt.Errorf("%w: nil root", ErrEncoding)
+	}
+
+	return v.(Node), nil
+}
+
+type decoder struct {
+	r     *bufio.Reader
+	table []string
+	err   error
+}
+
+// fail records the first error, the reads after it return zero values
+func (d *decoder) fail(err error) {
+	if d.err == nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+
+		d.err = fmt.Errorf("%w: %v", ErrEncoding, err)
+	}
+}
+
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+
+	x, err := binary.ReadUvarint(d.r)
+	if err != nil {
+		d.fail(err)
+	}
+
+	return x
+}
+
+func (d *decoder) varint() int64 {
+	if d.err != nil {
+		return 0
+	}
+
+	x, err := binary.ReadVarint(d.r)
+	if err != nil {
+		d.fail(err)
+	}
+
+	return x
+}
+
+func (d *decoder) byte() byte {
+	if d.err != nil {
+		return 0
+	}
+
+	b, err := d.r.ReadByte()
+	if err != nil {
+		d.fail(err)
+	}
+
+	return b
+}
+
+func (d *decoder) bytes() string {
+	n := d.uvarint()
+	if d.err != nil {
+		return ""
+	}
+
+	var b bytes.Buffer
+	_, err := io.CopyN(&b, d.r, int64(n))
+	if err != nil {
+		d.fail(err)
+	}
+
+	return b.String()
+}
+
+func (d *decoder) string() string {
+	i := d.uvarint()
+	if d.err == nil && i >= uint64(len(d.table)) {
+		d.fail(fmt.Errorf("string %d out of range", i))
+	}
+
+	if d.err != nil {
+		return ""
+	}
+
+	return d.table[i]
+}
+
+func (d *decoder) node() interface{} {
+	tag := d.uvarint()
+	if d.err != nil || tag == 0 {
+		return nil
+	}
+
+	if tag >= uint64(len(kindTags)) {
+		d.fail(fmt.Errorf("unknown tag %d", tag))
+		return nil
+	}
+
+	n := nodeKinds[kindTags[tag]]()
+	eachField(reflect.ValueOf(n).Elem(), func(name string, f reflect.Value) error {
+		d.value(f)
+		return d.err
+	})
+
+	return n
+}
+
+func (d *decoder) value(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Interface:
+		if n := d.node(); n != nil {
+			v.Set(reflect.ValueOf(n))
+		}
+
+	case reflect.Slice:
+		n := d.uvarint()
+		if d.err != nil || n == 0 {
+			return
+		}
+
+		s := reflect.MakeSlice(v.Type(), 0, 0)
+		for i := uint64(0); i < n-1 && d.err == nil; i++ {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			d.value(elem)
+			s = reflect.Append(s, elem)
+		}
+
+		v.Set(s)
+
+	case reflect.Ptr:
+		if d.byte() == 0 {
+			return
+		}
+
+		p := reflect.New(v.Type().Elem())
+		d.value(p.Elem())
+		v.Set(p)
+
+	case reflect.Struct:
+		eachField(v, func(name string, f reflect.Value) error {
+			d.value(f)
+			return d.err
+		})
+
+	case reflect.String:
+		v.SetString(d.string())
+
+	case reflect.Bool:
+		v.SetBool(d.byte() != 0)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(d.varint())
+	}
+}
@@ -0,0 +1,91 @@
+package ast_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/ear7h/lang/ast"
+	"github.com/ear7h/lang/internal/assert"
+)
+
+func TestEncodeRoundTrip(t *testing.T) {
+	tcases := map[string]string{
+		"empty":    "",
+		"binary":   "1 + 2 * 3 - (4 / a)",
+		"unary":    "-x; !(&y)",
+		"objects":  "a.b.c + (d).e",
+		"strings":  `"a\tb" + "\\" + "a\tb"`,
+		"return":   "return\nreturn -1",
+		"unicode":  "héllo + 1",
+		"bad stmt": "1 +\n2",
+		"bad expr": "(1 + ) * 2",
+		"funcs":    "let f = fn(a, b) { return a(b) }\n{ f(1, fn() {}).x }",
+	}
+
+	for k, v := range tcases {
+		src := v
+		t.Run(k, func(t *testing.T) {
+			f, _ := ast.ParseFile("test", src)
+
+			var b bytes.Buffer
+			err := ast.Encode(&b, f)
+			assert.Eq(t, nil, err)
+
+			n, err := ast.Decode(&b)
+			assert.Eq(t, nil, err)
+
+			assert.DeepEq(t, f, n)
+		})
+	}
+}
+
+func TestEncodeSize(t *testing.T) {
+	f, _ := ast.ParseFile("test", "abc + abc * abc - abc.abc")
+
+	var b bytes.Buffer
+	err := ast.Encode(&b, f)
+	assert.Eq(t, nil, err)
+
+	js, err := ast.MarshalJSON(f)
+	assert.Eq(t, nil, err)
+
+	if b.Len()*4 > len(js) {
+		t.Fatalf("encoding is %d bytes, the json %d", b.Len(), len(js))
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	f, _ := ast.ParseFile("test", "a + 1; return \"b\"")
+
+	var b bytes.Buffer
+	err := ast.Encode(&b, f)
+	assert.Eq(t, nil, err)
+	enc := b.Bytes()
+
+	// every truncation fails cleanly
+	for i := 0; i < len(enc); i++ {
+		_, err := ast.Decode(bytes.NewReader(enc[:i]))
+		if !errors.Is(err, ast.ErrEncoding) {
+			t.Fatalf("truncated at %d: %v", i, err)
+		}
+	}
+
+	tcases := map[string][]byte{
+		"magic":   []byte("json{}"),
+		"version": []byte("last\x7f\x00\x00"),
+		"tag":     []byte("last\x02\x00\x7f"),
+		"string":  []byte("last\x02\x00\x09\x05\x00\x00\x00"),
+		"nil":     []byte("last\x02\x00\x00"),
+	}
+
+	for k, v := range tcases {
+		data := v
+		t.Run(k, func(t *testing.T) {
+			_, err := ast.Decode(bytes.NewReader(data))
+			if !errors.Is(err, ast.ErrEncoding) {
+				t.Fatalf("expected an encoding error, got %v", err)
+			}
+		})
+	}
+}
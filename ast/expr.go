@@ -4,89 +4,191 @@ import (
 	"fmt"
 
 	"github.com/ear7h/lang/ast/parser"
+	"github.com/ear7h/lang/ast/parser/typed"
 )
 
 var _ = fmt.Println
 
 type ExprParser struct{}
 
-func (ExprParser) Parse(c *parser.Cursor) (interface{}, bool) {
-	return parser.First(
-		&UnaryExpr{},
+func (p ExprParser) Parse(c *parser.Cursor) (interface{}, bool) {
+	return p.grammar().Parse(c)
+}
+
+func (p ExprParser) Syntax() *parser.Syntax {
+	return parser.SyntaxOf(p.grammar())
+}
+
+func (ExprParser) grammar() parser.Parser {
+	return parser.Named("expr", parser.First(
+		parser.Named("unary-expr", &UnaryExpr{}),
 		&BinaryExpr{},
-	).Parse(c)
+	))
 }
 
 type ExprOperandParser struct{}
 
-func (ExprOperandParser) Parse(c *parser.Cursor) (interface{}, bool) {
-	fi := c.FileInfo()
+func (p ExprOperandParser) Parse(c *parser.Cursor) (interface{}, bool) {
+	return p.grammar(c.FileInfo()).Parse(c)
+}
 
-	v, ok :=  parser.All(
-		parser.First(
-			LiteralParser{},
-			&Ident{},
-			parser.Braced(
+func (p ExprOperandParser) Syntax() *parser.Syntax {
+	return parser.SyntaxOf(p.grammar(parser.FileInfo{}))
+}
+
+// grammar returns the parser of an operand starting at fi
+func (ExprOperandParser) grammar(fi parser.FileInfo) parser.Parser {
+	lift := typed.Lift[interface{}]
+
+	return typed.Erase(typed.Seq2(
+		typed.Alt(
+			lift(parser.Named("literal", LiteralParser{})),
+			lift(parser.Named("func-lit", &FuncLit{})),
+			lift(parser.Named("ident", &Ident{})),
+			lift(parser.Named("paren-expr", parser.Braced(
 				parser.ExpectString("("),
-				ExprParser{},
+				recoverExpr(
+					parser.AllIdx(0,
+						ExprParser{},
+						parser.WS(),
+						parser.Lookahead(parser.ExpectString(")")),
+					),
+					")",
+				),
 				parser.ExpectString(")"),
-			),
+			))),
 		),
-		parser.Maybe(ExprOperandParser1{}),
-	).Parse(c)
+		typed.Lift[*ObjExpr](parser.Maybe(ExprOperandParser1{})),
+		func(left interface{}, right *ObjExpr) interface{} {
+			if right == nil {
+				return left
+			}
+
+			right.Object = left
+			right.setFi(fi)
+
+			// the root spans the whole chain
+			last := right
+			for last.Right != nil {
+				last = last.Right.(*ObjExpr)
+			}
+			right.EndFi = last.End()
+
+			return right
+		},
+	))
+}
+
+// FuncLit is a function literal, fn(params) { body }
+type FuncLit struct {
+	BaseNode
+	Params []*Ident
+	Body   *BlockStmt
+}
+
+func (n *FuncLit) Parse(c *parser.Cursor) (interface{}, bool) {
+	n.setFileInfo(c)
+
+	v, ok := n.grammar().Parse(c)
 	if !ok {
 		return nil, false
 	}
 
 	slc := v.([]interface{})
-
-
-	left := slc[0]
-
-	if slc[1] == nil {
-		return left, true
+	for _, v := range slc[2].([]interface{}) {
+		n.Params = append(n.Params, v.(*Ident))
 	}
+	n.Body = slc[4].(*BlockStmt)
+	n.setEnd(c)
 
-	n := slc[1].(*ObjExpr)
+	return n, true
+}
 
-	n.Object = left
-	n.setFi(fi)
+func (n *FuncLit) Syntax() *parser.Syntax {
+	return parser.SyntaxOf(n.grammar())
+}
 
-	return n, true
+func (*FuncLit) grammar() parser.Parser {
+	return parser.All(
+		Keyword(KeywordFn),
+		parser.HS(),
+		parser.Braced(
+			parser.ExpectString("("),
+			parser.SepBy(identParser{}, listSep()),
+			parser.ExpectString(")"),
+		),
+		parser.HS(),
+		parser.Named("block", &BlockStmt{}),
+	)
+}
+
+// listSep returns a parser for the comma between the elements of a
+// list in parentheses
+func listSep() parser.Parser {
+	return parser.All(parser.WS(), parser.ExpectString(","), parser.WS())
 }
 
 // remove left recursion
 type ExprOperandParser1 struct{}
 
-func (ExprOperandParser1) Parse(c *parser.Cursor) (interface{}, bool) {
-
-	v, ok := parser.First(
-		ObjExprRightParser{},
-		/*
-		parser.First(
-			parser.Kleene(
-				parser.All(
-					ExpectString("."),
-					&Ident{},
-				),
-			),
-		),
-		*/
-	).Parse(c)
+func (p ExprOperandParser1) Parse(c *parser.Cursor) (interface{}, bool) {
+	v, ok := p.grammar().Parse(c)
 	if !ok {
 		return nil, false
 	}
 
+	slc := v.([]interface{})
+	for i := len(slc) - 1; i > 0; i-- {
+		slc[i-1].(*ObjExpr).Right = slc[i]
+	}
 
-	vv, ok := ExprOperandParser1{}.Parse(c)
-	if !ok {
-		return v,  true
+	return slc[0], true
+}
+
+func (p ExprOperandParser1) Syntax() *parser.Syntax {
+	return parser.SyntaxOf(p.grammar())
+}
+
+func (ExprOperandParser1) grammar() parser.Parser {
+	return parser.Many1(
+		parser.Named("obj-expr", ObjExprRightParser{}),
+	)
+}
+
+// BadExpr is a placeholder for an expression that failed to parse,
+// it spans the input that was skipped, and Err is the syntax
+// error it was replaced for.
+type BadExpr struct {
+	BaseNode
+	To  parser.FileInfo
+	Err *parser.Error
+}
+
+// recoverExpr wraps parser.Recover, replacing its *parser.Bad result
+// with a *BadExpr
+func recoverExpr(p parser.Parser, sync ...string) parser.Parser {
+	return recoverExprParser{p: p, sync: sync}
+}
+
+type recoverExprParser struct {
+	p    parser.Parser
+	sync []string
+}
+
+func (r recoverExprParser) Parse(c *parser.Cursor) (interface{}, bool) {
+	v, ok := parser.Recover(r.p, r.sync...).Parse(c)
+	if bad, isBad := v.(*parser.Bad); isBad {
+		n := &BadExpr{To: bad.To, Err: bad.Err}
+		n.setFi(bad.From)
+		n.EndFi = bad.To
+		return n, true
 	}
 
-	objExpr := v.(*ObjExpr)
-	objExpr.Right = vv
+	return v, ok
+}
 
-	return objExpr, true
+func (r recoverExprParser) Syntax() *parser.Syntax {
+	return parser.SyntaxOf(r.p)
 }
 
 type LeftRecursive interface {
@@ -0,0 +1,50 @@
+package ast
+
+import (
+	"github.com/ear7h/lang/ast/parser"
+)
+
+// File is the root of a source file's tree
+type File struct {
+	BaseNode
+	Name  string
+	Stmts []interface{}
+}
+
+// Parse parses statements up to the end of the input, it only fails if
+// the cursor panics. Syntax errors are recorded on the cursor.
+func (n *File) Parse(c *parser.Cursor) (interface{}, bool) {
+	n.setFileInfo(c)
+	n.Name = n.Fi.Name
+
+	n.Stmts = parseStmts(c, parser.ExpectEOF(), stmtSync)
+	n.EndFi = c.FileInfo()
+
+	return n, true
+}
+
+func (*File) Syntax() *parser.Syntax {
+	return parser.SyntaxOf(stmtsGrammar(parser.ExpectEOF()))
+}
+
+// Grammar returns the grammar of a source file
+func Grammar() *parser.Grammar {
+	return parser.Describe(parser.Named("file", &File{}))
+}
+
+// ParseFile parses the source of a file. The returned error is a
+// parser.ErrorList with all the syntax errors found, in which case the
+// file is still returned with the bad statements and expressions
+// replaced by *BadStmt and *BadExpr.
+func ParseFile(name, src string) (*File, error) {
+	return Parse(parser.NewCursorString(src, name))
+}
+
+// Parse is like ParseFile, but parses the file c reads. The error is
+// also the error of reading, if c fails.
+func Parse(c *parser.Cursor) (*File, error) {
+	v, _, err := parser.DoParse(&File{}, c)
+	f, _ := v.(*File)
+
+	return f, err
+}
@@ -0,0 +1,293 @@
+package ast_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ear7h/lang/ast"
+	"github.com/ear7h/lang/ast/parser"
+	"github.com/ear7h/lang/internal/assert"
+)
+
+func TestParseFile(t *testing.T) {
+	type tcase struct {
+		str  string
+		errs []string
+		out  []interface{}
+	}
+
+	fn := func(tc tcase) func(t *testing.T) {
+		return func(t *testing.T) {
+			f, err := ast.ParseFile("test", tc.str)
+
+			var errs []string
+			if err != nil {
+				for _, v := range err.(parser.ErrorList) {
+					errs = append(errs, v.Error())
+				}
+			}
+
+			assert.Eq(t, tc.errs, errs)
+
+			// the bad nodes carry the syntax errors
+			var bad parser.ErrorList
+			ast.Inspect(f, func(n ast.Node) bool {
+				switch n := n.(type) {
+				case *ast.BadStmt:
+					bad = append(bad, n.Err)
+					n.Err = nil
+				case *ast.BadExpr:
+					bad = append(bad, n.Err)
+					n.Err = nil
+				}
+				return true
+			})
+			var syntax parser.ErrorList
+			if err != nil {
+				for _, v := range err.(parser.ErrorList) {
+					if strings.HasPrefix(v.Msg, "syntax error") {
+						syntax = append(syntax, v)
+					}
+				}
+			}
+			assert.Eq(t, syntax.Err(), bad.Err())
+
+			assert.Eq(t, "test", f.Name)
+			assert.Eq(t, tc.out, f.Stmts)
+		}
+	}
+
+	fi := func(line, col int64) parser.FileInfo {
+		return parser.FileInfo{Name: "test", Line: line, Col: col}
+	}
+
+	num := func(s string) interface{} {
+		return &ast.ExprStmt{
+			X: parser.MustParseString(&ast.NumberLiteral{}, s),
+		}
+	}
+
+	tcases := map[string]tcase{
+		"empty": tcase{
+			str: "",
+		},
+		"one": tcase{
+			str: "1",
+			out: []interface{}{num("1")},
+		},
+		"semicolons": tcase{
+			str: "1; 2;\n3;",
+			out: []interface{}{num("1"), num("2"), num("3")},
+		},
+		"let": tcase{
+			str: "let x = 1 +\n2",
+			out: []interface{}{
+				&ast.LetStmt{
+					Name: &ast.Ident{Name: "x"},
+					X:    parser.MustParseString(ast.ExprParser{}, "1+2"),
+				},
+			},
+		},
+		"block": tcase{
+			str: "{\n1; {}\n}; 2",
+			out: []interface{}{
+				&ast.BlockStmt{Stmts: []interface{}{
+					num("1"),
+					&ast.BlockStmt{},
+				}},
+				num("2"),
+			},
+		},
+		"func": tcase{
+			str: "fn(a, b) {\n\treturn a\n}(1)",
+			out: []interface{}{
+				&ast.ExprStmt{X: &ast.ObjExpr{
+					Object: &ast.FuncLit{
+						Params: []*ast.Ident{
+							{Name: "a"},
+							{Name: "b"},
+						},
+						Body: &ast.BlockStmt{Stmts: []interface{}{
+							&ast.ReturnStmt{X: &ast.Ident{Name: "a"}},
+						}},
+					},
+					Op: ast.ObjCall,
+					Args: []interface{}{
+						parser.MustParseString(&ast.NumberLiteral{}, "1"),
+					},
+				}},
+			},
+		},
+		"calls": tcase{
+			str: "f().g(x, \"y\")",
+			out: []interface{}{
+				&ast.ExprStmt{X: &ast.ObjExpr{
+					Object: &ast.Ident{Name: "f"},
+					Op:     ast.ObjCall,
+					Right: &ast.ObjExpr{
+						Op:  ast.ObjField,
+						Arg: &ast.Ident{Name: "g"},
+						Right: &ast.ObjExpr{
+							Op: ast.ObjCall,
+							Args: []interface{}{
+								&ast.Ident{Name: "x"},
+								parser.MustParseString(&ast.StringLiteral{}, `"y"`),
+							},
+						},
+					},
+				}},
+			},
+		},
+		"bad block": tcase{
+			str: "{ 1 +; 2 }",
+			errs: []string{
+				`test:1:6: syntax error: unexpected ";", ` +
+					`expected string or number or "fn" or identifier or "("`,
+			},
+			out: []interface{}{
+				&ast.BlockStmt{Stmts: []interface{}{
+					&ast.BadStmt{To: fi(1, 6)},
+					num("2"),
+				}},
+			},
+		},
+		"fields": tcase{
+			str: "a.b; c",
+			out: []interface{}{
+				&ast.ExprStmt{
+					X: parser.MustParseString(ast.ExprParser{}, "a.b"),
+				},
+				&ast.ExprStmt{
+					X: parser.MustParseString(ast.ExprParser{}, "c"),
+				},
+			},
+		},
+		"bad stmt": tcase{
+			str: "1 +; 2",
+			errs: []string{
+				`test:1:4: syntax error: unexpected ";", ` +
+					`expected string or number or "fn" or identifier or "("`,
+			},
+			out: []interface{}{
+				&ast.BadStmt{To: fi(1, 4)},
+				num("2"),
+			},
+		},
+		"bad expr": tcase{
+			str: "(1 +); 2",
+			errs: []string{
+				`test:1:5: syntax error: unexpected ")", ` +
+					`expected string or number or "fn" or identifier or "("`,
+			},
+			out: []interface{}{
+				&ast.ExprStmt{
+					X: &ast.BadExpr{To: fi(1, 5)},
+				},
+				num("2"),
+			},
+		},
+		"newlines": tcase{
+			str: "1\n2 \r\n\n3",
+			out: []interface{}{num("1"), num("2"), num("3")},
+		},
+		"continued": tcase{
+			str: "1 +\n2\n(3\n)",
+			out: []interface{}{
+				&ast.ExprStmt{
+					X: parser.MustParseString(ast.ExprParser{}, "1 + 2"),
+				},
+				&ast.ExprStmt{
+					X: parser.MustParseString(ast.ExprParser{}, "(3)"),
+				},
+			},
+		},
+		"operator on next line": tcase{
+			str: "1\n+2",
+			out: []interface{}{
+				num("1"),
+				&ast.ExprStmt{
+					X: parser.MustParseString(ast.ExprParser{}, "+2"),
+				},
+			},
+		},
+		"return": tcase{
+			str: "return\nreturn 1 +\n2; return",
+			out: []interface{}{
+				&ast.ReturnStmt{},
+				&ast.ReturnStmt{
+					X: parser.MustParseString(ast.ExprParser{}, "1 + 2"),
+				},
+				&ast.ReturnStmt{},
+			},
+		},
+		"keyword": tcase{
+			str: "returned.return",
+			errs: []string{
+				`test:1:10: syntax error: unexpected "return", ` +
+					`expected identifier`,
+			},
+			out: []interface{}{
+				&ast.BadStmt{To: fi(1, 16)},
+			},
+		},
+		"unknown escape": tcase{
+			str: "\"a\\qb\" + \"\\z\"",
+			errs: []string{
+				`test:1:3: unknown escape sequence \q`,
+				`test:1:11: unknown escape sequence \z`,
+			},
+			out: []interface{}{
+				&ast.ExprStmt{X: &ast.BinaryExpr{
+					Op: "+",
+					Left: &ast.StringLiteral{
+						Orig:   `"a\qb"`,
+						Parsed: "aqb",
+					},
+					Right: &ast.StringLiteral{
+						Orig:   `"\z"`,
+						Parsed: "z",
+					},
+				}},
+			},
+		},
+		"errors in order": tcase{
+			str: "let y = (2 +\nfoo\n3 $ 4",
+			errs: []string{
+				`test:1:13: syntax error: unexpected newline, expected ")"`,
+				`test:3:3: syntax error: unexpected "$", ` +
+					`expected ">>" or "<<" or "&" or "|" or "^" or ` +
+					`"*" or "/" or "%" or "+" or "-" or ` +
+					`"<=" or ">=" or "==" or "!=" or "<" or ">" or ` +
+					`"&&" or "||" or ";" or newline or end of file`,
+			},
+			out: []interface{}{
+				&ast.BadStmt{To: fi(1, 13)},
+				&ast.ExprStmt{X: &ast.Ident{Name: "foo"}},
+				&ast.BadStmt{To: fi(3, 6)},
+			},
+		},
+		"multiple": tcase{
+			str: "1 +;\n2 2;\n(;\n3",
+			errs: []string{
+				`test:1:4: syntax error: unexpected ";", ` +
+					`expected string or number or "fn" or identifier or "("`,
+				`test:2:3: syntax error: unexpected "2", ` +
+					`expected ">>" or "<<" or "&" or "|" or "^" or ` +
+					`"*" or "/" or "%" or "+" or "-" or ` +
+					`"<=" or ">=" or "==" or "!=" or "<" or ">" or ` +
+					`"&&" or "||" or ";" or newline or end of file`,
+				`test:3:2: syntax error: unexpected ";", expected ")"`,
+			},
+			out: []interface{}{
+				&ast.BadStmt{To: fi(1, 4)},
+				&ast.BadStmt{To: fi(2, 4)},
+				&ast.BadStmt{To: fi(3, 2)},
+				num("3"),
+			},
+		},
+	}
+
+	for k, v := range tcases {
+		t.Run(k, fn(v))
+	}
+}
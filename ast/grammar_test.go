@@ -0,0 +1,58 @@
+package ast_test
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/ear7h/lang/ast"
+	"github.com/ear7h/lang/internal/assert"
+)
+
+var update = flag.Bool("update", false, "update the generated grammar docs")
+
+// TestGrammar checks the grammar docs are generated from the current
+// parsers, run with -update to regenerate them
+func TestGrammar(t *testing.T) {
+	type tcase struct {
+		file  string
+		write func(w io.Writer) error
+	}
+
+	fn := func(tc tcase) func(t *testing.T) {
+		return func(t *testing.T) {
+			var b bytes.Buffer
+			err := tc.write(&b)
+			assert.Eq(t, nil, err)
+
+			if *update {
+				err = os.WriteFile(tc.file, b.Bytes(), 0644)
+				assert.Eq(t, nil, err)
+				return
+			}
+
+			doc, err := os.ReadFile(tc.file)
+			assert.Eq(t, nil, err)
+			assert.Eq(t, string(doc), b.String())
+		}
+	}
+
+	g := ast.Grammar()
+
+	tcases := map[string]tcase{
+		"ebnf": tcase{
+			file:  "../doc/grammar.ebnf",
+			write: g.WriteEBNF,
+		},
+		"json": tcase{
+			file:  "../doc/grammar.json",
+			write: g.WriteJSON,
+		},
+	}
+
+	for k, v := range tcases {
+		t.Run(k, fn(v))
+	}
+}
@@ -15,36 +15,63 @@ type Ident struct {
 }
 
 func (n *Ident) Parse(c *parser.Cursor) (interface{}, bool) {
+	return parser.Lexeme(parser.ParserFunc(n.parse)).Parse(c)
+}
+
+func (*Ident) Syntax() *parser.Syntax {
+	return &parser.Syntax{Op: parser.SyntaxClass, Text: "identifier"}
+}
+
+func (n *Ident) parse(c *parser.Cursor) (interface{}, bool) {
 	n.setFileInfo(c)
 
 	r := c.PeekRune()
 
 
-	if unicode.In(r, unicode.Ll) || r == '_' {
-		n.IsExported = false
-	} else if unicode.In(r, unicode.Lu) {
-		n.IsExported = true
-	} else {
+	if !identStart.Is(r) {
+		c.Expected("identifier")
 		return nil, false
 	}
 
-	str := string(c.ReadRune())
+	n.IsExported = unicode.In(r, unicode.Lu)
+
+	// read a copy, keywords are not identifiers
+	cc := *c
 
-	isIdentTail := func(r rune) bool {
-		return unicode.In(r, unicode.Ll, unicode.Lu) ||
-			unicode.IsDigit(r) ||
-			r == '_'
+	str := string(cc.ReadRune())
+
+	for isIdentTail(cc.PeekRune()) {
+		str += string(cc.ReadRune())
 	}
 
-	for isIdentTail(c.PeekRune()) {
-		str += string(c.ReadRune())
+	if isKeyword(str) {
+		c.Expected("identifier")
+		return nil, false
 	}
 
+	*c = cc
 	n.Name = str
+	n.setEnd(c)
 
 	return n, true
 }
 
+// identParser parses a new *Ident each time, where an *Ident parses
+// into itself, for lists of identifiers
+type identParser struct{}
+
+func (identParser) Parse(c *parser.Cursor) (interface{}, bool) {
+	return (&Ident{}).Parse(c)
+}
+
+func (identParser) Syntax() *parser.Syntax {
+	return (&Ident{}).Syntax()
+}
+
+func isIdentTail(r rune) bool {
+	return identTail.Is(r)
+}
+
 var _ = fmt.Println
 
 const (
@@ -54,43 +81,94 @@ const (
 	ObjCall
 )
 
+// ObjExpr is a chain of field selections and calls on Object, each
+// link is an ObjExpr whose Op is the kind of the link. Arg is the *Ident
+// of a field, and Args are the arguments of a call.
 type ObjExpr struct {
 	BaseNode
 	Object interface{} // root object
 	Op int
 	Arg interface{}
+	Args []interface{}
 	Right interface{}
 }
 
 type ObjExprRightParser struct {}
 
 
-func (_ ObjExprRightParser) Parse(c *parser.Cursor) (interface{}, bool) {
+func (p ObjExprRightParser) Parse(c *parser.Cursor) (interface{}, bool) {
+	return p.grammar().Parse(c)
+}
+
+func (p ObjExprRightParser) Syntax() *parser.Syntax {
+	return parser.SyntaxOf(p.grammar())
+}
+
+func (ObjExprRightParser) grammar() parser.Parser {
 	return parser.First(
 		ObjFieldRightParser{},
-	).Parse(c)
+		ObjCallRightParser{},
+	)
 }
 
 type ObjFieldRightParser struct {}
 
 
-func (_ ObjFieldRightParser) Parse(c *parser.Cursor) (interface{}, bool) {
-	var (
-		n ObjExpr
-		ok bool
-	)
+func (p ObjFieldRightParser) Parse(c *parser.Cursor) (interface{}, bool) {
+	var n ObjExpr
 
 	n.setFileInfo(c)
 
-	_, ok = parser.ExpectString(".").Parse(c)
+	v, ok := p.grammar().Parse(c)
 	if !ok {
 		return nil, false
 	}
 
-	n.Arg, ok = (&Ident{}).Parse(c)
+	n.Arg = v
+	n.setEnd(c)
+
+	return &n, true
+}
+
+func (p ObjFieldRightParser) Syntax() *parser.Syntax {
+	return parser.SyntaxOf(p.grammar())
+}
+
+func (ObjFieldRightParser) grammar() parser.Parser {
+	return parser.AllIdx(1,
+		parser.ExpectString("."),
+		&Ident{},
+	)
+}
+
+// ObjCallRightParser parses the arguments of a call, which must start
+// right after the callee
+type ObjCallRightParser struct{}
+
+func (p ObjCallRightParser) Parse(c *parser.Cursor) (interface{}, bool) {
+	n := ObjExpr{Op: ObjCall}
+
+	n.setFileInfo(c)
+
+	v, ok := p.grammar().Parse(c)
 	if !ok {
 		return nil, false
 	}
 
+	n.Args = append(n.Args, v.([]interface{})...)
+	n.setEnd(c)
+
 	return &n, true
 }
+
+func (p ObjCallRightParser) Syntax() *parser.Syntax {
+	return parser.SyntaxOf(p.grammar())
+}
+
+func (ObjCallRightParser) grammar() parser.Parser {
+	return parser.Braced(
+		parser.ExpectString("("),
+		parser.SepBy(ExprParser{}, listSep()),
+		parser.ExpectString(")"),
+	)
+}
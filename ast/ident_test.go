@@ -5,6 +5,7 @@ import (
 
 	"github.com/ear7h/lang/ast"
 	"github.com/ear7h/lang/ast/parser"
+	"github.com/ear7h/lang/internal/assert"
 )
 
 func TestParseIdent(t *testing.T) {
@@ -20,17 +21,17 @@ func TestParseIdent(t *testing.T) {
 			initCur, v, ok, err :=
 				parser.DoParseStringForTest(&ast.Ident{}, tc.str, "test")
 
-			assertErrIs(t, tc.err, err)
-			assertEq(t, tc.ok, ok)
+			assert.ErrIs(t, tc.err, err)
+			assert.Eq(t, tc.ok, ok)
 			if !ok || err != nil {
 				return
 			}
 
 			n := v.(*ast.Ident)
 
-			assertEq(t, initCur.FileInfo(), n.FileInfo())
-			assertEq(t, tc.out.Name, n.Name)
-			assertEq(t, tc.out.IsExported, n.IsExported)
+			assert.Eq(t, initCur.FileInfo(), n.FileInfo())
+			assert.Eq(t, tc.out.Name, n.Name)
+			assert.Eq(t, tc.out.IsExported, n.IsExported)
 		}
 	}
 
@@ -108,19 +109,19 @@ func TestParseObjExpr(t *testing.T) {
 				parser.DoParseStringForTest(
 					&ast.ExprOperandParser{}, tc.str, "test")
 
-			assertErrIs(t, tc.err, err)
-			assertEq(t, tc.ok, ok)
+			assert.ErrIs(t, tc.err, err)
+			assert.Eq(t, tc.ok, ok)
 			if !ok || err != nil {
 				return
 			}
 
 			n := v.(*ast.ObjExpr)
 
-			assertEq(t, initCur.FileInfo(), n.FileInfo())
-			assertEq(t, tc.out.Object, n.Object)
-			assertEq(t, tc.out.Op, n.Op)
-			assertEq(t, tc.out.Arg, n.Arg)
-			assertEq(t, tc.out.Right, n.Right)
+			assert.Eq(t, initCur.FileInfo(), n.FileInfo())
+			assert.Eq(t, tc.out.Object, n.Object)
+			assert.Eq(t, tc.out.Op, n.Op)
+			assert.Eq(t, tc.out.Arg, n.Arg)
+			assert.Eq(t, tc.out.Right, n.Right)
 		}
 	}
 
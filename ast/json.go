@@ -0,0 +1,259 @@
+package ast
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"unicode/utf8"
+)
+
+// nodeKinds are the nodes by their kind, the name of their type
+var nodeKinds = map[string]func() Node{
+	"File":          func() Node { return &File{} },
+	"ExprStmt":      func() Node { return &ExprStmt{} },
+	"ReturnStmt":    func() Node { return &ReturnStmt{} },
+	"LetStmt":       func() Node { return &LetStmt{} },
+	"BlockStmt":     func() Node { return &BlockStmt{} },
+	"BadStmt":       func() Node { return &BadStmt{} },
+	"UnaryExpr":     func() Node { return &UnaryExpr{} },
+	"BinaryExpr":    func() Node { return &BinaryExpr{} },
+	"FuncLit":       func() Node { return &FuncLit{} },
+	"ObjExpr":       func() Node { return &ObjExpr{} },
+	"Ident":         func() Node { return &Ident{} },
+	"NumberLiteral": func() Node { return &NumberLiteral{} },
+	"StringLiteral": func() Node { return &StringLiteral{} },
+	"BadExpr":       func() Node { return &BadExpr{} },
+}
+
+// Kind returns the kind of the node n, the name of its type
+func Kind(n Node) string {
+	return reflect.TypeOf(n).Elem().Name()
+}
+
+// MarshalJSON returns the JSON encoding of the tree rooted at n. Nodes
+// are objects with their fields, the fields of BaseNode included, and a
+// "kind" field with the Kind of the node, runes are strings.
+func MarshalJSON(n Node) ([]byte, error) {
+	return json.Marshal(n)
+}
+
+// UnmarshalJSON returns the tree encoded by MarshalJSON, whatever the
+// kind of its root
+func UnmarshalJSON(data []byte) (Node, error) {
+	v, err := unmarshalAny(data)
+	if err != nil {
+		return nil, err
+	}
+
+	n, ok := v.(Node)
+	if !ok {
+		return nil, fmt.Errorf("ast: json is not a node")
+	}
+
+	return n, nil
+}
+
+// marshalNode encodes the node n, see MarshalJSON
+func marshalNode(n Node) ([]byte, error) {
+	var b bytes.Buffer
+
+	kind, err := json.Marshal(Kind(n))
+	if err != nil {
+		return nil, err
+	}
+
+	b.WriteString(`{"kind":`)
+	b.Write(kind)
+
+	err = eachField(reflect.ValueOf(n).Elem(), func(name string, f reflect.Value) error {
+		var (
+			v   []byte
+			err error
+		)
+
+		if f.Kind() == reflect.Int32 {
+			v, err = json.Marshal(string(rune(f.Int())))
+		} else {
+			v, err = json.Marshal(f.Interface())
+		}
+
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(&b, ",%q:", name)
+		b.Write(v)
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	b.WriteString("}")
+
+	return b.Bytes(), nil
+}
+
+// unmarshalNode decodes data into the node n, see MarshalJSON
+func unmarshalNode(data []byte, n Node) error {
+	var obj map[string]json.RawMessage
+	err := json.Unmarshal(data, &obj)
+	if err != nil {
+		return err
+	}
+
+	var kind string
+	json.Unmarshal(obj["kind"], &kind)
+	if kind != Kind(n) {
+		return fmt.Errorf("ast: cannot decode kind %q into %T", kind, n)
+	}
+
+	return eachField(reflect.ValueOf(n).Elem(), func(name string, f reflect.Value) error {
+		raw, ok := obj[name]
+		if !ok {
+			return nil
+		}
+
+		err := unmarshalField(raw, f)
+		if err != nil {
+			return fmt.Errorf("ast: %s.%s: %w", kind, name, err)
+		}
+
+		return nil
+	})
+}
+
+// unmarshalField decodes the field f, the interfaces in it are nodes
+func unmarshalField(data []byte, f reflect.Value) error {
+	switch {
+	case f.Kind() == reflect.Interface:
+		v, err := unmarshalAny(data)
+		if err == nil && v != nil {
+			f.Set(reflect.ValueOf(v))
+		}
+
+		return err
+
+	case f.Kind() == reflect.Slice && f.Type().Elem().Kind() == reflect.Interface:
+		var elems []json.RawMessage
+		err := json.Unmarshal(data, &elems)
+		if err != nil || elems == nil {
+			return err
+		}
+
+		s := reflect.MakeSlice(f.Type(), len(elems), len(elems))
+		for i, v := range elems {
+			err = unmarshalField(v, s.Index(i))
+			if err != nil {
+				return err
+			}
+		}
+
+		f.Set(s)
+
+		return nil
+
+	case f.Kind() == reflect.Int32:
+		var s string
+		err := json.Unmarshal(data, &s)
+		if err != nil {
+			return err
+		}
+
+		r, n := utf8.DecodeRuneInString(s)
+		if n == 0 || n != len(s) {
+			return fmt.Errorf("not a rune %q", s)
+		}
+
+		f.SetInt(int64(r))
+
+		return nil
+	}
+
+	return json.Unmarshal(data, f.Addr().Interface())
+}
+
+// unmarshalAny decodes a node by its kind, or nil
+func unmarshalAny(data []byte) (interface{}, error) {
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		return nil, nil
+	}
+
+	var obj struct {
+		Kind string `json:"kind"`
+	}
+
+	err := json.Unmarshal(data, &obj)
+	if err != nil {
+		return nil, err
+	}
+
+	newNode, ok := nodeKinds[obj.Kind]
+	if !ok {
+		return nil, fmt.Errorf("ast: unknown node kind %q", obj.Kind)
+	}
+
+	n := newNode()
+	err = json.Unmarshal(data, n)
+	if err != nil {
+		return nil, err
+	}
+
+	return n, nil
+}
+
+// eachField calls fn with the exported fields of the struct v, the
+// fields of embedded structs included
+func eachField(v reflect.Value, fn func(name string, f reflect.Value) error) error {
+	for i := 0; i < v.NumField(); i++ {
+		sf := v.Type().Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+
+		var err error
+		if sf.Anonymous && sf.Type.Kind() == reflect.Struct {
+			err = eachField(v.Field(i), fn)
+		} else {
+			err = fn(sf.Name, v.Field(i))
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (n *File) MarshalJSON() ([]byte, error)          { return marshalNode(n) }
+func (n *File) UnmarshalJSON(b []byte) error          { return unmarshalNode(b, n) }
+func (n *ExprStmt) MarshalJSON() ([]byte, error)      { return marshalNode(n) }
+func (n *ExprStmt) UnmarshalJSON(b []byte) error      { return unmarshalNode(b, n) }
+func (n *ReturnStmt) MarshalJSON() ([]byte, error)    { return marshalNode(n) }
+func (n *ReturnStmt) UnmarshalJSON(b []byte) error    { return unmarshalNode(b, n) }
+func (n *BadStmt) MarshalJSON() ([]byte, error)       { return marshalNode(n) }
+func (n *BadStmt) UnmarshalJSON(b []byte) error       { return unmarshalNode(b, n) }
+func (n *LetStmt) MarshalJSON() ([]byte, error)       { return marshalNode(n) }
+func (n *LetStmt) UnmarshalJSON(b []byte) error       { return unmarshalNode(b, n) }
+func (n *BlockStmt) MarshalJSON() ([]byte, error)     { return marshalNode(n) }
+func (n *BlockStmt) UnmarshalJSON(b []byte) error     { return unmarshalNode(b, n) }
+func (n *UnaryExpr) MarshalJSON() ([]byte, error)     { return marshalNode(n) }
+func (n *UnaryExpr) UnmarshalJSON(b []byte) error     { return unmarshalNode(b, n) }
+func (n *BinaryExpr) MarshalJSON() ([]byte, error)    { return marshalNode(n) }
+func (n *BinaryExpr) UnmarshalJSON(b []byte) error    { return unmarshalNode(b, n) }
+func (n *FuncLit) MarshalJSON() ([]byte, error)       { return marshalNode(n) }
+func (n *FuncLit) UnmarshalJSON(b []byte) error       { return unmarshalNode(b, n) }
+func (n *ObjExpr) MarshalJSON() ([]byte, error)       { return marshalNode(n) }
+func (n *ObjExpr) UnmarshalJSON(b []byte) error       { return unmarshalNode(b, n) }
+func (n *Ident) MarshalJSON() ([]byte, error)         { return marshalNode(n) }
+func (n *Ident) UnmarshalJSON(b []byte) error         { return unmarshalNode(b, n) }
+func (n *NumberLiteral) MarshalJSON() ([]byte, error) { return marshalNode(n) }
+func (n *NumberLiteral) UnmarshalJSON(b []byte) error { return unmarshalNode(b, n) }
+func (n *StringLiteral) MarshalJSON() ([]byte, error) { return marshalNode(n) }
+func (n *StringLiteral) UnmarshalJSON(b []byte) error { return unmarshalNode(b, n) }
+func (n *BadExpr) MarshalJSON() ([]byte, error)       { return marshalNode(n) }
+func (n *BadExpr) UnmarshalJSON(b []byte) error       { return unmarshalNode(b, n) }
@@ -0,0 +1,87 @@
+package ast_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/ear7h/lang/ast"
+	"github.com/ear7h/lang/internal/assert"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	tcases := map[string]string{
+		"empty":    "",
+		"binary":   "1 + 2 * 3 - (4 / a)",
+		"unary":    "-x; !(&y)",
+		"objects":  "a.b.c + (d).e",
+		"strings":  `"a\tb" + "\\"`,
+		"return":   "return\nreturn 1",
+		"unicode":  "héllo + 1",
+		"bad stmt": "1 +\n2",
+		"bad expr": "(1 + ) * 2",
+		"funcs":    "let f = fn(a, b) { return a(b) }\n{ f(1, fn() {}).x }",
+	}
+
+	for k, v := range tcases {
+		src := v
+		t.Run(k, func(t *testing.T) {
+			f, _ := ast.ParseFile("test", src)
+
+			b, err := ast.MarshalJSON(f)
+			assert.Eq(t, nil, err)
+
+			n, err := ast.UnmarshalJSON(b)
+			assert.Eq(t, nil, err)
+
+			assert.DeepEq(t, f, n)
+
+			// and through the node's method
+			var f1 ast.File
+			err = json.Unmarshal(b, &f1)
+			assert.Eq(t, nil, err)
+
+			if !reflect.DeepEqual(f, &f1) {
+				t.Fatalf("expected: %#v\ngot: %#v", f, &f1)
+			}
+		})
+	}
+}
+
+func TestMarshalJSON(t *testing.T) {
+	f, _ := ast.ParseFile("t", "-a")
+
+	b, err := ast.MarshalJSON(f.Stmts[0].(*ast.ExprStmt).X.(ast.Node))
+	assert.Eq(t, nil, err)
+	assert.Eq(t, `{"kind":"UnaryExpr",`+
+		`"Fi":{"Name":"t","Line":1,"Col":1},`+
+		`"EndFi":{"Name":"t","Line":1,"Col":3},`+
+		`"Op":"-",`+
+		`"Operand":{"kind":"Ident",`+
+		`"Fi":{"Name":"t","Line":1,"Col":2},`+
+		`"EndFi":{"Name":"t","Line":1,"Col":3},`+
+		`"IsExported":false,"Name":"a"}}`, string(b))
+}
+
+func TestUnmarshalJSONErrors(t *testing.T) {
+	tcases := map[string]string{
+		"unknown kind": `{"kind":"Nope"}`,
+		"not a node":   `null`,
+		"bad field":    `{"kind":"ExprStmt","X":{"kind":"Foo"}}`,
+		"bad rune":     `{"kind":"UnaryExpr","Op":"ab"}`,
+	}
+
+	for k, v := range tcases {
+		data := v
+		t.Run(k, func(t *testing.T) {
+			_, err := ast.UnmarshalJSON([]byte(data))
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+
+	var n ast.Ident
+	err := json.Unmarshal([]byte(`{"kind":"File"}`), &n)
+	assert.Eq(t, `ast: cannot decode kind "File" into *ast.Ident`, err.Error())
+}
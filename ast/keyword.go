@@ -0,0 +1,51 @@
+package ast
+
+import (
+	"github.com/ear7h/lang/ast/parser"
+)
+
+const (
+	KeywordReturn = "return"
+	KeywordLet    = "let"
+	KeywordFn     = "fn"
+)
+
+var keywords = []string{
+	KeywordReturn,
+	KeywordLet,
+	KeywordFn,
+}
+
+// Keywords returns the reserved words, which are not identifiers
+func Keywords() []string {
+	return append([]string(nil), keywords...)
+}
+
+func isKeyword(s string) bool {
+	for _, v := range keywords {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Keyword returns a parser that matches kw as a whole word, so it
+// doesn't match the start of a longer identifier
+func Keyword(kw string) parser.Parser {
+	return keyword(kw)
+}
+
+type keyword string
+
+func (kw keyword) Parse(c *parser.Cursor) (interface{}, bool) {
+	return parser.Lexeme(parser.AllIdx(0,
+		parser.ExpectString(string(kw)),
+		parser.NotFollowedBy(parser.PlusPred(isIdentTail)),
+	)).Parse(c)
+}
+
+func (kw keyword) Syntax() *parser.Syntax {
+	return &parser.Syntax{Op: parser.SyntaxString, Text: string(kw)}
+}
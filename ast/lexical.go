@@ -0,0 +1,96 @@
+package ast
+
+import (
+	"strings"
+	"unicode"
+)
+
+// RuneClass is a set of runes, those in the unicode tables and the
+// extra runes
+type RuneClass struct {
+	Tables []*unicode.RangeTable
+	Runes  []rune
+}
+
+// Is reports whether r is in the class
+func (cl RuneClass) Is(r rune) bool {
+	for _, v := range cl.Runes {
+		if v == r {
+			return true
+		}
+	}
+
+	return unicode.In(r, cl.Tables...)
+}
+
+// the lexical forms of identifiers and literals, the parsers use them
+// and so should tools like syntax highlighters
+var (
+	identStart = RuneClass{
+		Tables: []*unicode.RangeTable{unicode.Ll, unicode.Lu},
+		Runes:  []rune{'_'},
+	}
+	identTail = RuneClass{
+		Tables: []*unicode.RangeTable{unicode.Ll, unicode.Lu, unicode.Nd},
+		Runes:  []rune{'_'},
+	}
+	digits = RuneClass{
+		Tables: []*unicode.RangeTable{unicode.Nd},
+	}
+)
+
+// StringQuote delimits string literals
+const StringQuote = '"'
+
+// stringEscapes maps the rune after a backslash in a string literal to
+// the rune the escape stands for
+var stringEscapes = map[rune]rune{
+	'\\': '\\',
+	'n':  '\n',
+	't':  '\t',
+}
+
+// IdentClasses returns the runes identifiers start with and the runes
+// which may follow
+func IdentClasses() (start, tail RuneClass) {
+	return identStart, identTail
+}
+
+// NumberClass returns the runes of number literals
+func NumberClass() RuneClass {
+	return digits
+}
+
+// StringEscapes returns the escapes of string literals, by the rune
+// after the backslash
+func StringEscapes() map[rune]rune {
+	ret := make(map[rune]rune, len(stringEscapes))
+	for k, v := range stringEscapes {
+		ret[k] = v
+	}
+
+	return ret
+}
+
+// Quote returns the string literal of s, using only the escapes the
+// parser knows
+func Quote(s string) string {
+	var b strings.Builder
+
+	b.WriteRune(StringQuote)
+	for _, r := range s {
+		switch r {
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteRune(StringQuote)
+
+	return b.String()
+}
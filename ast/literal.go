@@ -2,15 +2,22 @@ package ast
 
 import (
 	"strconv"
-	"unicode"
 
 	"github.com/ear7h/lang/ast/parser"
 )
 
 type LiteralParser struct{}
 
-func (_ LiteralParser) Parse(c *parser.Cursor) (interface{}, bool) {
-	return parser.First(&StringLiteral{}, &NumberLiteral{}).Parse(c)
+func (p LiteralParser) Parse(c *parser.Cursor) (interface{}, bool) {
+	return p.grammar().Parse(c)
+}
+
+func (p LiteralParser) Syntax() *parser.Syntax {
+	return parser.SyntaxOf(p.grammar())
+}
+
+func (LiteralParser) grammar() parser.Parser {
+	return parser.First(&StringLiteral{}, &NumberLiteral{})
 }
 
 type StringLiteral struct {
@@ -21,38 +28,58 @@ type StringLiteral struct {
 }
 
 func (n *StringLiteral) Parse(c *parser.Cursor) (interface{}, bool) {
+	return parser.Lexeme(parser.ParserFunc(n.parse)).Parse(c)
+}
+
+func (*StringLiteral) Syntax() *parser.Syntax {
+	return &parser.Syntax{Op: parser.SyntaxClass, Text: "string"}
+}
+
+func (n *StringLiteral) parse(c *parser.Cursor) (interface{}, bool) {
 	n.setFileInfo(c)
 
+	if c.PeekRune() != StringQuote {
+		c.Expected("string")
+		return nil, false
+	}
+
 	var orig string
 
 	parsed, ok := parser.WriteTo(&orig,
 		parser.ParserFunc(func(c *parser.Cursor) (interface{}, bool) {
 			buf := ""
-			if c.ReadRune() != '"' {
+			if c.ReadRune() != StringQuote {
 				return nil, false
 			}
 
+			at := c.FileInfo()
 			r := c.ReadRune()
 
-			for ; r != '"'; r = c.ReadRune() {
+			for ; r != StringQuote; at, r = c.FileInfo(), c.ReadRune() {
+				if c.EOF() {
+					// unterminated
+					return nil, false
+				}
+
 				switch r {
 				case '\\':
 					r = c.ReadRune()
-					switch r {
-					case '\\':
-						r = '\\'
-					case 'n':
-						r = '\n'
-					case 't':
-						r = '\t'
-					default:
-						panic("unknwown escape sequece \\" + string(r))
+					if c.EOF() {
+						return nil, false
+					}
+
+					// an unknown escape stands for the rune after
+					// the backslash
+					if esc, ok := stringEscapes[r]; ok {
+						r = esc
+					} else {
+						c.Errorf(at, "unknown escape sequence \\%c", r)
 					}
 				}
 
 				buf += string(r)
 			}
-			if r != '"' {
+			if r != StringQuote {
 				return nil, false
 			}
 
@@ -65,6 +92,7 @@ func (n *StringLiteral) Parse(c *parser.Cursor) (interface{}, bool) {
 
 	n.Orig = string(orig)
 	n.Parsed = string(parsed.(string))
+	n.setEnd(c)
 
 	return n, true
 }
@@ -77,15 +105,28 @@ type NumberLiteral struct {
 }
 
 func (n *NumberLiteral) Parse(c *parser.Cursor) (interface{}, bool) {
+	return parser.Lexeme(parser.ParserFunc(n.parse)).Parse(c)
+}
+
+func (*NumberLiteral) Syntax() *parser.Syntax {
+	return &parser.Syntax{Op: parser.SyntaxClass, Text: "number"}
+}
+
+func (n *NumberLiteral) parse(c *parser.Cursor) (interface{}, bool) {
 	n.setFileInfo(c)
 
+	if !digits.Is(c.PeekRune()) {
+		c.Expected("number")
+		return nil, false
+	}
+
 	var orig string
 
 	parsed, ok := parser.WriteTo(&orig,
 		parser.ParserFunc(func(c *parser.Cursor) (interface{}, bool) {
 			buf := ""
 
-			for unicode.IsDigit(c.PeekRune()) {
+			for digits.Is(c.PeekRune()) {
 				buf += string(c.ReadRune())
 				if c.EOF() {
 					break
@@ -107,6 +148,7 @@ func (n *NumberLiteral) Parse(c *parser.Cursor) (interface{}, bool) {
 
 	n.Orig = string(orig)
 	n.Parsed = parsed.(int64)
+	n.setEnd(c)
 
 	return n, true
 }
@@ -5,6 +5,7 @@ import (
 
 	"github.com/ear7h/lang/ast"
 	"github.com/ear7h/lang/ast/parser"
+	"github.com/ear7h/lang/internal/assert"
 )
 
 func TestParseNumberLiteral(t *testing.T) {
@@ -21,17 +22,17 @@ func TestParseNumberLiteral(t *testing.T) {
 				parser.DoParseStringForTest(
 					&ast.NumberLiteral{}, tc.str, "test")
 
-			assertErrIs(t, tc.err, err)
-			assertEq(t, tc.ok, ok)
+			assert.ErrIs(t, tc.err, err)
+			assert.Eq(t, tc.ok, ok)
 			if !ok || err != nil {
 				return
 			}
 
 			n := v.(*ast.NumberLiteral)
 
-			assertEq(t, initCur.FileInfo(), n.FileInfo())
-			assertEq(t, tc.out.Orig, n.Orig)
-			assertEq(t, tc.out.Parsed, n.Parsed)
+			assert.Eq(t, initCur.FileInfo(), n.FileInfo())
+			assert.Eq(t, tc.out.Orig, n.Orig)
+			assert.Eq(t, tc.out.Parsed, n.Parsed)
 		}
 	}
 
@@ -66,17 +67,17 @@ func TestParseStringLiteral(t *testing.T) {
 				parser.DoParseStringForTest(
 					&ast.StringLiteral{}, tc.str, "test")
 
-			assertErrIs(t, tc.err, err)
-			assertEq(t, ok, tc.ok)
+			assert.ErrIs(t, tc.err, err)
+			assert.Eq(t, ok, tc.ok)
 			if !ok || err != nil {
 				return
 			}
 
 			n := v.(*ast.StringLiteral)
 
-			assertEq(t, initCur.FileInfo(), n.FileInfo())
-			assertEq(t, tc.out.Orig, n.Orig)
-			assertEq(t, tc.out.Parsed, n.Parsed)
+			assert.Eq(t, initCur.FileInfo(), n.FileInfo())
+			assert.Eq(t, tc.out.Orig, n.Orig)
+			assert.Eq(t, tc.out.Parsed, n.Parsed)
 		}
 	}
 
@@ -97,6 +98,14 @@ func TestParseStringLiteral(t *testing.T) {
 				Parsed: "asd\nqwe",
 			},
 		},
+		"unterminated": tcase{
+			str: `"asd`,
+			ok:  false,
+		},
+		"unterminated escape": tcase{
+			str: `"asd\`,
+			ok:  false,
+		},
 	}
 
 	for k, v := range tcases {
@@ -119,13 +128,13 @@ func TestParseLiteral(t *testing.T) {
 				parser.DoParseStringForTest(
 					&ast.LiteralParser{}, tc.str, "test")
 
-			assertErrIs(t, tc.err, err)
-			assertEq(t, tc.ok, ok)
+			assert.ErrIs(t, tc.err, err)
+			assert.Eq(t, tc.ok, ok)
 			if !ok || err != nil {
 				return
 			}
 
-			assertEq(t, tc.out, v)
+			assert.Eq(t, tc.out, v)
 		}
 	}
 
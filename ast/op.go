@@ -2,8 +2,10 @@ package ast
 
 import (
 	"fmt"
+	"sort"
 
 	"github.com/ear7h/lang/ast/parser"
+	"github.com/ear7h/lang/ast/parser/typed"
 )
 
 var _ = fmt.Println
@@ -33,30 +35,35 @@ type UnaryExpr struct {
 func (n *UnaryExpr) Parse(c *parser.Cursor) (interface{}, bool) {
 	n.setFileInfo(c)
 
-	r := c.PeekRune()
-
-	found := false
-	for _, v := range unaryOperators {
-		if r == v {
-			found = true
-			n.Op = c.ReadRune()
-			break
-		}
-	}
-
-	if !found {
-		return nil, false
-	}
-
-	var ok bool
-	n.Operand, ok = ExprParser{}.Parse(c)
+	v, ok := n.grammar().Parse(c)
 	if !ok {
 		return nil, false
 	}
 
+	slc := v.([]interface{})
+	n.Op = slc[0].(rune)
+	n.Operand = slc[1]
+	n.setEnd(c)
+
 	return n, true
 }
 
+func (n *UnaryExpr) Syntax() *parser.Syntax {
+	return parser.SyntaxOf(n.grammar())
+}
+
+func (*UnaryExpr) grammar() parser.Parser {
+	ops := make([]parser.Parser, len(unaryOperators))
+	for i, v := range unaryOperators {
+		ops[i] = parser.ExpectRune(v)
+	}
+
+	return parser.All(
+		parser.First(ops...),
+		ExprParser{},
+	)
+}
+
 const (
 	// arith
 	BinaryAdd = "+"
@@ -100,71 +107,155 @@ type BinaryExpr struct {
 	Left, Right interface{}
 }
 
-func (_ *BinaryExpr) Parse(c *parser.Cursor) (interface{}, bool) {
+// binaryPrecedenceNames are the grammar rules of the levels of
+// binaryPrecedence
+var binaryPrecedenceNames = []string{
+	"bit-expr",
+	"mul-expr",
+	"add-expr",
+	"cmp-expr",
+	"bool-expr",
+}
 
-	precs := [][]string{
-		{
-			BinaryShr, BinaryShl, BinaryBitAnd, BinaryBitOr, BinaryBitXor,
-		},
-		{
-			BinaryMul, BinaryDiv, BinaryMod,
-		},
-		{
-			BinaryAdd, BinarySub,
-		},
-		{
-			BinaryLt, BinaryGt, BinaryLte, BinaryGte, BinaryEq, BinaryNeq,
-		},
-		{
-			BinaryBoolAnd, BinaryBoolOr,
-		},
+// binaryPrecedence lists the binary operators from the highest
+// precedence to the lowest
+var binaryPrecedence = [][]string{
+	{
+		BinaryShr, BinaryShl, BinaryBitAnd, BinaryBitOr, BinaryBitXor,
+	},
+	{
+		BinaryMul, BinaryDiv, BinaryMod,
+	},
+	{
+		BinaryAdd, BinarySub,
+	},
+	{
+		BinaryLt, BinaryGt, BinaryLte, BinaryGte, BinaryEq, BinaryNeq,
+	},
+	{
+		BinaryBoolAnd, BinaryBoolOr,
+	},
+}
+
+// UnaryOperators returns the unary operators
+func UnaryOperators() []rune {
+	return append([]rune(nil), unaryOperators...)
+}
+
+// BinaryOperators returns the binary operators, from the highest
+// precedence to the lowest
+func BinaryOperators() []string {
+	var ret []string
+	for _, v := range binaryPrecedence {
+		ret = append(ret, v...)
+	}
+
+	return ret
+}
+
+// BinaryPrecedence returns the precedence of the binary operator op,
+// operators with a higher precedence bind tighter. It returns 0 if op
+// is not a binary operator.
+func BinaryPrecedence(op string) int {
+	for i, v := range binaryPrecedence {
+		for _, vv := range v {
+			if vv == op {
+				return len(binaryPrecedence) - i
+			}
+		}
 	}
 
+	return 0
+}
+
+func (n *BinaryExpr) Parse(c *parser.Cursor) (interface{}, bool) {
+	return n.grammar().Parse(c)
+}
+
+func (n *BinaryExpr) Syntax() *parser.Syntax {
+	return parser.SyntaxOf(n.grammar())
+}
+
+func (*BinaryExpr) grammar() parser.Parser {
 	lower := func() parser.Parser {
-		return ExprOperandParser{}
+		return parser.Named("operand", ExprOperandParser{})
 	}
 
-	for _, v := range precs {
-		vv := v
+	for i, v := range binaryPrecedence {
+		name, vv := binaryPrecedenceNames[i], v
 		tmp := lower
 		lower = func() parser.Parser {
-			return parser.First(
-				BinaryExprPrecedenceGroup(tmp, vv...),
-				tmp(),
-			)
+			return parser.Named(name, BinaryExprPrecedenceGroup(tmp, vv...))
 		}
 	}
 
-	return lower().Parse(c)
+	return lower()
 }
 
+// BinaryExprPrecedenceGroup returns a parser for the left associative
+// binary operators ops, whose operands are parsed by lower. A lone
+// operand is returned as is.
 func BinaryExprPrecedenceGroup(lower func() parser.Parser,
 	ops ...string) parser.Parser {
-	return parser.ParserFunc(func(c *parser.Cursor) (interface{}, bool) {
-		var (
-			n  BinaryExpr
-			ok bool
-		)
-
-		n.setFileInfo(c)
-
-		v, ok := parser.All(
-			lower(),
-			parser.WS(),
-			parser.FirstString(ops...),
-			parser.WS(),
-			lower(),
-		).Parse(c)
-		if !ok {
-			return nil, false
-		}
 
-		slc := v.([]interface{})
+	// an operator must be on the same line as its left operand, a
+	// newline before it ends the statement
+	hs := typed.Lift[string](parser.HS())
+	ws := typed.Lift[string](parser.WS())
 
-		n.Left = slc[0]
-		n.Op = slc[2].(string)
-		n.Right = slc[4]
-
-		return &n, true
+	// match the longest operator first, so "<=" isn't taken for "<"
+	ops = append([]string(nil), ops...)
+	sort.SliceStable(ops, func(i, j int) bool {
+		return len(ops[i]) > len(ops[j])
 	})
+
+	op := typed.Seq3(hs, typed.FirstString(ops...), ws,
+		func(_, op, _ string) func(left, right operand) operand {
+			return func(left, right operand) operand {
+				n := &BinaryExpr{
+					Op:    op,
+					Left:  left.x,
+					Right: right.x,
+				}
+				n.setFi(left.from)
+				n.EndFi = right.to
+
+				return operand{x: n, from: left.from, to: right.to}
+			}
+		},
+	)
+
+	return typed.Erase(typed.Map(
+		typed.ChainL1[operand](operandParser{lower()}, op),
+		func(v operand) interface{} {
+			return v.x
+		},
+	))
+}
+
+// operand is an operand of a binary expression and its span, which
+// takes in the parentheses around it
+type operand struct {
+	x        interface{}
+	from, to parser.FileInfo
+}
+
+// operandParser parses an operand with p
+type operandParser struct {
+	p parser.Parser
+}
+
+func (p operandParser) Parse(c *parser.Cursor) (operand, bool) {
+	from := c.FileInfo()
+
+	v, ok := p.p.Parse(c)
+	if !ok {
+		return operand{}, false
+	}
+
+	return operand{x: v, from: from, to: c.End()}, true
+}
+
+func (p operandParser) Syntax() *parser.Syntax {
+	return parser.SyntaxOf(p.p)
 }
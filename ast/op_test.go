@@ -5,6 +5,7 @@ import (
 
 	"github.com/ear7h/lang/ast"
 	"github.com/ear7h/lang/ast/parser"
+	"github.com/ear7h/lang/internal/assert"
 )
 
 func TestParseUnaryExpr(t *testing.T) {
@@ -20,17 +21,17 @@ func TestParseUnaryExpr(t *testing.T) {
 			initCur, v, ok, err :=
 				parser.DoParseStringForTest(&ast.UnaryExpr{}, tc.str, "test")
 
-			assertErrIs(t, tc.err, err)
-			assertEq(t, tc.ok, ok)
+			assert.ErrIs(t, tc.err, err)
+			assert.Eq(t, tc.ok, ok)
 			if !ok || err != nil {
 				return
 			}
 
 			n := v.(*ast.UnaryExpr)
 
-			assertEq(t, initCur.FileInfo(), n.FileInfo())
-			assertEq(t, tc.out.Op, n.Op)
-			assertEq(t, tc.out.Operand, n.Operand)
+			assert.Eq(t, initCur.FileInfo(), n.FileInfo())
+			assert.Eq(t, tc.out.Op, n.Op)
+			assert.Eq(t, tc.out.Operand, n.Operand)
 		}
 	}
 
@@ -129,8 +130,8 @@ func TestParseBinaryExpr(t *testing.T) {
 			initCur, v, ok, err :=
 				parser.DoParseStringForTest(&ast.BinaryExpr{}, tc.str, "test")
 
-			assertErrIs(t, tc.err, err)
-			assertEq(t, tc.ok, ok)
+			assert.ErrIs(t, tc.err, err)
+			assert.Eq(t, tc.ok, ok)
 			if !ok || err != nil {
 				return
 			}
@@ -138,16 +139,16 @@ func TestParseBinaryExpr(t *testing.T) {
 			n, ok := v.(*ast.BinaryExpr)
 			if !ok {
 				// number literals by themselves
-				assertEq(t, tc.out, v)
+				assert.Eq(t, tc.out, v)
 				return
 			}
 
 			tcout := tc.out.(*ast.BinaryExpr)
 
-			assertEq(t, initCur.FileInfo(), n.FileInfo())
-			assertEq(t, tcout.Op, n.Op)
-			assertEq(t, tcout.Left, n.Left)
-			assertEq(t, tcout.Right, n.Right)
+			assert.Eq(t, initCur.FileInfo(), n.FileInfo())
+			assert.Eq(t, tcout.Op, n.Op)
+			assert.Eq(t, tcout.Left, n.Left)
+			assert.Eq(t, tcout.Right, n.Right)
 		}
 	}
 
@@ -234,6 +235,43 @@ func TestParseBinaryExpr(t *testing.T) {
 				),
 			},
 		},
+		"lte": tcase{
+			str: `1 <= 2`,
+			ok:  true,
+			out: &ast.BinaryExpr{
+				Op: "<=",
+				Left: parser.MustParseString(
+					&ast.NumberLiteral{},
+					"1",
+				),
+				Right: parser.MustParseString(
+					&ast.NumberLiteral{},
+					"2",
+				),
+			},
+		},
+		"left assoc": tcase{
+			str: `1 - 2 + 3`,
+			ok:  true,
+			out: &ast.BinaryExpr{
+				Op: "+",
+				Left: &ast.BinaryExpr{
+					Op: "-",
+					Left: parser.MustParseString(
+						&ast.NumberLiteral{},
+						"1",
+					),
+					Right: parser.MustParseString(
+						&ast.NumberLiteral{},
+						"2",
+					),
+				},
+				Right: parser.MustParseString(
+					&ast.NumberLiteral{},
+					"3",
+				),
+			},
+		},
 		"add": tcase{
 			str: `1<<2*3+4`,
 			ok:  true,
@@ -270,3 +308,18 @@ func TestParseBinaryExpr(t *testing.T) {
 	}
 
 }
+
+func TestBinaryPrecedence(t *testing.T) {
+	tcases := map[string]int{
+		"<<": 5,
+		"*":  4,
+		"-":  3,
+		"==": 2,
+		"||": 1,
+		".":  0,
+	}
+
+	for k, v := range tcases {
+		assert.Eq(t, v, ast.BinaryPrecedence(k))
+	}
+}
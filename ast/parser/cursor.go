@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"os"
 	"strings"
 	"unicode/utf8"
 )
@@ -14,14 +15,42 @@ type FileInfo struct {
 	Col  int64
 }
 
+func (fi FileInfo) String() string {
+	return fmt.Sprintf("%s:%d:%d", fi.Name, fi.Line, fi.Col)
+}
+
 func NewCursorString(s string, name string) *Cursor {
-	return &Cursor{
-		r:    strings.NewReader(s),
+	return NewCursor(strings.NewReader(s), name)
+}
+
+// NewCursor returns a cursor reading the runes of the file name from r,
+// from its start
+func NewCursor(r io.ReaderAt, name string) *Cursor {
+	c := &Cursor{
+		r:    r,
 		i:    0,
 		name: name,
 		line: 1,
 		col:  1,
+		fail: &failure{},
+	}
+
+	if Debug {
+		c.trace = NewTextTracer(os.Stderr)
 	}
+
+	return c
+}
+
+// NewCursorStringAt is like NewCursorString, but the cursor starts at
+// the byte offset off of s, whose position is fi
+func NewCursorStringAt(s string, off int64, fi FileInfo) *Cursor {
+	c := NewCursorString(s, fi.Name)
+	c.i = off
+	c.line = fi.Line
+	c.col = fi.Col
+
+	return c
 }
 
 type Cursor struct {
@@ -31,6 +60,15 @@ type Cursor struct {
 	name string
 	line int64
 	col  int64
+
+	errs  ErrorList
+	fail  *failure
+	trace Tracer
+
+	// token level cursors, see NewCursorTokens
+	tokens bool
+	toks   []Token
+	end    FileInfo
 }
 
 func (c *Cursor) Fatalf(f string, v ...interface{}) {
@@ -43,6 +81,9 @@ func (c Cursor) Fatal(v interface{}) {
 
 // reads the next rune
 func (c *Cursor) readRune() rune {
+	if c.tokens {
+		c.Fatal("parser: reading runes from a token cursor")
+	}
 
 	buf := make([]byte, 4)
 	n, err := c.r.ReadAt(buf, c.i)
@@ -67,7 +108,7 @@ func (c *Cursor) readRune() rune {
 	c.i += int64(n)
 	if r == '\n' {
 		c.line++
-		c.col = 0
+		c.col = 1
 	} else {
 		c.col++
 	}
@@ -75,16 +116,15 @@ func (c *Cursor) readRune() rune {
 	return r
 }
 
-// reads an n length string starting at off
-// this method mutates the internal cursor, so use wisely
+// reads the n bytes starting at off, without moving the cursor
 func (c *Cursor) stringAt(off, n int64) string {
-	c.eof = false
-	c.i = off
-	ret := ""
-	for ; n > 0; n-- {
-		ret += string(c.readRune())
+	buf := make([]byte, n)
+	_, err := c.r.ReadAt(buf, off)
+	if err != nil && !errors.Is(err, io.EOF) {
+		c.Fatal(err)
 	}
-	return ret
+
+	return string(buf)
 }
 
 func (c *Cursor) ReadRune() rune {
@@ -97,10 +137,86 @@ func (c *Cursor) PeekRune() rune {
 }
 
 func (c *Cursor) EOF() bool {
+	if c.tokens {
+		return c.i >= int64(len(c.toks))
+	}
+
 	return c.eof
 }
 
+// atEOF reports whether the next read would be past the end of the
+// input, unlike EOF which reports whether it already was.
+func (c *Cursor) atEOF() bool {
+	if c.eof || c.tokens {
+		return c.EOF()
+	}
+
+	cc := *c
+	cc.readRune()
+	return cc.eof
+}
+
+// atAny reports whether the input at c starts with any of slc
+func (c *Cursor) atAny(slc []string) bool {
+	for _, v := range slc {
+		cc := *c
+		cc.fail = nil
+		if _, ok := ExpectString(v).Parse(&cc); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// skip moves the cursor forward by a rune, or a token
+func (c *Cursor) skip() {
+	if c.tokens {
+		c.i++
+		return
+	}
+
+	c.readRune()
+}
+
+// End returns the position just after the input read so far. On a rune
+// level cursor it is the same as FileInfo, but on a token level cursor
+// it is the end of the last token read rather than the start of the
+// next one.
+func (c *Cursor) End() FileInfo {
+	if !c.tokens || c.i == 0 {
+		return c.FileInfo()
+	}
+
+	tok := c.toks[c.i-1]
+	fi := tok.FileInfo()
+	for _, r := range tok.Text() {
+		if r == '\n' {
+			fi.Line++
+			fi.Col = 1
+		} else {
+			fi.Col++
+		}
+	}
+
+	return fi
+}
+
+// Offset returns the byte offset of c in the input, or the index of
+// the current token on a token level cursor
+func (c *Cursor) Offset() int64 {
+	return c.i
+}
+
 func (c *Cursor) FileInfo() FileInfo {
+	if c.tokens {
+		if c.EOF() {
+			return c.end
+		}
+
+		return c.toks[c.i].FileInfo()
+	}
+
 	return FileInfo{
 		Name: c.name,
 		Line: c.line,
@@ -0,0 +1,238 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// The ops of a Syntax
+const (
+	SyntaxString    = "string"    // Text is matched as is
+	SyntaxClass     = "class"     // Text names a lexical class
+	SyntaxSpace     = "space"     // Text names a class of white space
+	SyntaxRef       = "ref"       // Text names a rule
+	SyntaxSeq       = "seq"       // Args in sequence
+	SyntaxAlt       = "alt"       // one of Args
+	SyntaxOpt       = "opt"       // Args[0] or nothing
+	SyntaxMany      = "many"      // Args[0] zero or more times
+	SyntaxMany1     = "many1"     // Args[0] one or more times
+	SyntaxLookahead = "lookahead" // Args[0] follows, but isn't consumed
+	SyntaxNot       = "not"       // Args[0] doesn't follow
+)
+
+// Syntax describes what a parser matches. Its JSON encoding is a tree
+// of ops which can be drawn as a railroad diagram.
+type Syntax struct {
+	Op   string    `json:"op"`
+	Text string    `json:"text,omitempty"`
+	Args []*Syntax `json:"args,omitempty"`
+
+	// the parser of a rule
+	rule Parser
+}
+
+// Describer is implemented by parsers which can describe their syntax.
+// The combinators in this package are describers, so grammars built
+// from them describe themselves.
+type Describer interface {
+	Syntax() *Syntax
+}
+
+// SyntaxOf returns the syntax of p, parsers which are not describers
+// are described by their type as a lexical class.
+func SyntaxOf(p interface{}) *Syntax {
+	if d, ok := p.(Describer); ok {
+		return d.Syntax()
+	}
+
+	return &Syntax{Op: SyntaxClass, Text: fmt.Sprintf("%T", p)}
+}
+
+// described is a parser which carries its syntax
+type described struct {
+	ParserFunc
+	syntax func() *Syntax
+}
+
+func (d described) Syntax() *Syntax {
+	return d.syntax()
+}
+
+// describe attaches a syntax to fn, it is only built when asked for so
+// grammars can be recursive
+func describe(fn ParserFunc, syntax func() *Syntax) Parser {
+	return described{ParserFunc: fn, syntax: syntax}
+}
+
+// syntaxOp returns a syntax with the op, whose args are the syntax of ps
+func syntaxOp(op string, ps ...Parser) *Syntax {
+	ret := &Syntax{Op: op}
+	for _, v := range ps {
+		ret.Args = append(ret.Args, SyntaxOf(v))
+	}
+
+	return ret
+}
+
+// Lexical returns p described as the lexical class name, for parsers
+// which are not built from combinators
+func Lexical(name string, p Parser) Parser {
+	return describe(p.Parse, func() *Syntax {
+		return &Syntax{Op: SyntaxClass, Text: name}
+	})
+}
+
+// Rule is a named parser in a grammar
+type Rule struct {
+	Name   string  `json:"name"`
+	Syntax *Syntax `json:"syntax"`
+}
+
+// Grammar is the set of rules a parser is made of
+type Grammar struct {
+	Rules []Rule `json:"rules"`
+}
+
+// Describe returns the grammar of p. Each Named parser reachable from p
+// is a rule, the first being p itself, or a rule named "start" if p is
+// not named.
+func Describe(p Parser) *Grammar {
+	var (
+		g    Grammar
+		seen = map[string]bool{}
+		refs []*Syntax
+		walk func(s *Syntax)
+	)
+
+	walk = func(s *Syntax) {
+		if s.Op == SyntaxRef {
+			if !seen[s.Text] {
+				seen[s.Text] = true
+				refs = append(refs, s)
+			}
+			return
+		}
+
+		for _, v := range s.Args {
+			walk(v)
+		}
+	}
+
+	start := SyntaxOf(p)
+	if start.Op == SyntaxRef {
+		walk(start)
+	} else {
+		g.Rules = append(g.Rules, Rule{Name: "start", Syntax: start})
+		walk(start)
+	}
+
+	for i := 0; i < len(refs); i++ {
+		s := SyntaxOf(refs[i].rule)
+		g.Rules = append(g.Rules, Rule{Name: refs[i].Text, Syntax: s})
+		walk(s)
+	}
+
+	return &g
+}
+
+// WriteJSON writes the grammar as JSON
+func (g *Grammar) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+
+	return enc.Encode(g)
+}
+
+// WriteEBNF writes the grammar in ISO EBNF, with lexical classes as
+// special sequences. White space between tokens is left out, and
+// lookaheads are written as comments since EBNF can't express them.
+func (g *Grammar) WriteEBNF(w io.Writer) error {
+	var b strings.Builder
+
+	for _, v := range g.Rules {
+		fmt.Fprintf(&b, "%s = %s ;\n", v.Name, ebnf(v.Syntax, 0))
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// the binding strength of EBNF operators, weaker ones are grouped in
+// parentheses when nested in stronger ones
+const (
+	ebnfAlt = iota
+	ebnfSeq
+)
+
+// ebnf returns s in EBNF, prec is the binding strength of the operator
+// s is nested in
+func ebnf(s *Syntax, prec int) string {
+	group := func(p int, str string) string {
+		if p < prec {
+			return "( " + str + " )"
+		}
+
+		return str
+	}
+
+	join := func(p int, sep string, args []*Syntax) string {
+		var slc []string
+		for _, v := range nonSpace(args) {
+			slc = append(slc, ebnf(v, p))
+		}
+
+		return strings.Join(slc, sep)
+	}
+
+	switch s.Op {
+	case SyntaxString:
+		if strings.Contains(s.Text, `"`) {
+			return "'" + s.Text + "'"
+		}
+		return `"` + s.Text + `"`
+	case SyntaxClass:
+		return "? " + s.Text + " ?"
+	case SyntaxSpace:
+		return ""
+	case SyntaxRef:
+		return s.Text
+	case SyntaxSeq:
+		args := nonSpace(s.Args)
+		if len(args) == 1 {
+			return ebnf(args[0], prec)
+		}
+		return group(ebnfSeq, join(ebnfSeq, " , ", args))
+	case SyntaxAlt:
+		if len(s.Args) == 1 {
+			return ebnf(s.Args[0], prec)
+		}
+		return group(ebnfAlt, join(ebnfAlt, " | ", s.Args))
+	case SyntaxOpt:
+		return "[ " + ebnf(s.Args[0], ebnfAlt) + " ]"
+	case SyntaxMany:
+		return "{ " + ebnf(s.Args[0], ebnfAlt) + " }"
+	case SyntaxMany1:
+		return group(ebnfSeq, ebnf(s.Args[0], ebnfSeq)+
+			" , { "+ebnf(s.Args[0], ebnfAlt)+" }")
+	case SyntaxLookahead:
+		return "(* followed by " + ebnf(s.Args[0], ebnfAlt) + " *)"
+	case SyntaxNot:
+		return "(* not followed by " + ebnf(s.Args[0], ebnfAlt) + " *)"
+	}
+
+	return "? " + s.Op + " ?"
+}
+
+// nonSpace returns the args which are not white space
+func nonSpace(args []*Syntax) []*Syntax {
+	var ret []*Syntax
+	for _, v := range args {
+		if v.Op != SyntaxSpace {
+			ret = append(ret, v)
+		}
+	}
+
+	return ret
+}
@@ -0,0 +1,129 @@
+package parser_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ear7h/lang/ast/parser"
+	"github.com/ear7h/lang/internal/assert"
+)
+
+func TestDescribe(t *testing.T) {
+	type tcase struct {
+		p   parser.Parser
+		out string
+	}
+
+	fn := func(tc tcase) func(t *testing.T) {
+		return func(t *testing.T) {
+			var b strings.Builder
+			err := parser.Describe(tc.p).WriteEBNF(&b)
+			assert.Eq(t, nil, err)
+			assert.Eq(t, tc.out, b.String())
+		}
+	}
+
+	// a list of a's nested in parentheses
+	var list parser.Parser
+	list = parser.Named("list", parser.Braced(
+		parser.ExpectString("("),
+		parser.SepBy(
+			parser.First(
+				parser.ExpectString("a"),
+				parser.ParserFunc(func(c *parser.Cursor) (interface{}, bool) {
+					return list.Parse(c)
+				}),
+			),
+			parser.ExpectString(","),
+		),
+		parser.ExpectString(")"),
+	))
+
+	tcases := map[string]tcase{
+		"start": tcase{
+			p:   parser.All(parser.ExpectString("a"), parser.WS(), parser.ExpectEOF()),
+			out: `start = "a" , ? end of file ? ;` + "\n",
+		},
+		"named": tcase{
+			p:   parser.Named("a", parser.Maybe(parser.FirstString("b", `"`))),
+			out: `a = [ "b" | '"' ] ;` + "\n",
+		},
+		"rules": tcase{
+			p: parser.Named("a", parser.Many1(parser.All(
+				parser.Named("b", parser.ExpectRune('b')),
+				parser.First(
+					parser.Named("b", parser.ExpectRune('c')),
+					parser.Lexical("digit", parser.ReadRune()),
+				),
+			))),
+			out: `a = b , ( b | ? digit ? ) , { b , ( b | ? digit ? ) } ;` + "\n" +
+				`b = "b" ;` + "\n",
+		},
+		"lookahead": tcase{
+			p: parser.All(
+				parser.ExpectString("a"),
+				parser.NotFollowedBy(parser.ExpectString("b")),
+				parser.Lookahead(parser.ExpectString("c")),
+			),
+			out: `start = "a" , (* not followed by "b" *) , ` +
+				`(* followed by "c" *) ;` + "\n",
+		},
+		"undescribed": tcase{
+			p:   parser.Named("a", parser.ParserFunc(nil)),
+			out: `a = ? parser.ParserFunc ? ;` + "\n",
+		},
+		"recursive": tcase{
+			p: parser.Named("top", parser.AllIdx(0, list, parser.ExpectEOF())),
+			out: `top = list , ? end of file ? ;` + "\n" +
+				`list = "(" , [ ( "a" | ? parser.ParserFunc ? ) , ` +
+				`{ "," , ( "a" | ? parser.ParserFunc ? ) } ] , ")" ;` + "\n",
+		},
+	}
+
+	for k, v := range tcases {
+		t.Run(k, fn(v))
+	}
+}
+
+func TestDescribeJSON(t *testing.T) {
+	var b strings.Builder
+	err := parser.Describe(parser.Named("a", parser.All(
+		parser.ExpectString("b"),
+		parser.Many(parser.Named("c", parser.ExpectString("c"))),
+	))).WriteJSON(&b)
+	assert.Eq(t, nil, err)
+
+	assert.Eq(t, `{
+	"rules": [
+		{
+			"name": "a",
+			"syntax": {
+				"op": "seq",
+				"args": [
+					{
+						"op": "string",
+						"text": "b"
+					},
+					{
+						"op": "many",
+						"args": [
+							{
+								"op": "ref",
+								"text": "c"
+							}
+						]
+					}
+				]
+			}
+		},
+		{
+			"name": "c",
+			"syntax": {
+				"op": "string",
+				"text": "c"
+			}
+		}
+	]
+}
+`, b.String())
+}
@@ -0,0 +1,25 @@
+// Package parser is a parser combinator library over a rune Cursor.
+//
+// A Parser either matches, returning its result and true, or fails,
+// returning false. A parser that matches moves the cursor past the
+// input it matched. A parser that fails may or may not have moved the
+// cursor, this is what tells backtracking and committed parsers apart:
+//
+//   - primitive parsers (ExpectRune, ExpectString, FirstString,
+//     PlusPred, ExpectEOF) never consume anything when they fail
+//   - First, Maybe and the repetition combinators (Many, SepBy,
+//     ChainL1, ...) backtrack, an alternative or repetition that fails
+//     is undone as if it had been wrapped in Try
+//   - All and AllIdx do not backtrack, when an element fails the cursor
+//     is left after the last element that matched
+//   - Choice commits to the first alternative that consumes input, it
+//     fails with that alternative rather than trying the others
+//   - Try undoes whatever its parser consumed when it fails
+//   - Lookahead and NotFollowedBy never consume anything
+//
+// Parser results that fail are unspecified, they are usually nil.
+//
+// The combinators also describe the syntax they match, so the grammar
+// of a parser built from them can be written out as EBNF or JSON with
+// Describe. Named parsers are the rules of the grammar.
+package parser
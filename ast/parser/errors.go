@@ -0,0 +1,188 @@
+package parser
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Error is a syntax error recorded while parsing
+type Error struct {
+	Fi  FileInfo
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Fi, e.Msg)
+}
+
+// ErrorList is the list of errors recorded on a cursor, in the order
+// they were recorded
+type ErrorList []*Error
+
+// Sort sorts the list by position, errors at the same position are
+// kept in the order they were recorded
+func (l ErrorList) Sort() {
+	sort.SliceStable(l, func(i, j int) bool {
+		a, b := l[i].Fi, l[j].Fi
+		switch {
+		case a.Name != b.Name:
+			return a.Name < b.Name
+		case a.Line != b.Line:
+			return a.Line < b.Line
+		}
+
+		return a.Col < b.Col
+	})
+}
+
+func (l ErrorList) Error() string {
+	switch len(l) {
+	case 0:
+		return "no errors"
+	case 1:
+		return l[0].Error()
+	}
+
+	return fmt.Sprintf("%s (and %d more errors)", l[0], len(l)-1)
+}
+
+// Err returns nil if the list is empty, and the list otherwise
+func (l ErrorList) Err() error {
+	if len(l) == 0 {
+		return nil
+	}
+
+	return l
+}
+
+// failure tracks the furthest position any parser failed at and what
+// was expected there. It is shared between copies of a cursor so it
+// survives backtracking, which makes it a good guess for where a syntax
+// error actually is.
+type failure struct {
+	set    bool
+	at     Cursor
+	expect []string
+
+	// failures past limit are taken to be at limit, if bounded
+	bounded bool
+	limit   Cursor
+}
+
+// Expected notes that the input at c's position did not match what.
+// Parsers that fail without consuming input should call it so that
+// syntax errors can say what was expected.
+func (c *Cursor) Expected(what string) {
+	f := c.fail
+	if f == nil {
+		return
+	}
+
+	f.merge(failure{
+		set:    true,
+		at:     *c,
+		expect: []string{what},
+	})
+}
+
+// merge keeps the furthest of f and o, or both their expectations if
+// they're at the same position
+func (f *failure) merge(o failure) {
+	if f.bounded && o.at.i > f.limit.i {
+		o.at = f.limit
+	}
+
+	switch {
+	case !o.set:
+	case !f.set || o.at.i > f.at.i:
+		f.set, f.at, f.expect = true, o.at, o.expect
+	case o.at.i == f.at.i:
+	outer:
+		for _, v := range o.expect {
+			for _, vv := range f.expect {
+				if v == vv {
+					continue outer
+				}
+			}
+			f.expect = append(f.expect, v)
+		}
+	}
+}
+
+// Errorf records an error at fi, unlike Fatalf parsing carries on
+func (c *Cursor) Errorf(fi FileInfo, f string, v ...interface{}) {
+	c.addError(&Error{Fi: fi, Msg: fmt.Sprintf(f, v...)})
+}
+
+func (c *Cursor) addError(err *Error) {
+	// the slice is shared with copies of the cursor, so always
+	// reallocate to keep backtracking from clobbering errors
+	n := len(c.errs)
+	c.errs = append(c.errs[:n:n], err)
+}
+
+// Errors returns the errors recorded on the cursor
+func (c *Cursor) Errors() ErrorList {
+	return c.errs
+}
+
+// syntaxError builds an error for a parser that failed starting at c,
+// the error is placed at the furthest failure past c, if any.
+func (c *Cursor) syntaxError() *Error {
+	at := *c
+	var expect []string
+
+	if f := c.fail; f != nil && f.set && f.at.i >= c.i {
+		at = f.at
+		expect = f.expect
+	}
+
+	msg := "syntax error: unexpected " + at.describe()
+	if len(expect) > 0 {
+		msg += ", expected " + strings.Join(expect, " or ")
+	}
+
+	return &Error{Fi: at.FileInfo(), Msg: msg}
+}
+
+// describe returns a description of the input at c, for error messages
+func (c Cursor) describe() string {
+	if c.EOF() {
+		return "end of file"
+	}
+
+	if c.tokens {
+		if c.toks[c.i].Text() == "\n" {
+			return "newline"
+		}
+
+		return fmt.Sprintf("%q", c.toks[c.i].Text())
+	}
+
+	r := c.readRune()
+	switch {
+	case c.eof:
+		return "end of file"
+	case r == '\n':
+		return "newline"
+	}
+
+	isWord := func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+	}
+
+	s := string(r)
+	for isWord(r) {
+		cc := c
+		r = cc.readRune()
+		if cc.eof || !isWord(r) {
+			break
+		}
+		s += string(r)
+		c = cc
+	}
+
+	return fmt.Sprintf("%q", s)
+}
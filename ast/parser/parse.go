@@ -2,15 +2,31 @@ package parser
 
 import "fmt"
 
+// ExpectString returns a parser that matches s, it does not consume
+// anything if it fails.
 func ExpectString(s string) Parser {
-	return ParserFunc(func(c *Cursor) (interface{}, bool) {
+	return describe(func(c *Cursor) (interface{}, bool) {
+		start := *c
+		if c.tokens {
+			if !c.expectToken(s) {
+				c.Expected(fmt.Sprintf("%q", s))
+				return nil, false
+			}
+
+			return s, true
+		}
+
 		for _, v := range s {
-			if v != c.ReadRune() {
+			if c.atEOF() || v != c.ReadRune() {
+				*c = start
+				c.Expected(fmt.Sprintf("%q", s))
 				return nil, false
 			}
 		}
 
 		return s, true
+	}, func() *Syntax {
+		return &Syntax{Op: SyntaxString, Text: s}
 	})
 }
 
@@ -27,6 +43,12 @@ func MustParseString(p Parser, s string) interface{} {
 }
 
 func DoParseString(p Parser, s string, name string) (v interface{}, ok bool, err error) {
+	return DoParse(p, NewCursorString(s, name))
+}
+
+// DoParse parses p from c, the returned error is either the panic of a
+// fatal error or the errors recorded on c, sorted by position
+func DoParse(p Parser, c *Cursor) (v interface{}, ok bool, err error) {
 	defer func() {
 		if r := recover(); r != nil {
 			if rerr, ok := r.(error); ok {
@@ -37,8 +59,9 @@ func DoParseString(p Parser, s string, name string) (v interface{}, ok bool, err
 		}
 	}()
 
-	v, ok = p.Parse(NewCursorString(s, name))
-	return v, ok, err
+	v, ok = p.Parse(c)
+	c.errs.Sort()
+	return v, ok, c.Errors().Err()
 }
 
 func DoParseStringForTest(p Parser, s string, name string) (initCur Cursor, v interface{}, ok bool, err error) {
@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"fmt"
 	"unicode"
 )
 
@@ -15,25 +16,69 @@ func (fn ParserFunc) Parse(c *Cursor) (ret interface{}, ok bool) {
 }
 
 
+// ReadRune returns a parser that matches any rune
 func ReadRune() Parser {
-	return ParserFunc(func(c *Cursor) (interface{}, bool) {
+	return describe(func(c *Cursor) (interface{}, bool) {
 		return c.readRune(), true
+	}, func() *Syntax {
+		return &Syntax{Op: SyntaxClass, Text: "any rune"}
 	})
 }
 
+// ExpectRune returns a parser that matches expect, it does not consume
+// anything if it fails.
 func ExpectRune(expect rune) Parser {
-	return ParserFunc(func(c *Cursor) (interface{}, bool) {
+	return describe(func(c *Cursor) (interface{}, bool) {
+		start := *c
+		if c.tokens {
+			if !c.expectToken(string(expect)) {
+				c.Expected(fmt.Sprintf("%q", string(expect)))
+				return nil, false
+			}
+
+			return expect, true
+		}
+
 		r := c.readRune()
 		if r != expect {
+			*c = start
+			c.Expected(fmt.Sprintf("%q", string(expect)))
 			return nil, false
 		}
 
 		return r, true
+	}, func() *Syntax {
+		return &Syntax{Op: SyntaxString, Text: string(expect)}
 	})
 }
 
+// First returns a parser that returns the result of the first of p to
+// match. It backtracks: every alternative is tried from the same
+// position, and it does not consume anything if they all fail.
 func First(p ...Parser) Parser {
-	return ParserFunc(func(c *Cursor) (interface{}, bool) {
+	return describe(func(c *Cursor) (interface{}, bool) {
+		for _, v := range p {
+			cc := *c
+			ret, ok := v.Parse(&cc)
+			if ok {
+				*c = cc
+				return ret, true
+			}
+		}
+
+		return nil, false
+	}, func() *Syntax {
+		return syntaxOp(SyntaxAlt, p...)
+	})
+}
+
+// Choice is like First, but commits to an alternative once it has
+// consumed input, the next alternative is only tried if the previous
+// one failed without consuming anything. This keeps the error of a
+// partially matched alternative from being swallowed by the others,
+// alternatives that should backtrack need to be wrapped in Try.
+func Choice(p ...Parser) Parser {
+	return describe(func(c *Cursor) (interface{}, bool) {
 		for _, v := range p {
 			cc := *c
 			ret, ok := v.Parse(&cc)
@@ -41,14 +86,33 @@ func First(p ...Parser) Parser {
 				*c = cc
 				return ret, true
 			}
+
+			if cc.i != c.i {
+				*c = cc
+				return nil, false
+			}
 		}
 
 		return nil, false
+	}, func() *Syntax {
+		return syntaxOp(SyntaxAlt, p...)
+	})
+}
+
+// Try returns a parser that matches p, but does not consume anything if
+// p fails.
+func Try(p Parser) Parser {
+	return describe(func(c *Cursor) (interface{}, bool) {
+		return attempt(c, p)
+	}, func() *Syntax {
+		return SyntaxOf(p)
 	})
 }
 
+// FirstString returns a parser that matches the first of slc found in
+// the input, it does not consume anything if it fails.
 func FirstString(slc ...string) Parser {
-	return ParserFunc(func(c *Cursor) (interface{}, bool) {
+	return describe(func(c *Cursor) (interface{}, bool) {
 		for _, v := range slc {
 			cc := *c
 			_, ok := ExpectString(v).Parse(&cc)
@@ -58,12 +122,26 @@ func FirstString(slc ...string) Parser {
 			}
 		}
 
+		for _, v := range slc {
+			c.Expected(fmt.Sprintf("%q", v))
+		}
+
 		return nil, false
+	}, func() *Syntax {
+		ret := &Syntax{Op: SyntaxAlt}
+		for _, v := range slc {
+			ret.Args = append(ret.Args, &Syntax{Op: SyntaxString, Text: v})
+		}
+
+		return ret
 	})
 }
 
+// All returns a parser that matches each of p in sequence and returns
+// their results in a []interface{}. It does not backtrack: when one of p
+// fails the cursor is left after the last match.
 func All(p ...Parser) Parser {
-	return ParserFunc(func(c *Cursor) (interface{}, bool) {
+	return describe(func(c *Cursor) (interface{}, bool) {
 		ret := make([]interface{}, len(p))
 
 		for i, v := range p {
@@ -78,39 +156,131 @@ func All(p ...Parser) Parser {
 		}
 
 		return ret, true
+	}, func() *Syntax {
+		return syntaxOp(SyntaxSeq, p...)
 	})
 }
 
-func AllIdx(idx int, p ...Parser) ParserFunc {
+// AllIdx is like All, but returns only the result at idx
+func AllIdx(idx int, p ...Parser) Parser {
 	parser := All(p...)
 
-	return func(c *Cursor) (interface{}, bool) {
+	return describe(func(c *Cursor) (interface{}, bool) {
 		v, ok := parser.Parse(c)
 		if !ok {
 			return nil, false
 		}
 
 		return v.([]interface{})[idx], true
-	}
+	}, func() *Syntax {
+		return SyntaxOf(parser)
+	})
 }
 
+// Maybe returns a parser that matches p or nothing, in which case the
+// result is nil. It backtracks: if p fails nothing is consumed.
 func Maybe(p Parser) Parser {
-	return ParserFunc(func(c *Cursor) (interface{}, bool) {
-		cc := *c
-		v, ok := p.Parse(c)
+	return describe(func(c *Cursor) (interface{}, bool) {
+		v, ok := attempt(c, p)
 		if !ok {
 			return nil, true
 		}
 
-		*c = cc
 		return v, true
+	}, func() *Syntax {
+		return syntaxOp(SyntaxOpt, p)
+	})
+}
+
+// ExpectEOF returns a parser that matches the end of the input, it
+// does not consume anything.
+func ExpectEOF() Parser {
+	return describe(func(c *Cursor) (interface{}, bool) {
+		if !c.atEOF() {
+			c.Expected("end of file")
+			return nil, false
+		}
+
+		return nil, true
+	}, func() *Syntax {
+		return &Syntax{Op: SyntaxClass, Text: "end of file"}
+	})
+}
+
+// Bad is the result of a Recover parser whose parser failed, it spans
+// the input that was skipped.
+type Bad struct {
+	From, To FileInfo
+	Err      *Error
+}
+
+// Recover returns a parser that recovers from p failing. When p fails
+// a syntax error is recorded on the cursor, and the input is skipped up
+// to, but not including, the first of the sync strings or the end of
+// the input. A *Bad is then returned in place of p's result so parsing
+// can carry on and report any further errors. The error is at the
+// furthest failure of p in the skipped input, or at its end if p failed
+// past it, the input after it is left to the parsers that carry on.
+func Recover(p Parser, sync ...string) Parser {
+	return describe(func(c *Cursor) (interface{}, bool) {
+		// only failures from p are relevant to its error, those
+		// from earlier parses of the same input may have gone
+		// past it
+		if c.fail == nil {
+			c.fail = &failure{}
+		}
+		outer := *c.fail
+		*c.fail = failure{bounded: outer.bounded, limit: outer.limit}
+
+		cc := *c
+		v, ok := p.Parse(&cc)
+		if ok {
+			c.fail.merge(outer)
+			*c = cc
+			return v, true
+		}
+
+		end := *c
+		for !end.atEOF() && !end.atAny(sync) {
+			end.skip()
+		}
+
+		if f := c.fail; f.set && f.at.i > end.i {
+			// parse again, with the failures past the skipped
+			// input taken to be at its end
+			*f = failure{bounded: true, limit: end}
+			if outer.bounded && outer.limit.i < end.i {
+				f.limit = outer.limit
+			}
+
+			cc := *c
+			p.Parse(&cc)
+		}
+
+		bad := &Bad{
+			From: c.FileInfo(),
+			Err:  c.syntaxError(),
+		}
+		c.addError(bad.Err)
+		*c.fail = outer
+
+		for !c.atEOF() && !c.atAny(sync) {
+			c.skip()
+		}
+
+		bad.To = c.FileInfo()
+
+		return bad, true
+	}, func() *Syntax {
+		return SyntaxOf(p)
 	})
 }
 
 // WriteTo returns a parser that wraps another parser p
-// and sets the literal string tha p matched to *dst
+// and sets the literal string tha p matched to *dst.
+// If p fails, the cursor is left wherever p left it.
 func WriteTo(dst *string, p Parser) Parser {
-	return ParserFunc(func(c *Cursor) (interface{}, bool) {
+	return describe(func(c *Cursor) (interface{}, bool) {
 		start := c.i
 
 		ret, ok := p.Parse(c)
@@ -118,6 +288,11 @@ func WriteTo(dst *string, p Parser) Parser {
 			return nil, false
 		}
 		end := c.i
+		if c.tokens {
+			*dst = c.tokensAt(start, end-start, "")
+			return ret, true
+		}
+
 		if c.eof {
 			end--
 		}
@@ -125,23 +300,56 @@ func WriteTo(dst *string, p Parser) Parser {
 		*dst = c.stringAt(start, end-start)
 
 		return ret, true
+	}, func() *Syntax {
+		return SyntaxOf(p)
 	})
 }
 
 
-func KleenePred(fn func(r rune) bool) ParserFunc {
-	return func(c *Cursor) (interface{}, bool) {
+// KleenePred returns a parser that matches zero or more runes for
+// which fn is true, it never fails. On a token level cursor it matches
+// whole tokens made of such runes, like the newlines a lexer inserts.
+func KleenePred(fn func(r rune) bool) Parser {
+	return describe(func(c *Cursor) (interface{}, bool) {
 		ret := ""
-		for !c.EOF() && fn(c.PeekRune()) {
+		if c.tokens {
+			for !c.EOF() && isPred(fn, c.toks[c.i].Text()) {
+				ret += c.toks[c.i].Text()
+				c.i++
+			}
+
+			return ret, true
+		}
+
+		for !c.atEOF() && fn(c.PeekRune()) {
 			ret += string(c.ReadRune())
 		}
 
 		return ret, true
+	}, func() *Syntax {
+		return &Syntax{Op: SyntaxMany, Args: []*Syntax{
+			{Op: SyntaxClass, Text: "rune"},
+		}}
+	})
+}
+
+// isPred reports whether s is made of runes for which fn is true
+func isPred(fn func(r rune) bool, s string) bool {
+	if len(s) == 0 {
+		return false
+	}
+
+	for _, r := range s {
+		if !fn(r) {
+			return false
+		}
 	}
+
+	return true
 }
 
-func PlusPred(fn func(r rune) bool) ParserFunc {
-	return func(c *Cursor) (interface{}, bool) {
+func PlusPred(fn func(r rune) bool) Parser {
+	return describe(func(c *Cursor) (interface{}, bool) {
 		v, ok := KleenePred(fn).Parse(c)
 		if !ok {
 			return nil, false
@@ -152,43 +360,89 @@ func PlusPred(fn func(r rune) bool) ParserFunc {
 		}
 
 		return v, true
-	}
+	}, func() *Syntax {
+		return &Syntax{Op: SyntaxMany1, Args: []*Syntax{
+			{Op: SyntaxClass, Text: "rune"},
+		}}
+	})
 }
 
 // WS returns a parser that matches white space
 func WS() Parser {
-	return KleenePred(unicode.IsSpace)
+	return space("white space", KleenePred(unicode.IsSpace))
 }
 
 func WS1() Parser {
-	return PlusPred(unicode.IsSpace)
+	return space("white space", PlusPred(unicode.IsSpace))
 }
 
 // HS returns a parser that matches horizontal space
 func HS() Parser {
-	return KleenePred(func(r rune) bool {
-		return unicode.IsSpace(r) && r != '\r' && r != '\n'
-	})
+	return space("horizontal space", KleenePred(isHS))
 }
 
 func HS1() Parser {
-	return PlusPred(func(r rune) bool {
-		return unicode.IsSpace(r) && r != '\r' && r != '\n'
+	return space("horizontal space", PlusPred(isHS))
+}
+
+// space describes p as the class of white space name
+func space(name string, p Parser) Parser {
+	return describe(p.Parse, func() *Syntax {
+		return &Syntax{Op: SyntaxSpace, Text: name}
 	})
 }
 
-// EOL scanns until the end of the line
+// isHS reports whether r is horizontal space
+func isHS(r rune) bool {
+	return unicode.IsSpace(r) && r != '\r' && r != '\n'
+}
+
+// Newline returns a parser that matches a line break, "\n" or "\r\n"
+func Newline() Parser {
+	return describe(func(c *Cursor) (interface{}, bool) {
+		v, ok := newline(c)
+		if !ok {
+			c.Expected("newline")
+			return nil, false
+		}
+
+		return v, true
+	}, func() *Syntax {
+		return &Syntax{Op: SyntaxClass, Text: "newline"}
+	})
+}
+
+// newline matches a line break without recording an expectation
+func newline(c *Cursor) (string, bool) {
+	for _, v := range []string{"\n", "\r\n"} {
+		cc := *c
+		cc.fail = nil
+		if _, ok := ExpectString(v).Parse(&cc); ok {
+			cc.fail = c.fail
+			*c = cc
+			return v, true
+		}
+	}
+
+	return "", false
+}
+
+// EOL matches the rest of the line, including the line break if the
+// input doesn't end first. It never fails.
 func EOL() Parser {
-	atEnd := false
-	return KleenePred(func(r rune) bool {
-		if r == '\r' || r == '\n' {
-			atEnd = atEnd || true
-			return true
-		} else if atEnd {
-			return false
-		} else {
-			return true
+	return describe(func(c *Cursor) (interface{}, bool) {
+		v, _ := KleenePred(func(r rune) bool {
+			return r != '\r' && r != '\n'
+		}).Parse(c)
+		ret := v.(string)
+
+		if nl, ok := newline(c); ok {
+			ret += nl
 		}
+
+		return ret, true
+	}, func() *Syntax {
+		return &Syntax{Op: SyntaxClass, Text: "rest of line"}
 	})
 }
 
@@ -1,6 +1,9 @@
 package parser
 
 var Test = false
+
+// Debug enables PeekN, and traces Named parsers to stderr on new
+// cursors
 var Debug = false
 
 func (c *Cursor) PeekN(n int) string {
@@ -8,11 +11,19 @@ func (c *Cursor) PeekN(n int) string {
 		panic("!Debug")
 	}
 
+	if c.tokens {
+		return c.tokensAt(c.i, int64(n), " ")
+	}
+
 	cc := *c
 
 	ret := ""
-	for ;uint(n) > 0 && !cc.EOF(); n--{
-		ret += string(cc.ReadRune())
+	for ; uint(n) > 0 && !cc.EOF(); n-- {
+		r := cc.ReadRune()
+		if cc.EOF() {
+			break
+		}
+		ret += string(r)
 	}
 
 	return ret
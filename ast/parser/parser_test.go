@@ -2,9 +2,15 @@ package parser_test
 
 import (
 	"testing"
+
 	"github.com/ear7h/lang/ast/parser"
+	"github.com/ear7h/lang/internal/assert"
 )
 
+func init() {
+	parser.Test = true
+}
+
 func TestParsers(t *testing.T) {
 	type tcase struct {
 		str string
@@ -19,13 +25,13 @@ func TestParsers(t *testing.T) {
 			_, v, ok, err :=
 				parser.DoParseStringForTest(tc.p, tc.str, "test")
 
-			assertErrIs(t, tc.err, err)
-			assertEq(t, tc.ok, ok)
+			assert.ErrIs(t, tc.err, err)
+			assert.Eq(t, tc.ok, ok)
 			if !ok || err != nil {
 				return
 			}
 
-			assertEq(t, tc.out, v)
+			assert.Eq(t, tc.out, v)
 		}
 	}
 
@@ -75,10 +81,203 @@ func TestParsers(t *testing.T) {
 				"asd",
 			},
 		},
+		"Newline": tcase{
+			str: "\r\nasd",
+			ok:  true,
+			p:   parser.Newline(),
+			out: "\r\n",
+		},
+		"Newline fail": tcase{
+			str: " \n",
+			ok:  false,
+			p:   parser.Newline(),
+		},
+		"EOL": tcase{
+			str: "qwe asd\nzxc",
+			ok:  true,
+			p:   parser.All(parser.EOL(), parser.EOL()),
+			out: []interface{}{
+				"qwe asd\n",
+				"zxc",
+			},
+		},
+	}
+
+	for k, v := range tcases {
+		t.Run(k, fn(v))
+	}
+
+}
+
+func TestRecover(t *testing.T) {
+	type tcase struct {
+		str  string
+		p    parser.Parser
+		errs []string
+		out  interface{}
+	}
+
+	fn := func(tc tcase) func(t *testing.T) {
+		return func(t *testing.T) {
+			v, ok, err := parser.DoParseString(tc.p, tc.str, "test")
+
+			var errs []string
+			if err != nil {
+				for _, v := range err.(parser.ErrorList) {
+					errs = append(errs, v.Error())
+				}
+			}
+
+			assert.Eq(t, tc.errs, errs)
+			assert.Eq(t, true, ok)
+			assert.Eq(t, tc.out, v)
+		}
+	}
+
+	fi := func(col int64) parser.FileInfo {
+		return parser.FileInfo{Name: "test", Line: 1, Col: col}
+	}
+
+	asd := parser.Recover(parser.ExpectString("asd"), ";")
+
+	tcases := map[string]tcase{
+		"no error": tcase{
+			str: "asd;",
+			p:   parser.All(asd, parser.ExpectString(";")),
+			out: []interface{}{"asd", ";"},
+		},
+		"skip to sync": tcase{
+			str: "qwe;",
+			p:   parser.All(asd, parser.ExpectString(";")),
+			errs: []string{
+				`test:1:1: syntax error: unexpected "qwe", expected "asd"`,
+			},
+			out: []interface{}{
+				&parser.Bad{
+					From: fi(1),
+					To:   fi(4),
+					Err: &parser.Error{
+						Fi:  fi(1),
+						Msg: `syntax error: unexpected "qwe", expected "asd"`,
+					},
+				},
+				";",
+			},
+		},
+		"skip to eof": tcase{
+			str: "qwe",
+			p:   parser.AllIdx(0, asd, parser.ExpectEOF()),
+			errs: []string{
+				`test:1:1: syntax error: unexpected "qwe", expected "asd"`,
+			},
+			out: &parser.Bad{
+				From: fi(1),
+				To:   fi(4),
+				Err: &parser.Error{
+					Fi:  fi(1),
+					Msg: `syntax error: unexpected "qwe", expected "asd"`,
+				},
+			},
+		},
+		"furthest failure": tcase{
+			str: "asq;",
+			p: parser.AllIdx(0,
+				parser.Recover(
+					parser.First(
+						parser.ExpectString("asd"),
+						parser.All(
+							parser.ExpectString("as"),
+							parser.ExpectString("df"),
+						),
+					),
+					";",
+				),
+				parser.ExpectString(";"),
+			),
+			errs: []string{
+				`test:1:3: syntax error: unexpected "q", expected "df"`,
+			},
+			out: &parser.Bad{
+				From: fi(1),
+				To:   fi(4),
+				Err: &parser.Error{
+					Fi:  fi(3),
+					Msg: `syntax error: unexpected "q", expected "df"`,
+				},
+			},
+		},
+		"failure past sync": tcase{
+			str: "as;df",
+			p: parser.AllIdx(0,
+				parser.Recover(
+					parser.All(
+						parser.ExpectString("as"),
+						parser.ExpectString(";"),
+						parser.ExpectString("dx"),
+					),
+					";",
+				),
+				parser.ExpectString(";"),
+			),
+			errs: []string{
+				`test:1:3: syntax error: unexpected ";", expected "dx"`,
+			},
+			out: &parser.Bad{
+				From: fi(1),
+				To:   fi(3),
+				Err: &parser.Error{
+					Fi:  fi(3),
+					Msg: `syntax error: unexpected ";", expected "dx"`,
+				},
+			},
+		},
+		"multiple": tcase{
+			str: "qwe;zxc;asd",
+			p: parser.AllIdx(4,
+				asd,
+				parser.ExpectString(";"),
+				asd,
+				parser.ExpectString(";"),
+				asd,
+			),
+			errs: []string{
+				`test:1:1: syntax error: unexpected "qwe", expected "asd"`,
+				`test:1:5: syntax error: unexpected "zxc", expected "asd"`,
+			},
+			out: "asd",
+		},
 	}
 
 	for k, v := range tcases {
 		t.Run(k, fn(v))
 	}
+}
+
+func TestErrorListSort(t *testing.T) {
+	fi := func(name string, line, col int64) parser.FileInfo {
+		return parser.FileInfo{Name: name, Line: line, Col: col}
+	}
+
+	l := parser.ErrorList{
+		{Fi: fi("b", 1, 1), Msg: "4"},
+		{Fi: fi("a", 2, 1), Msg: "3"},
+		{Fi: fi("a", 1, 5), Msg: "1"},
+		{Fi: fi("a", 1, 5), Msg: "2"},
+		{Fi: fi("a", 1, 1), Msg: "0"},
+	}
+	l.Sort()
+
+	var msgs []string
+	for _, v := range l {
+		msgs = append(msgs, v.Msg)
+	}
+
+	assert.Eq(t, []string{"0", "1", "2", "3", "4"}, msgs)
+}
 
+func TestDefaultFileInfo(t *testing.T) {
+	// a new cursor starts at line 1 col 1, a non-zero file info also
+	// makes sure Parse properly initializes it
+	fi := parser.NewCursorString("", "").FileInfo()
+	assert.Eq(t, parser.FileInfo{Line: 1, Col: 1}, fi)
 }
@@ -0,0 +1,209 @@
+package parser
+
+// attempt parses p on a copy of c, and only moves c if p matched
+func attempt(c *Cursor, p Parser) (interface{}, bool) {
+	cc := *c
+	v, ok := p.Parse(&cc)
+	if ok {
+		*c = cc
+	}
+
+	return v, ok
+}
+
+// Many returns a parser that matches p zero or more times, it leaves
+// the cursor after the last match.
+func Many(p Parser) Parser {
+	return describe(func(c *Cursor) (interface{}, bool) {
+		ret := []interface{}{}
+
+		for {
+			start := c.i
+
+			v, ok := attempt(c, p)
+			if !ok {
+				return ret, true
+			}
+
+			ret = append(ret, v)
+
+			if c.i == start {
+				c.Fatalf("Many: parser matched nothing at %s", c.FileInfo())
+			}
+		}
+	}, func() *Syntax {
+		return syntaxOp(SyntaxMany, p)
+	})
+}
+
+// Many1 is like Many but p must match at least once
+func Many1(p Parser) Parser {
+	return describe(func(c *Cursor) (interface{}, bool) {
+		v, ok := attempt(c, p)
+		if !ok {
+			return nil, false
+		}
+
+		rest, _ := Many(p).Parse(c)
+
+		return append([]interface{}{v}, rest.([]interface{})...), true
+	}, func() *Syntax {
+		return syntaxOp(SyntaxMany1, p)
+	})
+}
+
+// Count returns a parser that matches p exactly n times
+func Count(n int, p Parser) Parser {
+	return describe(func(c *Cursor) (interface{}, bool) {
+		ret := make([]interface{}, n)
+
+		for i := range ret {
+			var ok bool
+			ret[i], ok = attempt(c, p)
+			if !ok {
+				return nil, false
+			}
+		}
+
+		return ret, true
+	}, func() *Syntax {
+		ret := &Syntax{Op: SyntaxSeq}
+		for i := 0; i < n; i++ {
+			ret.Args = append(ret.Args, SyntaxOf(p))
+		}
+
+		return ret
+	})
+}
+
+// SepBy returns a parser that matches zero or more p separated by sep,
+// the results of sep are dropped. A trailing sep is not consumed.
+func SepBy(p, sep Parser) Parser {
+	return describe(func(c *Cursor) (interface{}, bool) {
+		v, ok := SepBy1(p, sep).Parse(c)
+		if !ok {
+			return []interface{}{}, true
+		}
+
+		return v, true
+	}, func() *Syntax {
+		return syntaxOp(SyntaxOpt, SepBy1(p, sep))
+	})
+}
+
+// SepBy1 is like SepBy but p must match at least once
+func SepBy1(p, sep Parser) Parser {
+	return describe(func(c *Cursor) (interface{}, bool) {
+		v, ok := attempt(c, p)
+		if !ok {
+			return nil, false
+		}
+
+		rest, _ := Many(AllIdx(1, sep, p)).Parse(c)
+
+		return append([]interface{}{v}, rest.([]interface{})...), true
+	}, func() *Syntax {
+		return &Syntax{Op: SyntaxSeq, Args: []*Syntax{
+			SyntaxOf(p),
+			syntaxOp(SyntaxMany, All(sep, p)),
+		}}
+	})
+}
+
+// SepEndBy is like SepBy but allows, and consumes, a trailing sep
+func SepEndBy(p, sep Parser) Parser {
+	return describe(func(c *Cursor) (interface{}, bool) {
+		v, _ := SepBy(p, sep).Parse(c)
+		if len(v.([]interface{})) > 0 {
+			attempt(c, sep)
+		}
+
+		return v, true
+	}, func() *Syntax {
+		return &Syntax{Op: SyntaxOpt, Args: []*Syntax{
+			syntaxOp(SyntaxSeq, SepBy1(p, sep), Maybe(sep)),
+		}}
+	})
+}
+
+// Between returns a parser that matches open, p then close, and
+// returns the result of p
+func Between(open, close, p Parser) Parser {
+	return AllIdx(1, open, p, close)
+}
+
+// ChainL1 returns a parser for one or more p separated by op, where op
+// returns a func(left, right interface{}) interface{} to combine the
+// results of the surrounding p. The results are combined from the left,
+// so it parses left associative operators.
+func ChainL1(p, op Parser) Parser {
+	return describe(func(c *Cursor) (interface{}, bool) {
+		left, ok := attempt(c, p)
+		if !ok {
+			return nil, false
+		}
+
+		for {
+			v, ok := attempt(c, All(op, p))
+			if !ok {
+				return left, true
+			}
+
+			slc := v.([]interface{})
+			fn := slc[0].(func(left, right interface{}) interface{})
+
+			left = fn(left, slc[1])
+		}
+	}, func() *Syntax {
+		return SyntaxOf(SepBy1(p, op))
+	})
+}
+
+// ChainR1 is like ChainL1 but the results are combined from the right,
+// so it parses right associative operators.
+func ChainR1(p, op Parser) Parser {
+	return describe(func(c *Cursor) (interface{}, bool) {
+		left, ok := attempt(c, p)
+		if !ok {
+			return nil, false
+		}
+
+		v, ok := attempt(c, All(op, ChainR1(p, op)))
+		if !ok {
+			return left, true
+		}
+
+		slc := v.([]interface{})
+		fn := slc[0].(func(left, right interface{}) interface{})
+
+		return fn(left, slc[1]), true
+	}, func() *Syntax {
+		return SyntaxOf(SepBy1(p, op))
+	})
+}
+
+// Lookahead returns a parser that matches p without consuming any input
+func Lookahead(p Parser) Parser {
+	return describe(func(c *Cursor) (interface{}, bool) {
+		cc := *c
+		return p.Parse(&cc)
+	}, func() *Syntax {
+		return syntaxOp(SyntaxLookahead, p)
+	})
+}
+
+// NotFollowedBy returns a parser that only matches if p does not, it
+// does not consume any input
+func NotFollowedBy(p Parser) Parser {
+	return describe(func(c *Cursor) (interface{}, bool) {
+		cc := *c
+		cc.fail = nil
+		if _, ok := p.Parse(&cc); ok {
+			return nil, false
+		}
+
+		return nil, true
+	}, func() *Syntax {
+		return syntaxOp(SyntaxNot, p)
+	})
+}
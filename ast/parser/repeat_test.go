@@ -0,0 +1,207 @@
+package parser_test
+
+import (
+	"testing"
+
+	"github.com/ear7h/lang/ast/parser"
+	"github.com/ear7h/lang/internal/assert"
+)
+
+func TestRepeat(t *testing.T) {
+	type tcase struct {
+		str  string
+		p    parser.Parser
+		ok   bool
+		err  error
+		out  interface{}
+		rest string
+	}
+
+	fn := func(tc tcase) func(t *testing.T) {
+		return func(t *testing.T) {
+			c := parser.NewCursorString(tc.str, "test")
+			v, ok := tc.p.Parse(c)
+
+			assert.Eq(t, tc.ok, ok)
+			if !ok {
+				return
+			}
+
+			assert.Eq(t, tc.out, v)
+			assert.Eq(t, tc.rest, c.PeekN(len(tc.str)))
+		}
+	}
+
+	a := parser.ExpectString("a")
+	comma := parser.ExpectString(",")
+
+	sub := parser.ParserFunc(func(c *parser.Cursor) (interface{}, bool) {
+		_, ok := parser.ExpectString("-").Parse(c)
+		if !ok {
+			return nil, false
+		}
+
+		return func(left, right interface{}) interface{} {
+			return []interface{}{left, "-", right}
+		}, true
+	})
+
+	tcases := map[string]tcase{
+		"Many": tcase{
+			str:  "aab",
+			p:    parser.Many(a),
+			ok:   true,
+			out:  []interface{}{"a", "a"},
+			rest: "b",
+		},
+		"Many none": tcase{
+			str:  "b",
+			p:    parser.Many(a),
+			ok:   true,
+			out:  []interface{}{},
+			rest: "b",
+		},
+		"Many partial": tcase{
+			str:  "a,a,b",
+			p:    parser.Many(parser.AllIdx(0, a, comma)),
+			ok:   true,
+			out:  []interface{}{"a", "a"},
+			rest: "b",
+		},
+		"Many1": tcase{
+			str:  "aab",
+			p:    parser.Many1(a),
+			ok:   true,
+			out:  []interface{}{"a", "a"},
+			rest: "b",
+		},
+		"Many1 none": tcase{
+			str: "b",
+			p:   parser.Many1(a),
+			ok:  false,
+		},
+		"Count": tcase{
+			str:  "aaa",
+			p:    parser.Count(2, a),
+			ok:   true,
+			out:  []interface{}{"a", "a"},
+			rest: "a",
+		},
+		"Count short": tcase{
+			str: "ab",
+			p:   parser.Count(2, a),
+			ok:  false,
+		},
+		"SepBy": tcase{
+			str:  "a,a,ab",
+			p:    parser.SepBy(a, comma),
+			ok:   true,
+			out:  []interface{}{"a", "a", "a"},
+			rest: "b",
+		},
+		"SepBy trailing": tcase{
+			str:  "a,a,b",
+			p:    parser.SepBy(a, comma),
+			ok:   true,
+			out:  []interface{}{"a", "a"},
+			rest: ",b",
+		},
+		"SepBy none": tcase{
+			str:  "b",
+			p:    parser.SepBy(a, comma),
+			ok:   true,
+			out:  []interface{}{},
+			rest: "b",
+		},
+		"SepBy1 none": tcase{
+			str: "b",
+			p:   parser.SepBy1(a, comma),
+			ok:  false,
+		},
+		"SepEndBy": tcase{
+			str:  "a,a,b",
+			p:    parser.SepEndBy(a, comma),
+			ok:   true,
+			out:  []interface{}{"a", "a"},
+			rest: "b",
+		},
+		"SepEndBy no trailing": tcase{
+			str:  "a,ab",
+			p:    parser.SepEndBy(a, comma),
+			ok:   true,
+			out:  []interface{}{"a", "a"},
+			rest: "b",
+		},
+		"SepEndBy none": tcase{
+			str:  ",b",
+			p:    parser.SepEndBy(a, comma),
+			ok:   true,
+			out:  []interface{}{},
+			rest: ",b",
+		},
+		"Between": tcase{
+			str:  "(a)b",
+			p:    parser.Between(parser.ExpectString("("), parser.ExpectString(")"), a),
+			ok:   true,
+			out:  "a",
+			rest: "b",
+		},
+		"ChainL1": tcase{
+			str: "a-a-a-b",
+			p:   parser.ChainL1(a, sub),
+			ok:  true,
+			out: []interface{}{
+				[]interface{}{"a", "-", "a"},
+				"-",
+				"a",
+			},
+			rest: "-b",
+		},
+		"ChainL1 single": tcase{
+			str:  "ab",
+			p:    parser.ChainL1(a, sub),
+			ok:   true,
+			out:  "a",
+			rest: "b",
+		},
+		"ChainR1": tcase{
+			str: "a-a-a-b",
+			p:   parser.ChainR1(a, sub),
+			ok:  true,
+			out: []interface{}{
+				"a",
+				"-",
+				[]interface{}{"a", "-", "a"},
+			},
+			rest: "-b",
+		},
+		"Lookahead": tcase{
+			str:  "ab",
+			p:    parser.Lookahead(a),
+			ok:   true,
+			out:  "a",
+			rest: "ab",
+		},
+		"Lookahead fail": tcase{
+			str: "b",
+			p:   parser.Lookahead(a),
+			ok:  false,
+		},
+		"NotFollowedBy": tcase{
+			str:  "b",
+			p:    parser.NotFollowedBy(a),
+			ok:   true,
+			out:  nil,
+			rest: "b",
+		},
+		"NotFollowedBy fail": tcase{
+			str: "ab",
+			p:   parser.NotFollowedBy(a),
+			ok:  false,
+		},
+	}
+
+	for k, v := range tcases {
+		t.Run(k, fn(v))
+	}
+}
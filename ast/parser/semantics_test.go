@@ -0,0 +1,207 @@
+package parser_test
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/quick"
+
+	"github.com/ear7h/lang/ast/parser"
+)
+
+// grammar is a randomly generated combinator tree, it can be turned into
+// a parser and also interpreted by model, which implements the
+// documented cursor semantics on its own.
+type grammar struct {
+	kind string
+	str  string
+	kids []*grammar
+}
+
+func (g *grammar) String() string {
+	if g.kind == "str" {
+		return fmt.Sprintf("%q", g.str)
+	}
+
+	kids := make([]string, len(g.kids))
+	for i, v := range g.kids {
+		kids[i] = v.String()
+	}
+
+	return g.kind + "(" + strings.Join(kids, ", ") + ")"
+}
+
+func (g *grammar) parser() parser.Parser {
+	kids := make([]parser.Parser, len(g.kids))
+	for i, v := range g.kids {
+		kids[i] = v.parser()
+	}
+
+	switch g.kind {
+	case "str":
+		return parser.ExpectString(g.str)
+	case "all":
+		return parser.All(kids...)
+	case "first":
+		return parser.First(kids...)
+	case "choice":
+		return parser.Choice(kids...)
+	case "maybe":
+		return parser.Maybe(kids[0])
+	case "try":
+		return parser.Try(kids[0])
+	case "many":
+		return parser.Many(kids[0])
+	case "lookahead":
+		return parser.Lookahead(kids[0])
+	case "notfollowedby":
+		return parser.NotFollowedBy(kids[0])
+	}
+
+	panic("unknown kind " + g.kind)
+}
+
+// model returns whether g matches s at i, and the offset the cursor is
+// left at
+func (g *grammar) model(s string, i int) (bool, int) {
+	switch g.kind {
+	case "str":
+		if strings.HasPrefix(s[i:], g.str) {
+			return true, i + len(g.str)
+		}
+		return false, i
+
+	case "all":
+		for _, v := range g.kids {
+			ok, j := v.model(s, i)
+			if !ok {
+				return false, i
+			}
+			i = j
+		}
+		return true, i
+
+	case "first":
+		for _, v := range g.kids {
+			if ok, j := v.model(s, i); ok {
+				return true, j
+			}
+		}
+		return false, i
+
+	case "choice":
+		for _, v := range g.kids {
+			ok, j := v.model(s, i)
+			if ok || j != i {
+				return ok, j
+			}
+		}
+		return false, i
+
+	case "maybe":
+		if ok, j := g.kids[0].model(s, i); ok {
+			return true, j
+		}
+		return true, i
+
+	case "try":
+		if ok, j := g.kids[0].model(s, i); ok {
+			return true, j
+		}
+		return false, i
+
+	case "many":
+		for {
+			ok, j := g.kids[0].model(s, i)
+			if !ok {
+				return true, i
+			}
+			i = j
+		}
+
+	case "lookahead":
+		ok, _ := g.kids[0].model(s, i)
+		return ok, i
+
+	case "notfollowedby":
+		ok, _ := g.kids[0].model(s, i)
+		return !ok, i
+	}
+
+	panic("unknown kind " + g.kind)
+}
+
+func randString(r *rand.Rand, min, max int) string {
+	n := min + r.Intn(max-min+1)
+
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = "ab"[r.Intn(2)]
+	}
+
+	return string(b)
+}
+
+func randGrammar(r *rand.Rand, depth int) *grammar {
+	kinds := []string{
+		"all", "first", "choice", "maybe", "try",
+		"many", "lookahead", "notfollowedby",
+	}
+
+	if depth == 0 || r.Intn(3) == 0 {
+		return &grammar{kind: "str", str: randString(r, 1, 3)}
+	}
+
+	g := &grammar{kind: kinds[r.Intn(len(kinds))]}
+
+	switch g.kind {
+	case "all", "first", "choice":
+		n := 1 + r.Intn(3)
+		for i := 0; i < n; i++ {
+			g.kids = append(g.kids, randGrammar(r, depth-1))
+		}
+	case "many":
+		// many must consume something on each match
+		g.kids = []*grammar{{kind: "str", str: randString(r, 1, 2)}}
+	default:
+		g.kids = []*grammar{randGrammar(r, depth-1)}
+	}
+
+	return g
+}
+
+type semanticsCase struct {
+	g   *grammar
+	str string
+}
+
+func (semanticsCase) Generate(r *rand.Rand, size int) reflect.Value {
+	return reflect.ValueOf(semanticsCase{
+		g:   randGrammar(r, 4),
+		str: randString(r, 0, 8),
+	})
+}
+
+func TestCursorSemantics(t *testing.T) {
+	prop := func(tc semanticsCase) bool {
+		c := parser.NewCursorString(tc.str, "test")
+		_, ok := tc.g.parser().Parse(c)
+		rest := c.PeekN(len(tc.str))
+
+		expectOk, i := tc.g.model(tc.str, 0)
+		if ok != expectOk || rest != tc.str[i:] {
+			t.Logf("%s on %q: expected %v %q, got %v %q",
+				tc.g, tc.str, expectOk, tc.str[i:], ok, rest)
+			return false
+		}
+
+		return true
+	}
+
+	err := quick.Check(prop, &quick.Config{MaxCount: 2000})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,108 @@
+package parser
+
+import (
+	"os"
+	"strings"
+)
+
+// Token is an item of a token level cursor, as produced by a lexer
+type Token interface {
+	Text() string
+	FileInfo() FileInfo
+}
+
+// NewCursorTokens returns a cursor over toks rather than runes, end is
+// the position of the end of the input.
+//
+// On a token level cursor the parsers matching strings (ExpectString,
+// ExpectRune and FirstString) match whole tokens, the white space
+// parsers only match tokens of white space, such as newlines inserted
+// by the lexer to end statements, and Lexeme runs a rune level parser
+// over a single token. The combinators work the same over either cursor, but
+// reading runes from a token level cursor is fatal.
+func NewCursorTokens(toks []Token, end FileInfo) *Cursor {
+	c := &Cursor{
+		tokens: true,
+		toks:   toks,
+		end:    end,
+		name:   end.Name,
+		fail:   &failure{},
+	}
+
+	if Debug {
+		c.trace = NewTextTracer(os.Stderr)
+	}
+
+	return c
+}
+
+// Tokens reports whether c is a token level cursor
+func (c *Cursor) Tokens() bool {
+	return c.tokens
+}
+
+// Lexeme returns a parser that matches p on a single token of a token
+// level cursor, p is run over the text of the token and must match all
+// of it. On a rune level cursor it is just p.
+func Lexeme(p Parser) Parser {
+	return describe(func(c *Cursor) (interface{}, bool) {
+		if !c.tokens {
+			return p.Parse(c)
+		}
+
+		text := ""
+		if !c.EOF() {
+			text = c.toks[c.i].Text()
+		}
+
+		fi := c.FileInfo()
+		sub := &Cursor{
+			r:     strings.NewReader(text),
+			name:  fi.Name,
+			line:  fi.Line,
+			col:   fi.Col,
+			errs:  c.errs,
+			fail:  &failure{},
+			trace: c.trace,
+		}
+
+		v, ok := p.Parse(sub)
+		if !ok || !sub.atEOF() {
+			// positions in sub are not comparable to c's
+			for _, v := range sub.fail.expect {
+				c.Expected(v)
+			}
+
+			return nil, false
+		}
+
+		c.errs = sub.errs
+		c.i++
+
+		return v, true
+	}, func() *Syntax {
+		return SyntaxOf(p)
+	})
+}
+
+// expectToken matches a token with text s
+func (c *Cursor) expectToken(s string) bool {
+	if c.EOF() || c.toks[c.i].Text() != s {
+		return false
+	}
+
+	c.i++
+	return true
+}
+
+// tokensAt returns the text of n tokens starting at i, separated by
+// sep
+func (c *Cursor) tokensAt(i, n int64, sep string) string {
+	var slc []string
+	for ; n > 0 && i < int64(len(c.toks)); n-- {
+		slc = append(slc, c.toks[i].Text())
+		i++
+	}
+
+	return strings.Join(slc, sep)
+}
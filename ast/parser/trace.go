@@ -0,0 +1,173 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Tracer is notified when a cursor enters and exits a Named parser.
+// Exit is passed the cursor's position after the parse, even if it
+// failed.
+type Tracer interface {
+	Enter(name string, fi FileInfo)
+	Exit(name string, fi FileInfo, ok bool)
+}
+
+// SetTracer sets the tracer for c and all the cursors copied from it,
+// a nil tracer turns tracing off
+func (c *Cursor) SetTracer(t Tracer) {
+	c.trace = t
+}
+
+// Named returns a parser that parses p and labels it with name when
+// tracing, it is also a rule of the grammar returned by Describe
+func Named(name string, p Parser) Parser {
+	return named{name: name, p: p}
+}
+
+type named struct {
+	name string
+	p    Parser
+}
+
+// Syntax returns a reference to the rule n, see Describe
+func (n named) Syntax() *Syntax {
+	return &Syntax{Op: SyntaxRef, Text: n.name, rule: n.p}
+}
+
+func (n named) Parse(c *Cursor) (interface{}, bool) {
+	if c.trace == nil {
+		return n.p.Parse(c)
+	}
+
+	c.trace.Enter(n.name, c.FileInfo())
+	v, ok := n.p.Parse(c)
+	c.trace.Exit(n.name, c.FileInfo(), ok)
+
+	return v, ok
+}
+
+// NewTextTracer returns a tracer that logs to w, indenting the log
+// by how deeply nested the named parsers are
+func NewTextTracer(w io.Writer) Tracer {
+	return &textTracer{w: w}
+}
+
+type textTracer struct {
+	w     io.Writer
+	start []FileInfo
+}
+
+func (t *textTracer) Enter(name string, fi FileInfo) {
+	fmt.Fprintf(t.w, "%senter %s %s\n",
+		strings.Repeat("  ", len(t.start)), name, fi)
+	t.start = append(t.start, fi)
+}
+
+func (t *textTracer) Exit(name string, fi FileInfo, ok bool) {
+	start := t.start[len(t.start)-1]
+	t.start = t.start[:len(t.start)-1]
+
+	res := "fail"
+	if ok {
+		res = "ok"
+	}
+
+	fmt.Fprintf(t.w, "%s%s %s %s -> %s\n",
+		strings.Repeat("  ", len(t.start)), res, name, start, fi)
+}
+
+// TraceNode is a named parse recorded by a TraceTree
+type TraceNode struct {
+	Name     string       `json:"name"`
+	From     FileInfo     `json:"from"`
+	To       FileInfo     `json:"to"`
+	Ok       bool         `json:"ok"`
+	Children []*TraceNode `json:"children,omitempty"`
+}
+
+// TraceTree is a Tracer that records named parses as a tree, which can
+// be written out as JSON or Graphviz
+type TraceTree struct {
+	Roots []*TraceNode
+
+	stack []*TraceNode
+}
+
+func (t *TraceTree) Enter(name string, fi FileInfo) {
+	n := &TraceNode{
+		Name: name,
+		From: fi,
+	}
+
+	if len(t.stack) == 0 {
+		t.Roots = append(t.Roots, n)
+	} else {
+		parent := t.stack[len(t.stack)-1]
+		parent.Children = append(parent.Children, n)
+	}
+
+	t.stack = append(t.stack, n)
+}
+
+func (t *TraceTree) Exit(name string, fi FileInfo, ok bool) {
+	n := t.stack[len(t.stack)-1]
+	t.stack = t.stack[:len(t.stack)-1]
+
+	n.To = fi
+	n.Ok = ok
+}
+
+// WriteJSON writes the roots of the tree as a JSON array
+func (t *TraceTree) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+
+	roots := t.Roots
+	if roots == nil {
+		roots = []*TraceNode{}
+	}
+
+	return enc.Encode(roots)
+}
+
+// WriteDot writes the tree as a Graphviz digraph, failed parses are
+// colored red and successful ones green
+func (t *TraceTree) WriteDot(w io.Writer) error {
+	var (
+		b    strings.Builder
+		id   int
+		walk func(n *TraceNode) int
+	)
+
+	walk = func(n *TraceNode) int {
+		id++
+		nid := id
+
+		color := "red"
+		if n.Ok {
+			color = "green"
+		}
+
+		label := fmt.Sprintf("%s\n%d:%d-%d:%d", n.Name,
+			n.From.Line, n.From.Col, n.To.Line, n.To.Col)
+		fmt.Fprintf(&b, "\tn%d [label=%q color=%s];\n", nid, label, color)
+
+		for _, v := range n.Children {
+			fmt.Fprintf(&b, "\tn%d -> n%d;\n", nid, walk(v))
+		}
+
+		return nid
+	}
+
+	b.WriteString("digraph trace {\n")
+	for _, v := range t.Roots {
+		walk(v)
+	}
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,110 @@
+package parser_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/ear7h/lang/ast/parser"
+	"github.com/ear7h/lang/internal/assert"
+)
+
+func TestTracer(t *testing.T) {
+	type tcase struct {
+		str string
+		p   parser.Parser
+		log string
+	}
+
+	fn := func(tc tcase) func(t *testing.T) {
+		return func(t *testing.T) {
+			var buf bytes.Buffer
+
+			c := parser.NewCursorString(tc.str, "test")
+			c.SetTracer(parser.NewTextTracer(&buf))
+			tc.p.Parse(c)
+
+			assert.Eq(t, strings.TrimLeft(tc.log, "\n"), buf.String())
+		}
+	}
+
+	tcases := map[string]tcase{
+		"unnamed": tcase{
+			str: "asd",
+			p:   parser.ExpectString("asd"),
+			log: "",
+		},
+		"ok": tcase{
+			str: "asd",
+			p:   parser.Named("asd", parser.ExpectString("asd")),
+			log: `
+enter asd test:1:1
+ok asd test:1:1 -> test:1:4
+`,
+		},
+		"nested": tcase{
+			str: "asdqwe",
+			p: parser.Named("all", parser.All(
+				parser.Named("asd", parser.ExpectString("asd")),
+				parser.First(
+					parser.Named("zxc", parser.ExpectString("zxc")),
+					parser.Named("qwe", parser.ExpectString("qwe")),
+				),
+			)),
+			log: `
+enter all test:1:1
+  enter asd test:1:1
+  ok asd test:1:1 -> test:1:4
+  enter zxc test:1:4
+  fail zxc test:1:4 -> test:1:4
+  enter qwe test:1:4
+  ok qwe test:1:4 -> test:1:7
+ok all test:1:1 -> test:1:7
+`,
+		},
+	}
+
+	for k, v := range tcases {
+		t.Run(k, fn(v))
+	}
+}
+
+func TestTraceTree(t *testing.T) {
+	var tree parser.TraceTree
+
+	c := parser.NewCursorString("asdqwe", "test")
+	c.SetTracer(&tree)
+	parser.Named("all", parser.All(
+		parser.Named("asd", parser.ExpectString("asd")),
+		parser.Named("zxc", parser.ExpectString("zxc")),
+	)).Parse(c)
+
+	fi := func(col int64) parser.FileInfo {
+		return parser.FileInfo{Name: "test", Line: 1, Col: col}
+	}
+
+	assert.Eq(t, []*parser.TraceNode{
+		{
+			Name: "all",
+			From: fi(1),
+			To:   fi(4),
+			Ok:   false,
+			Children: []*parser.TraceNode{
+				{Name: "asd", From: fi(1), To: fi(4), Ok: true},
+				{Name: "zxc", From: fi(4), To: fi(4), Ok: false},
+			},
+		},
+	}, tree.Roots)
+
+	var buf bytes.Buffer
+	err := tree.WriteDot(&buf)
+	assert.ErrIs(t, nil, err)
+	assert.Eq(t, `digraph trace {
+	n1 [label="all\n1:1-1:4" color=red];
+	n2 [label="asd\n1:1-1:4" color=green];
+	n1 -> n2;
+	n3 [label="zxc\n1:4-1:4" color=red];
+	n1 -> n3;
+}
+`, buf.String())
+}
@@ -0,0 +1,294 @@
+// Package typed is a type safe counterpart to the combinators in the
+// parser package. Parsers from either package can be used with the
+// other through Lift and Erase, so grammars can be migrated piece by
+// piece.
+package typed
+
+import (
+	"github.com/ear7h/lang/ast/parser"
+)
+
+type Parser[T any] interface {
+	Parse(*parser.Cursor) (ret T, ok bool)
+}
+
+type Func[T any] func(*parser.Cursor) (ret T, ok bool)
+
+func (fn Func[T]) Parse(c *parser.Cursor) (T, bool) {
+	return fn(c)
+}
+
+// Lift adapts an untyped parser, its results must be a T, or nil in
+// which case the zero T is returned.
+func Lift[T any](p parser.Parser) Parser[T] {
+	return describe(Func[T](func(c *parser.Cursor) (T, bool) {
+		var ret T
+
+		v, ok := p.Parse(c)
+		if !ok {
+			return ret, false
+		}
+
+		if v == nil {
+			return ret, true
+		}
+
+		ret, isT := v.(T)
+		if !isT {
+			c.Fatalf("typed.Lift: expected %T, got %T", ret, v)
+		}
+
+		return ret, true
+	}), func() *parser.Syntax {
+		return parser.SyntaxOf(p)
+	})
+}
+
+// Erase adapts p to the parser.Parser interface
+func Erase[T any](p Parser[T]) parser.Parser {
+	return erased[T]{p}
+}
+
+type erased[T any] struct {
+	p Parser[T]
+}
+
+func (e erased[T]) Parse(c *parser.Cursor) (interface{}, bool) {
+	v, ok := e.p.Parse(c)
+	if !ok {
+		return nil, false
+	}
+
+	return v, true
+}
+
+func (e erased[T]) Syntax() *parser.Syntax {
+	return parser.SyntaxOf(e.p)
+}
+
+// String matches s, see parser.ExpectString
+func String(s string) Parser[string] {
+	return Lift[string](parser.ExpectString(s))
+}
+
+// FirstString matches the first of slc, see parser.FirstString
+func FirstString(slc ...string) Parser[string] {
+	return Lift[string](parser.FirstString(slc...))
+}
+
+// Map returns a parser that parses p and applies fn to its result
+func Map[A, B any](p Parser[A], fn func(A) B) Parser[B] {
+	return describe(Func[B](func(c *parser.Cursor) (B, bool) {
+		v, ok := p.Parse(c)
+		if !ok {
+			var zero B
+			return zero, false
+		}
+
+		return fn(v), true
+	}), func() *parser.Syntax {
+		return parser.SyntaxOf(p)
+	})
+}
+
+// Seq2 returns a parser that parses a then b, like parser.All, and
+// combines their results with fn
+func Seq2[A, B, R any](a Parser[A], b Parser[B], fn func(A, B) R) Parser[R] {
+	return describe(Func[R](func(c *parser.Cursor) (R, bool) {
+		var zero R
+
+		va, ok := attempt(c, a)
+		if !ok {
+			return zero, false
+		}
+
+		vb, ok := attempt(c, b)
+		if !ok {
+			return zero, false
+		}
+
+		return fn(va, vb), true
+	}), func() *parser.Syntax {
+		return syntaxOp(parser.SyntaxSeq, a, b)
+	})
+}
+
+// Seq3 returns a parser that parses a, b then c, like parser.All, and
+// combines their results with fn
+func Seq3[A, B, C, R any](a Parser[A], b Parser[B], c Parser[C],
+	fn func(A, B, C) R) Parser[R] {
+
+	return describe(Func[R](func(cur *parser.Cursor) (R, bool) {
+		var zero R
+
+		va, ok := attempt(cur, a)
+		if !ok {
+			return zero, false
+		}
+
+		vb, ok := attempt(cur, b)
+		if !ok {
+			return zero, false
+		}
+
+		vc, ok := attempt(cur, c)
+		if !ok {
+			return zero, false
+		}
+
+		return fn(va, vb, vc), true
+	}), func() *parser.Syntax {
+		return syntaxOp(parser.SyntaxSeq, a, b, c)
+	})
+}
+
+// Alt returns a parser that returns the result of the first of ps to
+// match, like parser.First
+func Alt[T any](ps ...Parser[T]) Parser[T] {
+	return describe(Func[T](func(c *parser.Cursor) (T, bool) {
+		for _, p := range ps {
+			v, ok := attempt(c, p)
+			if ok {
+				return v, true
+			}
+		}
+
+		var zero T
+		return zero, false
+	}), func() *parser.Syntax {
+		ret := &parser.Syntax{Op: parser.SyntaxAlt}
+		for _, v := range ps {
+			ret.Args = append(ret.Args, parser.SyntaxOf(v))
+		}
+
+		return ret
+	})
+}
+
+// Many returns a parser that matches p zero or more times
+func Many[T any](p Parser[T]) Parser[[]T] {
+	return describe(Func[[]T](func(c *parser.Cursor) ([]T, bool) {
+		ret := []T{}
+
+		for {
+			start := c.FileInfo()
+
+			v, ok := attempt(c, p)
+			if !ok {
+				return ret, true
+			}
+
+			ret = append(ret, v)
+
+			if c.FileInfo() == start {
+				c.Fatalf("typed.Many: parser matched nothing at %s", start)
+			}
+		}
+	}), func() *parser.Syntax {
+		return syntaxOp(parser.SyntaxMany, p)
+	})
+}
+
+// SepBy returns a parser that matches zero or more p separated by sep,
+// the results of sep are dropped
+func SepBy[T, S any](p Parser[T], sep Parser[S]) Parser[[]T] {
+	return describe(Func[[]T](func(c *parser.Cursor) ([]T, bool) {
+		ret := []T{}
+
+		v, ok := attempt(c, p)
+		if !ok {
+			return ret, true
+		}
+		ret = append(ret, v)
+
+		rest, _ := Many(Seq2(sep, p, func(_ S, v T) T {
+			return v
+		})).Parse(c)
+
+		return append(ret, rest...), true
+	}), func() *parser.Syntax {
+		return &parser.Syntax{Op: parser.SyntaxOpt, Args: []*parser.Syntax{
+			sepBy1(p, sep),
+		}}
+	})
+}
+
+// attempt parses p on a copy of c, and only moves c if p matched
+func attempt[T any](c *parser.Cursor, p Parser[T]) (T, bool) {
+	cc := *c
+	v, ok := p.Parse(&cc)
+	if ok {
+		*c = cc
+	}
+
+	return v, ok
+}
+
+// ChainL1 returns a parser for one or more p separated by op, the
+// results of p are combined from the left with the function returned
+// by op, so it parses left associative operators. See parser.ChainL1.
+func ChainL1[T any](p Parser[T], op Parser[func(left, right T) T]) Parser[T] {
+	return describe(Func[T](func(c *parser.Cursor) (T, bool) {
+		left, ok := attempt(c, p)
+		if !ok {
+			return left, false
+		}
+
+		for {
+			// op and the right operand are matched together
+			cc := *c
+
+			fn, ok := op.Parse(&cc)
+			if !ok {
+				return left, true
+			}
+
+			right, ok := p.Parse(&cc)
+			if !ok {
+				return left, true
+			}
+
+			*c = cc
+
+			left = fn(left, right)
+		}
+	}), func() *parser.Syntax {
+		return sepBy1(p, op)
+	})
+}
+
+// described is a parser which carries its syntax, see parser.Describe
+type described[T any] struct {
+	Func[T]
+	syntax func() *parser.Syntax
+}
+
+func (d described[T]) Syntax() *parser.Syntax {
+	return d.syntax()
+}
+
+// describe attaches a syntax to fn, it is only built when asked for so
+// grammars can be recursive
+func describe[T any](fn Func[T], syntax func() *parser.Syntax) Parser[T] {
+	return described[T]{Func: fn, syntax: syntax}
+}
+
+// syntaxOp returns a syntax with the op, whose args are the syntax of ps
+func syntaxOp(op string, ps ...interface{}) *parser.Syntax {
+	ret := &parser.Syntax{Op: op}
+	for _, v := range ps {
+		ret.Args = append(ret.Args, parser.SyntaxOf(v))
+	}
+
+	return ret
+}
+
+// sepBy1 is the syntax of one or more p separated by sep
+func sepBy1(p, sep interface{}) *parser.Syntax {
+	return &parser.Syntax{Op: parser.SyntaxSeq, Args: []*parser.Syntax{
+		parser.SyntaxOf(p),
+		{Op: parser.SyntaxMany, Args: []*parser.Syntax{
+			syntaxOp(parser.SyntaxSeq, sep, p),
+		}},
+	}}
+}
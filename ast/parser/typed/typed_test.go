@@ -0,0 +1,165 @@
+package typed_test
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ear7h/lang/ast/parser"
+	"github.com/ear7h/lang/ast/parser/typed"
+	"github.com/ear7h/lang/internal/assert"
+)
+
+func TestParsers(t *testing.T) {
+	type tcase struct {
+		str string
+		p   parser.Parser
+		ok  bool
+		err error
+		out interface{}
+	}
+
+	fn := func(tc tcase) func(t *testing.T) {
+		return func(t *testing.T) {
+			_, v, ok, err :=
+				parser.DoParseStringForTest(tc.p, tc.str, "test")
+
+			assert.ErrIs(t, tc.err, err)
+			assert.Eq(t, tc.ok, ok)
+			if !ok || err != nil {
+				return
+			}
+
+			assert.Eq(t, tc.out, v)
+		}
+	}
+
+	digit := typed.Alt(
+		typed.String("0"),
+		typed.String("1"),
+		typed.String("2"),
+	)
+
+	num := typed.Map(digit, func(s string) int {
+		n, _ := strconv.Atoi(s)
+		return n
+	})
+
+	tcases := map[string]tcase{
+		"Lift": tcase{
+			str: "asd",
+			ok:  true,
+			p: typed.Erase(
+				typed.Lift[string](parser.ExpectString("asd")),
+			),
+			out: "asd",
+		},
+		"Lift nil": tcase{
+			str: "asd",
+			ok:  true,
+			p: typed.Erase(
+				typed.Lift[*int](parser.Maybe(parser.ExpectString("qwe"))),
+			),
+			out: (*int)(nil),
+		},
+		"Map": tcase{
+			str: "1",
+			ok:  true,
+			p:   typed.Erase(num),
+			out: 1,
+		},
+		"Alt": tcase{
+			str: "2",
+			ok:  true,
+			p:   typed.Erase(digit),
+			out: "2",
+		},
+		"Alt fail": tcase{
+			str: "3",
+			ok:  false,
+			p:   typed.Erase(digit),
+		},
+		"Seq2": tcase{
+			str: "12",
+			ok:  true,
+			p: typed.Erase(typed.Seq2(num, num, func(a, b int) int {
+				return a*10 + b
+			})),
+			out: 12,
+		},
+		"Seq2 fail": tcase{
+			str: "1a",
+			ok:  false,
+			p: typed.Erase(typed.Seq2(num, num, func(a, b int) int {
+				return a*10 + b
+			})),
+		},
+		"Seq3": tcase{
+			str: "1+2",
+			ok:  true,
+			p: typed.Erase(typed.Seq3(num, typed.String("+"), num,
+				func(a int, _ string, b int) int {
+					return a + b
+				},
+			)),
+			out: 3,
+		},
+		"Many": tcase{
+			str: "0120",
+			ok:  true,
+			p:   typed.Erase(typed.Many(num)),
+			out: []int{0, 1, 2, 0},
+		},
+		"Many none": tcase{
+			str: "a",
+			ok:  true,
+			p:   typed.Erase(typed.Many(num)),
+			out: []int{},
+		},
+		"SepBy": tcase{
+			str: "0,1,2",
+			ok:  true,
+			p:   typed.Erase(typed.SepBy(num, typed.String(","))),
+			out: []int{0, 1, 2},
+		},
+		"SepBy trailing": tcase{
+			str: "0,1,",
+			ok:  true,
+			p: typed.Erase(typed.Seq2(
+				typed.SepBy(num, typed.String(",")),
+				typed.String(","),
+				func(v []int, _ string) []int {
+					return v
+				},
+			)),
+			out: []int{0, 1},
+		},
+		"SepBy none": tcase{
+			str: "a",
+			ok:  true,
+			p:   typed.Erase(typed.SepBy(num, typed.String(","))),
+			out: []int{},
+		},
+	}
+
+	for k, v := range tcases {
+		t.Run(k, fn(v))
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	num := typed.Lift[string](parser.Lexical("number",
+		parser.PlusPred(func(r rune) bool { return '0' <= r && r <= '9' })))
+
+	p := typed.Erase(typed.ChainL1(
+		typed.Alt(num, typed.String("x")),
+		typed.Map(typed.FirstString("+", "-"),
+			func(string) func(l, r string) string { return nil }),
+	))
+
+	var b strings.Builder
+	err := parser.Describe(parser.Named("sum", p)).WriteEBNF(&b)
+	assert.Eq(t, nil, err)
+	assert.Eq(t, `sum = ( ? number ? | "x" ) , `+
+		`{ ( "+" | "-" ) , ( ? number ? | "x" ) } ;`+"\n", b.String())
+}
@@ -0,0 +1,193 @@
+package ast
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// FieldFilter decides which struct fields Fprint prints, name is the
+// name of the field and v its value
+type FieldFilter func(name string, v reflect.Value) bool
+
+// NotNilFilter is a FieldFilter which leaves out nil fields
+func NotNilFilter(_ string, v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Chan, reflect.Func, reflect.Interface, reflect.Map,
+		reflect.Ptr, reflect.Slice:
+		return !v.IsNil()
+	}
+
+	return true
+}
+
+// NoPositions is a FieldFilter which leaves out positions, so trees
+// parsed from differently laid out sources print the same
+func NoPositions(_ string, v reflect.Value) bool {
+	return v.Type() != fileInfoType
+}
+
+// Print prints x to the standard output, leaving out nil fields
+func Print(x interface{}) error {
+	return Fprint(os.Stdout, x, NotNilFilter)
+}
+
+// Fprint prints the tree x to w, one field per line, indented by depth
+// like go/ast.Print. The fields of embedded structs, like BaseNode, are
+// printed as fields of the node, positions are printed as
+// file:line:col, runes are quoted and map entries are sorted by key.
+// Only the struct fields f returns true for are printed, f may be nil
+// to print all of them.
+func Fprint(w io.Writer, x interface{}, f FieldFilter) error {
+	p := &printer{
+		w:      w,
+		filter: f,
+		stack:  map[uintptr]bool{},
+	}
+
+	p.value(reflect.ValueOf(x))
+	p.printf("\n")
+
+	return p.err
+}
+
+type printer struct {
+	w      io.Writer
+	filter FieldFilter
+	indent int
+	err    error
+
+	// the pointers being printed, to stop at cycles
+	stack map[uintptr]bool
+}
+
+func (p *printer) printf(format string, args ...interface{}) {
+	if p.err != nil {
+		return
+	}
+
+	s := fmt.Sprintf(format, args...)
+	s = strings.ReplaceAll(s, "\n", "\n"+strings.Repeat(".  ", p.indent))
+	_, p.err = io.WriteString(p.w, s)
+}
+
+func (p *printer) value(v reflect.Value) {
+	if !v.IsValid() {
+		p.printf("nil")
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Interface:
+		p.value(v.Elem())
+
+	case reflect.Ptr:
+		if v.IsNil() {
+			p.printf("nil")
+			return
+		}
+
+		if p.stack[v.Pointer()] {
+			p.printf("%s (cycle)", v.Type())
+			return
+		}
+
+		p.stack[v.Pointer()] = true
+		p.printf("*")
+		p.value(v.Elem())
+		delete(p.stack, v.Pointer())
+
+	case reflect.Slice, reflect.Array:
+		p.printf("%s (len = %d) {", v.Type(), v.Len())
+		if v.Len() > 0 {
+			p.indent++
+			for i := 0; i < v.Len(); i++ {
+				p.printf("\n%d: ", i)
+				p.value(v.Index(i))
+			}
+			p.indent--
+			p.printf("\n")
+		}
+		p.printf("}")
+
+	case reflect.Map:
+		p.printf("%s (len = %d) {", v.Type(), v.Len())
+		if v.Len() > 0 {
+			p.indent++
+			// in a stable order
+			keys := v.MapKeys()
+			sort.Slice(keys, func(i, j int) bool {
+				return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j])
+			})
+
+			for _, k := range keys {
+				p.printf("\n")
+				p.value(k)
+				p.printf(": ")
+				p.value(v.MapIndex(k))
+			}
+			p.indent--
+			p.printf("\n")
+		}
+		p.printf("}")
+
+	case reflect.Struct:
+		if v.Type() == fileInfoType {
+			p.printf("%v", v.Interface())
+			return
+		}
+
+		p.printf("%s {", v.Type())
+		p.indent++
+		n := p.fields(v)
+		p.indent--
+		if n > 0 {
+			p.printf("\n")
+		}
+		p.printf("}")
+
+	case reflect.String:
+		p.printf("%q", v.String())
+
+	case reflect.Int32:
+		p.printf("%q", rune(v.Int()))
+
+	default:
+		if v.CanInterface() {
+			p.printf("%v", v.Interface())
+		} else {
+			p.printf("%v", v)
+		}
+	}
+}
+
+// fields prints the exported fields of the struct v, flattening
+// embedded structs, and returns how many were printed
+func (p *printer) fields(v reflect.Value) int {
+	n := 0
+	for i := 0; i < v.NumField(); i++ {
+		sf := v.Type().Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+
+		f := v.Field(i)
+		if sf.Anonymous && f.Kind() == reflect.Struct {
+			n += p.fields(f)
+			continue
+		}
+
+		if p.filter != nil && !p.filter(sf.Name, f) {
+			continue
+		}
+
+		p.printf("\n%s: ", sf.Name)
+		p.value(f)
+		n++
+	}
+
+	return n
+}
@@ -0,0 +1,95 @@
+package ast_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ear7h/lang/ast"
+	"github.com/ear7h/lang/internal/assert"
+)
+
+func TestFprint(t *testing.T) {
+	type tcase struct {
+		str    string
+		filter ast.FieldFilter
+		out    string
+	}
+
+	fn := func(tc tcase) func(t *testing.T) {
+		return func(t *testing.T) {
+			f, _ := ast.ParseFile("test", tc.str)
+
+			var b strings.Builder
+			err := ast.Fprint(&b, f.Stmts[0], tc.filter)
+			assert.Eq(t, nil, err)
+			assert.Eq(t, tc.out, b.String())
+		}
+	}
+
+	tcases := map[string]tcase{
+		"positions": tcase{
+			str: "-x",
+			out: `*ast.ExprStmt {
+.  Fi: test:1:1
+.  EndFi: test:1:3
+.  X: *ast.UnaryExpr {
+.  .  Fi: test:1:1
+.  .  EndFi: test:1:3
+.  .  Op: '-'
+.  .  Operand: *ast.Ident {
+.  .  .  Fi: test:1:2
+.  .  .  EndFi: test:1:3
+.  .  .  IsExported: false
+.  .  .  Name: "x"
+.  .  }
+.  }
+}
+`,
+		},
+		"no positions": tcase{
+			str:    "1 + \"a\"",
+			filter: ast.NoPositions,
+			out: `*ast.ExprStmt {
+.  X: *ast.BinaryExpr {
+.  .  Op: "+"
+.  .  Left: *ast.NumberLiteral {
+.  .  .  Orig: "1"
+.  .  .  Parsed: 1
+.  .  }
+.  .  Right: *ast.StringLiteral {
+.  .  .  Orig: "\"a\""
+.  .  .  Parsed: "a"
+.  .  }
+.  }
+}
+`,
+		},
+		"not nil": tcase{
+			str:    "return; )",
+			filter: ast.NotNilFilter,
+			out: `*ast.ReturnStmt {
+.  Fi: test:1:1
+.  EndFi: test:1:7
+}
+`,
+		},
+	}
+
+	for k, v := range tcases {
+		t.Run(k, fn(v))
+	}
+}
+
+func TestFprintValues(t *testing.T) {
+	var b strings.Builder
+	err := ast.Fprint(&b, map[string][]int{"a": {1, 2}, "b": nil}, nil)
+	assert.Eq(t, nil, err)
+
+	assert.Eq(t, "map[string][]int (len = 2) {\n"+
+		".  \"a\": []int (len = 2) {\n"+
+		".  .  0: 1\n"+
+		".  .  1: 2\n"+
+		".  }\n"+
+		".  \"b\": []int (len = 0) {}\n"+
+		"}\n", b.String())
+}
@@ -0,0 +1,191 @@
+package ast
+
+import (
+	"fmt"
+	"sort"
+	"unicode/utf8"
+
+	"github.com/ear7h/lang/ast/parser"
+)
+
+// Edit is a change to a source text, the Del bytes at the byte offset
+// Off are replaced by Ins
+type Edit struct {
+	Off int64
+	Del int64
+	Ins string
+}
+
+// Apply returns src with the edit made
+func (e Edit) Apply(src string) string {
+	return src[:e.Off] + e.Ins + src[e.Off+e.Del:]
+}
+
+// Reparse returns the tree of src after the edit e, where old is the
+// tree of src before it and oldErr the error parsing it gave. Only the
+// statements around the edit are parsed again, the ones before it are
+// reused as is and the ones after it are reused with their positions
+// moved, along with their errors. changed are the statements which were
+// parsed again, and err is like the error of ParseFile.
+//
+// The nodes of old are moved into the returned tree, so old must not be
+// used afterwards.
+func Reparse(old *File, oldErr error, src string, e Edit) (f *File, changed []Node, err error) {
+	if e.Off < 0 || e.Del < 0 || e.Off+e.Del > int64(len(src)) {
+		return nil, nil, fmt.Errorf("ast: edit %+v out of range", e)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			f, changed = nil, nil
+			if rerr, ok := r.(error); ok {
+				err = rerr
+			} else {
+				err = fmt.Errorf("%v", r)
+			}
+		}
+	}()
+
+	newSrc := e.Apply(src)
+	oldIdx := newLineIndex(src)
+	newIdx := newLineIndex(newSrc)
+
+	starts := make([]int64, len(old.Stmts))
+	byStart := map[int64]int{}
+	for i, v := range old.Stmts {
+		starts[i] = oldIdx.offset(v.(Node).FileInfo())
+		byStart[starts[i]] = i
+	}
+
+	// statements are reused if the next one starts before the edit,
+	// unless they failed to parse, since the parse may have read past
+	// them
+	k := 0
+	for k+1 < len(starts) && starts[k+1] <= e.Off &&
+		len(badErrors(old.Stmts[k].(Node))) == 0 {
+		k++
+	}
+
+	off := int64(0)
+	if k > 0 {
+		off = starts[k]
+	}
+
+	f = &File{
+		BaseNode: old.BaseNode,
+		Name:     old.Name,
+		Stmts:    append([]interface{}(nil), old.Stmts[:k]...),
+	}
+	f.EndFi = newIdx.fileInfo(old.Name, int64(len(newSrc)))
+
+	c := parser.NewCursorStringAt(newSrc, off, newIdx.fileInfo(old.Name, off))
+	shift := int64(len(e.Ins)) - e.Del
+
+	// the old errors before the parsed statements are kept, those after
+	// them are kept once the reused statements are known
+	oldErrs, _ := oldErr.(parser.ErrorList)
+	var errs parser.ErrorList
+	for _, v := range oldErrs {
+		if oldIdx.offset(v.Fi) < off {
+			errs = append(errs, v)
+		}
+	}
+
+	for {
+		parser.WS().Parse(c)
+
+		// past the edit, the old statements can be reused from the
+		// first one the new ones line up with
+		pos := c.Offset()
+		if pos >= e.Off+int64(len(e.Ins)) {
+			if j, ok := byStart[pos-shift]; ok {
+				move := func(fi parser.FileInfo) parser.FileInfo {
+					return newIdx.fileInfo(fi.Name, oldIdx.offset(fi)+shift)
+				}
+
+				for _, v := range oldErrs {
+					if oldIdx.offset(v.Fi) >= starts[j] {
+						errs = append(errs, &parser.Error{Fi: move(v.Fi), Msg: v.Msg})
+					}
+				}
+
+				for _, v := range old.Stmts[j:] {
+					mapFileInfo(v.(Node), move)
+				}
+
+				f.Stmts = append(f.Stmts, old.Stmts[j:]...)
+				break
+			}
+		}
+
+		v, ok := nextStmt(c, parser.ExpectEOF(), stmtSync)
+		if !ok {
+			break
+		}
+
+		f.Stmts = append(f.Stmts, v)
+		changed = append(changed, v.(Node))
+	}
+
+	errs = append(errs, c.Errors()...)
+	errs.Sort()
+
+	return f, changed, errs.Err()
+}
+
+// lineIndex converts between the byte offsets of a source text and its
+// line and column positions
+type lineIndex struct {
+	src   string
+	lines []int64 // the offset of each line
+}
+
+func newLineIndex(src string) lineIndex {
+	l := lineIndex{src: src, lines: []int64{0}}
+	for i, v := range src {
+		if v == '\n' {
+			l.lines = append(l.lines, int64(i)+1)
+		}
+	}
+
+	return l
+}
+
+// offset returns the offset of fi. Positions past the end of the text,
+// as left by reading the end of the input, are counted in bytes.
+func (l lineIndex) offset(fi parser.FileInfo) int64 {
+	off := l.lines[fi.Line-1]
+	for col := int64(1); col < fi.Col; col++ {
+		if off >= int64(len(l.src)) {
+			off++
+			continue
+		}
+
+		_, n := utf8.DecodeRuneInString(l.src[off:])
+		off += int64(n)
+	}
+
+	return off
+}
+
+// fileInfo returns the position of off, the inverse of offset
+func (l lineIndex) fileInfo(name string, off int64) parser.FileInfo {
+	past := int64(0)
+	if off > int64(len(l.src)) {
+		past = off - int64(len(l.src))
+		off = int64(len(l.src))
+	}
+
+	line := sort.Search(len(l.lines), func(i int) bool {
+		return l.lines[i] > off
+	})
+
+	start := l.lines[line-1]
+	col := int64(utf8.RuneCountInString(l.src[start:off])) + 1
+
+	return parser.FileInfo{
+		Name: name,
+		Line: int64(line),
+		Col:  col + past,
+	}
+}
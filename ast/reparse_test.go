@@ -0,0 +1,145 @@
+package ast_test
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/ear7h/lang/ast"
+	"github.com/ear7h/lang/internal/assert"
+)
+
+func TestReparse(t *testing.T) {
+	type tcase struct {
+		src     string
+		edit    ast.Edit
+		changed []string // the new source of the changed statements
+	}
+
+	fn := func(tc tcase) func(t *testing.T) {
+		return func(t *testing.T) {
+			old, oldErr := ast.ParseFile("test", tc.src)
+			newSrc := tc.edit.Apply(tc.src)
+
+			expect, expectErr := ast.ParseFile("test", newSrc)
+			got, changed, err := ast.Reparse(old, oldErr, tc.src, tc.edit)
+
+			if !reflect.DeepEqual(expect, got) {
+				t.Fatalf("expected: %#v\ngot: %#v", expect, got)
+			}
+			if !reflect.DeepEqual(expectErr, err) {
+				t.Fatalf("expected: %v\ngot: %v", expectErr, err)
+			}
+
+			var changedStmts []interface{}
+			for _, v := range tc.changed {
+				f, _ := ast.ParseFile("test", v)
+				changedStmts = append(changedStmts, f.Stmts...)
+			}
+
+			var gotChanged []interface{}
+			for _, v := range changed {
+				gotChanged = append(gotChanged, v)
+			}
+
+			assert.Eq(t, changedStmts, gotChanged)
+		}
+	}
+
+	tcases := map[string]tcase{
+		"noop": tcase{
+			src:  "1\n2\n3",
+			edit: ast.Edit{Off: 2},
+		},
+		"middle": tcase{
+			src:     "1\n2\n3",
+			edit:    ast.Edit{Off: 2, Del: 1, Ins: "a.b"},
+			changed: []string{"a.b"},
+		},
+		"same line": tcase{
+			src:     "1; 2; 3 + 4\n5",
+			edit:    ast.Edit{Off: 3, Del: 1, Ins: "22"},
+			changed: []string{"22"},
+		},
+		"new line": tcase{
+			src:     "1; 2 + 3; 4",
+			edit:    ast.Edit{Off: 4, Del: 3, Ins: "\n"},
+			changed: []string{"2", "3"},
+		},
+		"join": tcase{
+			src:     "1\n2\n3\n4",
+			edit:    ast.Edit{Off: 3, Del: 1, Ins: " +"},
+			changed: []string{"2 + 3"},
+		},
+		"end": tcase{
+			src:     "1\n2",
+			edit:    ast.Edit{Off: 3, Ins: "3"},
+			changed: []string{"23"},
+		},
+		"error": tcase{
+			src:     "1\n(2 +)\n3",
+			edit:    ast.Edit{Off: 0, Del: 1, Ins: "x"},
+			changed: []string{"x"},
+		},
+		"escapes around the edit": tcase{
+			src:     "\"\\q\"\n1\n\"\\w\"",
+			edit:    ast.Edit{Off: 5, Del: 1, Ins: "2"},
+			changed: []string{"2"},
+		},
+		"escapes moved": tcase{
+			src:  "1\n\"a\\q\"",
+			edit: ast.Edit{Off: 0, Ins: "\n"},
+		},
+		"new escape": tcase{
+			src:     "1\n2",
+			edit:    ast.Edit{Off: 2, Del: 1, Ins: "\"\\q\""},
+			changed: []string{"\"\\q\""},
+		},
+		"fix error": tcase{
+			src:     "1\n2 +;\n3",
+			edit:    ast.Edit{Off: 5, Ins: "5"},
+			changed: []string{"2 +5"},
+		},
+	}
+
+	for k, v := range tcases {
+		t.Run(k, fn(v))
+	}
+}
+
+// TestReparseRandom checks that reparsing gives the same tree and
+// errors as parsing from scratch, for random edits
+func TestReparseRandom(t *testing.T) {
+	start := "a.b + 1\nreturn (2 *\n3)\n\"x\"; -y\n1 +; 2\n(4 +)\nreturn\nz"
+	src := start
+	pieces := []string{"", "\n", ";", " ", "1", "+", "(", ")", "a.", "return ",
+		`"\q"`}
+
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		// garbage is slow to parse, start over before there's too much
+		if i%20 == 0 {
+			src = start
+		}
+
+		off := r.Int63n(int64(len(src)) + 1)
+		e := ast.Edit{
+			Off: off,
+			Del: r.Int63n(int64(len(src))-off+1) % 4,
+			Ins: pieces[r.Intn(len(pieces))] + pieces[r.Intn(len(pieces))],
+		}
+
+		old, oldErr := ast.ParseFile("test", src)
+		next := e.Apply(src)
+
+		expect, expectErr := ast.ParseFile("test", next)
+		got, _, err := ast.Reparse(old, oldErr, src, e)
+
+		if !reflect.DeepEqual(expect, got) ||
+			!reflect.DeepEqual(expectErr, err) {
+			t.Fatalf("%q %+v:\nexpected: %v\ngot: %v", src, e, expectErr, err)
+		}
+
+		src = next
+	}
+}
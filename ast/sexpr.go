@@ -0,0 +1,105 @@
+package ast
+
+import (
+	"strconv"
+	"strings"
+)
+
+// SExpr returns the tree rooted at n as an S-expression, like
+// (+ 1 (* 2 3)) for 1 + 2 * 3. Operators are applied to their operands,
+// field selections are (. object field...) and calls (call f arg...),
+// functions are (fn (param...) stmt...), statements are their
+// expression, (return x), (let x value) or (block stmt...), a file is
+// (file stmt...) and bad nodes are (bad). Parentheses in the source are
+// left out, the nesting shows the grouping.
+func SExpr(n interface{}) string {
+	var b strings.Builder
+	sexpr(&b, n)
+
+	return b.String()
+}
+
+func sexpr(b *strings.Builder, n interface{}) {
+	list := func(head string, args ...interface{}) {
+		b.WriteString("(" + head)
+		for _, v := range args {
+			b.WriteString(" ")
+			sexpr(b, v)
+		}
+		b.WriteString(")")
+	}
+
+	switch n := n.(type) {
+	case *File:
+		list("file", n.Stmts...)
+	case *ExprStmt:
+		sexpr(b, n.X)
+	case *ReturnStmt:
+		if n.X == nil {
+			list(KeywordReturn)
+		} else {
+			list(KeywordReturn, n.X)
+		}
+	case *LetStmt:
+		list(KeywordLet, n.Name, n.X)
+	case *BlockStmt:
+		list("block", n.Stmts...)
+	case *FuncLit:
+		b.WriteString("(" + KeywordFn + " (")
+		for i, v := range n.Params {
+			if i > 0 {
+				b.WriteString(" ")
+			}
+			b.WriteString(v.Name)
+		}
+		b.WriteString(")")
+		for _, v := range n.Body.Stmts {
+			b.WriteString(" ")
+			sexpr(b, v)
+		}
+		b.WriteString(")")
+	case *UnaryExpr:
+		list(string(n.Op), n.Operand)
+	case *BinaryExpr:
+		list(n.Op, n.Left, n.Right)
+	case *ObjExpr:
+		// consecutive fields are selected in one list
+		obj := SExpr(n.Object)
+		var fields []string
+		flush := func() {
+			if len(fields) > 0 {
+				obj = "(. " + obj + " " + strings.Join(fields, " ") + ")"
+				fields = nil
+			}
+		}
+
+		for v := n; v != nil; v, _ = v.Right.(*ObjExpr) {
+			if v.Op != ObjCall {
+				fields = append(fields, SExpr(v.Arg))
+				continue
+			}
+
+			flush()
+			call := []string{"call", obj}
+			for _, v := range v.Args {
+				call = append(call, SExpr(v))
+			}
+			obj = "(" + strings.Join(call, " ") + ")"
+		}
+		flush()
+
+		b.WriteString(obj)
+	case *Ident:
+		b.WriteString(n.Name)
+	case *NumberLiteral:
+		b.WriteString(strconv.FormatInt(n.Parsed, 10))
+	case *StringLiteral:
+		b.WriteString(strconv.Quote(n.Parsed))
+	case *BadStmt, *BadExpr:
+		list("bad")
+	case nil:
+		b.WriteString("()")
+	default:
+		list("unknown")
+	}
+}
@@ -0,0 +1,62 @@
+package ast_test
+
+import (
+	"testing"
+
+	"github.com/ear7h/lang/ast"
+	"github.com/ear7h/lang/internal/assert"
+)
+
+func TestSExpr(t *testing.T) {
+	type tcase struct {
+		str string
+		out string
+	}
+
+	fn := func(tc tcase) func(t *testing.T) {
+		return func(t *testing.T) {
+			f, _ := ast.ParseFile("test", tc.str)
+			assert.Eq(t, tc.out, ast.SExpr(f))
+		}
+	}
+
+	tcases := map[string]tcase{
+		"empty": tcase{
+			str: "",
+			out: "(file)",
+		},
+		"binary": tcase{
+			str: "1 + 2 * 3",
+			out: "(file (+ 1 (* 2 3)))",
+		},
+		"parens": tcase{
+			str: "(1 + 2) * 3",
+			out: "(file (* (+ 1 2) 3))",
+		},
+		"unary": tcase{
+			str: "-a.b.c",
+			out: "(file (- (. a b c)))",
+		},
+		"stmts": tcase{
+			str: "return\nreturn \"a\\n\"; 007",
+			out: `(file (return) (return "a\n") 7)`,
+		},
+		"funcs": tcase{
+			str: "let f = fn(a, b) { return a }\nf(1).x.y(2)()",
+			out: "(file (let f (fn (a b) (return a))) " +
+				"(call (call (. (call f 1) x y) 2)))",
+		},
+		"block": tcase{
+			str: "{ 1; {} }",
+			out: "(file (block 1 (block)))",
+		},
+		"bad": tcase{
+			str: "1 +\n(+)",
+			out: "(file (+ 1 (bad)))",
+		},
+	}
+
+	for k, v := range tcases {
+		t.Run(k, fn(v))
+	}
+}
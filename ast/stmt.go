@@ -0,0 +1,247 @@
+package ast
+
+import (
+	"github.com/ear7h/lang/ast/parser"
+)
+
+type StmtParser struct{}
+
+func (p StmtParser) Parse(c *parser.Cursor) (interface{}, bool) {
+	return p.grammar().Parse(c)
+}
+
+func (p StmtParser) Syntax() *parser.Syntax {
+	return parser.SyntaxOf(p.grammar())
+}
+
+func (StmtParser) grammar() parser.Parser {
+	return parser.Named("stmt", parser.First(
+		parser.Named("let-stmt", &LetStmt{}),
+		parser.Named("return-stmt", &ReturnStmt{}),
+		parser.Named("block", &BlockStmt{}),
+		parser.Named("expr-stmt", &ExprStmt{}),
+	))
+}
+
+// ExprStmt is an expression evaluated for its side effects
+type ExprStmt struct {
+	BaseNode
+	X interface{}
+}
+
+func (n *ExprStmt) Parse(c *parser.Cursor) (interface{}, bool) {
+	n.setFileInfo(c)
+
+	var ok bool
+	n.X, ok = ExprParser{}.Parse(c)
+	if !ok {
+		return nil, false
+	}
+
+	n.setEnd(c)
+
+	return n, true
+}
+
+func (*ExprStmt) Syntax() *parser.Syntax {
+	return parser.SyntaxOf(ExprParser{})
+}
+
+// ReturnStmt returns from a function, X is nil for a bare return. The
+// value must start on the same line as the keyword.
+type ReturnStmt struct {
+	BaseNode
+	X interface{}
+}
+
+func (n *ReturnStmt) Parse(c *parser.Cursor) (interface{}, bool) {
+	n.setFileInfo(c)
+
+	v, ok := n.grammar().Parse(c)
+	if !ok {
+		return nil, false
+	}
+
+	n.X = v.([]interface{})[1]
+	n.setEnd(c)
+
+	return n, true
+}
+
+func (n *ReturnStmt) Syntax() *parser.Syntax {
+	return parser.SyntaxOf(n.grammar())
+}
+
+func (*ReturnStmt) grammar() parser.Parser {
+	return parser.All(
+		Keyword(KeywordReturn),
+		parser.Maybe(parser.AllIdx(1,
+			parser.HS(),
+			ExprParser{},
+		)),
+	)
+}
+
+// LetStmt declares the variable Name with the value of X. The name and
+// the "=" must be on the same line as the keyword.
+type LetStmt struct {
+	BaseNode
+	Name *Ident
+	X    interface{}
+}
+
+func (n *LetStmt) Parse(c *parser.Cursor) (interface{}, bool) {
+	n.setFileInfo(c)
+
+	v, ok := n.grammar().Parse(c)
+	if !ok {
+		return nil, false
+	}
+
+	slc := v.([]interface{})
+	n.Name = slc[2].(*Ident)
+	n.X = slc[6]
+	n.setEnd(c)
+
+	return n, true
+}
+
+func (n *LetStmt) Syntax() *parser.Syntax {
+	return parser.SyntaxOf(n.grammar())
+}
+
+func (*LetStmt) grammar() parser.Parser {
+	return parser.All(
+		Keyword(KeywordLet),
+		parser.HS(),
+		&Ident{},
+		parser.HS(),
+		parser.ExpectString("="),
+		parser.WS(),
+		ExprParser{},
+	)
+}
+
+// BlockStmt is a list of statements in braces, the statements are
+// terminated like in a file
+type BlockStmt struct {
+	BaseNode
+	Stmts []interface{}
+}
+
+func (n *BlockStmt) Parse(c *parser.Cursor) (interface{}, bool) {
+	n.setFileInfo(c)
+
+	_, ok := parser.ExpectString("{").Parse(c)
+	if !ok {
+		return nil, false
+	}
+
+	// an unterminated block ends with the input
+	end := parser.First(
+		parser.Lookahead(parser.ExpectString("}")),
+		parser.ExpectEOF(),
+	)
+	n.Stmts = parseStmts(c, end, blockSync)
+
+	_, ok = parser.ExpectString("}").Parse(c)
+	if !ok {
+		return nil, false
+	}
+
+	n.setEnd(c)
+
+	return n, true
+}
+
+func (*BlockStmt) Syntax() *parser.Syntax {
+	return parser.SyntaxOf(parser.All(
+		parser.ExpectString("{"),
+		stmtsGrammar(parser.ExpectString("}")),
+	))
+}
+
+// BadStmt is a placeholder for a statement that failed to parse,
+// it spans the input that was skipped, and Err is the syntax
+// error it was replaced for.
+type BadStmt struct {
+	BaseNode
+	To  parser.FileInfo
+	Err *parser.Error
+}
+
+// stmtSync are the strings statement parsing recovers at
+var stmtSync = []string{";", "\n"}
+
+// blockSync are the strings statement parsing in a block recovers at,
+// the end of the block too
+var blockSync = []string{";", "\n", "}"}
+
+// parseStmts parses statements, each followed by a terminator, until
+// the input is at end. A terminator is a semicolon or the end of the
+// line, like in Go a statement continues on the next line only if its
+// line ends with an operator or inside parentheses. Statements that
+// fail to parse are recorded as errors on the cursor and replaced by a
+// *BadStmt, skipping to the first of the sync strings.
+func parseStmts(c *parser.Cursor, end parser.Parser, sync []string) []interface{} {
+	var ret []interface{}
+
+	for {
+		v, ok := nextStmt(c, end, sync)
+		if !ok {
+			return ret
+		}
+
+		ret = append(ret, v)
+	}
+}
+
+// nextStmt skips white space and parses the next statement, ok is false
+// if the input is at end instead
+func nextStmt(c *parser.Cursor, end parser.Parser, sync []string) (v interface{}, ok bool) {
+	parser.WS().Parse(c)
+
+	cc := *c
+	if _, ok := end.Parse(&cc); ok {
+		return nil, false
+	}
+
+	v, _ = parser.Recover(terminatedStmt(end), sync...).Parse(c)
+
+	if bad, ok := v.(*parser.Bad); ok {
+		n := &BadStmt{To: bad.To, Err: bad.Err}
+		n.setFi(bad.From)
+		n.EndFi = bad.To
+		v = n
+
+		cc = *c
+		if _, ok := parser.ExpectString(";").Parse(&cc); ok {
+			*c = cc
+		}
+	}
+
+	return v, true
+}
+
+// terminatedStmt returns a parser for a statement and its terminator,
+// the terminator may be left out before end
+func terminatedStmt(end parser.Parser) parser.Parser {
+	return parser.AllIdx(0,
+		StmtParser{},
+		parser.HS(),
+		parser.First(
+			parser.ExpectString(";"),
+			parser.Newline(),
+			parser.Lookahead(end),
+		),
+	)
+}
+
+// stmtsGrammar describes the input matched by parseStmts
+func stmtsGrammar(end parser.Parser) parser.Parser {
+	return parser.All(
+		parser.Many(parser.AllIdx(1, parser.WS(), terminatedStmt(end))),
+		parser.WS(),
+		end,
+	)
+}
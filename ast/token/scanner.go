@@ -0,0 +1,209 @@
+package token
+
+import (
+	"fmt"
+
+	"github.com/ear7h/lang/ast"
+	"github.com/ear7h/lang/ast/parser"
+)
+
+// Scanner reads tokens from source text, using the same rune level
+// parsers as the ast package so tokens split exactly where they would
+// when parsing runes.
+type Scanner struct {
+	// Trivia makes the scanner keep the white space around tokens, see
+	// Token
+	Trivia bool
+
+	c *parser.Cursor
+
+	// whether the last token ends a statement at the end of its line
+	endsLine bool
+}
+
+func NewScanner(src, name string) *Scanner {
+	return &Scanner{
+		c: parser.NewCursorString(src, name),
+	}
+}
+
+// Scan returns the next token, or an EOF token at the end of the input.
+// Runes which don't start a token are returned as Illegal tokens and
+// recorded as errors. A line break after a token which can end a
+// statement is returned as a Newline token, with the value "\n".
+func (s *Scanner) Scan() Token {
+	tok := s.scan()
+	s.endsLine = tok.endsLine()
+
+	if tok.Kind != Newline && tok.Kind != EOF {
+		trailing := s.space(parser.HS())
+		if s.Trivia {
+			tok.Trailing = trailing
+		}
+	}
+
+	return tok
+}
+
+// space matches p, and returns the white space it matched
+func (s *Scanner) space(p parser.Parser) string {
+	var text string
+	parser.WriteTo(&text, p).Parse(s.c)
+
+	return text
+}
+
+func (s *Scanner) scan() Token {
+	if s.endsLine {
+		fi := s.c.FileInfo()
+
+		var text string
+		if _, ok := parser.WriteTo(&text, parser.Newline()).Parse(s.c); ok {
+			tok := Token{Kind: Newline, Value: "\n", Fi: fi}
+			if s.Trivia {
+				// the \r of \r\n
+				tok.Leading = text[:len(text)-1]
+			}
+
+			return tok
+		}
+	}
+
+	leading := s.space(parser.WS())
+	if !s.Trivia {
+		leading = ""
+	}
+
+	fi := s.c.FileInfo()
+
+	if _, ok := parser.ExpectEOF().Parse(s.c); ok {
+		return Token{Kind: EOF, Fi: fi, Leading: leading}
+	}
+
+	lexers := []struct {
+		kind Kind
+		p    parser.Parser
+	}{
+		{Keyword, parser.First(keywords...)},
+		{Ident, &ast.Ident{}},
+		{Number, &ast.NumberLiteral{}},
+		{String, &ast.StringLiteral{}},
+		{Operator, parser.FirstString(operators...)},
+	}
+
+	for _, v := range lexers {
+		var text string
+
+		cc := *s.c
+		if _, ok := parser.WriteTo(&text, v.p).Parse(&cc); ok {
+			*s.c = cc
+			return Token{Kind: v.kind, Value: text, Fi: fi, Leading: leading}
+		}
+	}
+
+	r := s.c.ReadRune()
+	s.c.Errorf(fi, "illegal character %q", r)
+
+	return Token{Kind: Illegal, Value: string(r), Fi: fi, Leading: leading}
+}
+
+// Errors returns the errors found while scanning
+func (s *Scanner) Errors() parser.ErrorList {
+	return s.c.Errors()
+}
+
+// Scan returns all the tokens in src, the last being an EOF token. The
+// error is a parser.ErrorList if there were illegal characters.
+func Scan(src, name string) (toks []Token, err error) {
+	return scan(NewScanner(src, name))
+}
+
+// ScanTrivia is like Scan, but the tokens keep their trivia so they add
+// up to src
+func ScanTrivia(src, name string) (toks []Token, err error) {
+	s := NewScanner(src, name)
+	s.Trivia = true
+
+	return scan(s)
+}
+
+func scan(s *Scanner) (toks []Token, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if rerr, ok := r.(error); ok {
+				err = rerr
+			} else {
+				err = fmt.Errorf("%v", r)
+			}
+		}
+	}()
+
+	for {
+		tok := s.Scan()
+		toks = append(toks, tok)
+		if tok.Kind == EOF {
+			return toks, s.Errors().Err()
+		}
+	}
+}
+
+// NewCursor scans src and returns a token level cursor over it, see
+// parser.NewCursorTokens. The cursor is returned along with any scanning
+// errors so parsing can carry on and report syntax errors too.
+func NewCursor(src, name string) (*parser.Cursor, error) {
+	toks, err := Scan(src, name)
+	return newCursor(toks), err
+}
+
+// newCursor returns a token level cursor over toks, or nil if scanning
+// them didn't get to the EOF token
+func newCursor(toks []Token) *parser.Cursor {
+	if len(toks) == 0 || toks[len(toks)-1].Kind != EOF {
+		return nil
+	}
+
+	ptoks := make([]parser.Token, len(toks)-1)
+	for i := range ptoks {
+		ptoks[i] = toks[i]
+	}
+
+	return parser.NewCursorTokens(ptoks, toks[len(toks)-1].Fi)
+}
+
+// ParseFile is like ast.ParseFile, but parses from tokens. Scanning and
+// syntax errors are returned together in a parser.ErrorList.
+func ParseFile(name, src string) (*ast.File, error) {
+	toks, err := Scan(src, name)
+	return parseFile(toks, err)
+}
+
+// ParseFileTrivia is like ParseFile, but also returns the tokens with
+// their trivia, for a concrete syntax tree
+func ParseFileTrivia(name, src string) (*ast.File, []Token, error) {
+	toks, err := ScanTrivia(src, name)
+	f, err := parseFile(toks, err)
+
+	return f, toks, err
+}
+
+// parseFile parses the tokens of a file, where err is the error from
+// scanning them
+func parseFile(toks []Token, err error) (*ast.File, error) {
+	c := newCursor(toks)
+	if c == nil {
+		return nil, err
+	}
+
+	scanErrs, _ := err.(parser.ErrorList)
+
+	v, _, err := parser.DoParse(&ast.File{}, c)
+	f, _ := v.(*ast.File)
+
+	if errs, ok := err.(parser.ErrorList); ok || err == nil {
+		errs = append(scanErrs, errs...)
+		errs.Sort()
+		return f, errs.Err()
+	}
+
+	return f, err
+}
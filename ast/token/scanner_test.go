@@ -0,0 +1,241 @@
+package token_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ear7h/lang/ast"
+	"github.com/ear7h/lang/ast/parser"
+	"github.com/ear7h/lang/ast/token"
+	"github.com/ear7h/lang/internal/assert"
+)
+
+func TestScan(t *testing.T) {
+	type tcase struct {
+		str  string
+		errs []string
+		out  []token.Token
+	}
+
+	fn := func(tc tcase) func(t *testing.T) {
+		return func(t *testing.T) {
+			toks, err := token.Scan(tc.str, "test")
+
+			var errs []string
+			if err != nil {
+				for _, v := range err.(parser.ErrorList) {
+					errs = append(errs, v.Error())
+				}
+			}
+
+			assert.Eq(t, tc.errs, errs)
+			assert.Eq(t, tc.out, toks)
+		}
+	}
+
+	fi := func(line, col int64) parser.FileInfo {
+		return parser.FileInfo{Name: "test", Line: line, Col: col}
+	}
+
+	tcases := map[string]tcase{
+		"empty": tcase{
+			str: "  ",
+			out: []token.Token{
+				{Kind: token.EOF, Fi: fi(1, 3)},
+			},
+		},
+		"literals": tcase{
+			str: "hello 123 \"a\\nb\"",
+			out: []token.Token{
+				{Kind: token.Ident, Value: "hello", Fi: fi(1, 1)},
+				{Kind: token.Number, Value: "123", Fi: fi(1, 7)},
+				{Kind: token.String, Value: `"a\nb"`, Fi: fi(1, 11)},
+				{Kind: token.EOF, Fi: fi(1, 17)},
+			},
+		},
+		"maximal munch": tcase{
+			str: "a<=b<c&&d&e",
+			out: []token.Token{
+				{Kind: token.Ident, Value: "a", Fi: fi(1, 1)},
+				{Kind: token.Operator, Value: "<=", Fi: fi(1, 2)},
+				{Kind: token.Ident, Value: "b", Fi: fi(1, 4)},
+				{Kind: token.Operator, Value: "<", Fi: fi(1, 5)},
+				{Kind: token.Ident, Value: "c", Fi: fi(1, 6)},
+				{Kind: token.Operator, Value: "&&", Fi: fi(1, 7)},
+				{Kind: token.Ident, Value: "d", Fi: fi(1, 9)},
+				{Kind: token.Operator, Value: "&", Fi: fi(1, 10)},
+				{Kind: token.Ident, Value: "e", Fi: fi(1, 11)},
+				{Kind: token.EOF, Fi: fi(1, 12)},
+			},
+		},
+		"lines": tcase{
+			str: "(a\n.b);\n",
+			out: []token.Token{
+				{Kind: token.Operator, Value: "(", Fi: fi(1, 1)},
+				{Kind: token.Ident, Value: "a", Fi: fi(1, 2)},
+				{Kind: token.Newline, Value: "\n", Fi: fi(1, 3)},
+				{Kind: token.Operator, Value: ".", Fi: fi(2, 1)},
+				{Kind: token.Ident, Value: "b", Fi: fi(2, 2)},
+				{Kind: token.Operator, Value: ")", Fi: fi(2, 3)},
+				{Kind: token.Operator, Value: ";", Fi: fi(2, 4)},
+				{Kind: token.EOF, Fi: fi(3, 1)},
+			},
+		},
+		"newlines": tcase{
+			str: "return \n\nx +\ny\r\nreturned",
+			out: []token.Token{
+				{Kind: token.Keyword, Value: "return", Fi: fi(1, 1)},
+				{Kind: token.Newline, Value: "\n", Fi: fi(1, 8)},
+				{Kind: token.Ident, Value: "x", Fi: fi(3, 1)},
+				{Kind: token.Operator, Value: "+", Fi: fi(3, 3)},
+				{Kind: token.Ident, Value: "y", Fi: fi(4, 1)},
+				{Kind: token.Newline, Value: "\n", Fi: fi(4, 2)},
+				{Kind: token.Ident, Value: "returned", Fi: fi(5, 1)},
+				{Kind: token.EOF, Fi: fi(5, 9)},
+			},
+		},
+		"illegal": tcase{
+			str: "1 $ 2",
+			errs: []string{
+				`test:1:3: illegal character '$'`,
+			},
+			out: []token.Token{
+				{Kind: token.Number, Value: "1", Fi: fi(1, 1)},
+				{Kind: token.Illegal, Value: "$", Fi: fi(1, 3)},
+				{Kind: token.Number, Value: "2", Fi: fi(1, 5)},
+				{Kind: token.EOF, Fi: fi(1, 6)},
+			},
+		},
+	}
+
+	for k, v := range tcases {
+		t.Run(k, fn(v))
+	}
+}
+
+func TestScanTrivia(t *testing.T) {
+	type tcase struct {
+		str string
+		out []token.Token
+	}
+
+	fn := func(tc tcase) func(t *testing.T) {
+		return func(t *testing.T) {
+			toks, _ := token.ScanTrivia(tc.str, "test")
+			assert.Eq(t, tc.out, toks)
+
+			src := ""
+			for _, v := range toks {
+				src += v.Source()
+			}
+			assert.Eq(t, tc.str, src)
+		}
+	}
+
+	fi := func(line, col int64) parser.FileInfo {
+		return parser.FileInfo{Name: "test", Line: line, Col: col}
+	}
+
+	tcases := map[string]tcase{
+		"empty": tcase{
+			str: " \n ",
+			out: []token.Token{
+				{Kind: token.EOF, Fi: fi(2, 2), Leading: " \n "},
+			},
+		},
+		"spaces": tcase{
+			str: "  a  +\tb ",
+			out: []token.Token{
+				{Kind: token.Ident, Value: "a", Fi: fi(1, 3), Leading: "  ", Trailing: "  "},
+				{Kind: token.Operator, Value: "+", Fi: fi(1, 6), Trailing: "\t"},
+				{Kind: token.Ident, Value: "b", Fi: fi(1, 8), Trailing: " "},
+				{Kind: token.EOF, Fi: fi(1, 10)},
+			},
+		},
+		"lines": tcase{
+			str: "a \r\n\n  b +\n c\n",
+			out: []token.Token{
+				{Kind: token.Ident, Value: "a", Fi: fi(1, 1), Trailing: " "},
+				{Kind: token.Newline, Value: "\n", Fi: fi(1, 3), Leading: "\r"},
+				{Kind: token.Ident, Value: "b", Fi: fi(3, 3), Leading: "\n  ", Trailing: " "},
+				{Kind: token.Operator, Value: "+", Fi: fi(3, 5)},
+				{Kind: token.Ident, Value: "c", Fi: fi(4, 2), Leading: "\n "},
+				{Kind: token.Newline, Value: "\n", Fi: fi(4, 3)},
+				{Kind: token.EOF, Fi: fi(5, 1)},
+			},
+		},
+		"illegal": tcase{
+			str: "1 $\n",
+			out: []token.Token{
+				{Kind: token.Number, Value: "1", Fi: fi(1, 1), Trailing: " "},
+				{Kind: token.Illegal, Value: "$", Fi: fi(1, 3)},
+				{Kind: token.EOF, Fi: fi(2, 1), Leading: "\n"},
+			},
+		},
+	}
+
+	for k, v := range tcases {
+		t.Run(k, fn(v))
+	}
+}
+
+// TestParseFile checks that parsing tokens gives the same tree, and
+// errors, as parsing runes
+func TestParseFile(t *testing.T) {
+	tcases := map[string]string{
+		"binary":    "1 + 2 * 3; 4 << 5",
+		"cmp":       "a <= b && c >= d || e != f",
+		"unary":     "-x.y; !a",
+		"paren":     "(1 + (2)).x.y",
+		"string":    `"a\tb" + "c"`,
+		"errors":    "1 +;\n2 2;\n(;\n3",
+		"bad expr":  "(1 +); 2",
+		"newlines":  "1\n2 \r\n\n3\n",
+		"continued": "1 +\n2\n(3\n).x\n",
+		"next line": "1\n+2\na\n.b",
+		"return":    "return\nreturn 1 +\n2; return",
+		"let":       "let x = 1 +\n2\nlet y = x",
+		"funcs":     "let f = fn(a, b) {\n\treturn a\n}\nf(1,\n2).x()",
+		"blocks":    "{ 1; {}\n{\n2\n} }",
+		"bad block": "{ 1 +; 2 }\n{ 3",
+	}
+
+	for k, v := range tcases {
+		v := v
+		t.Run(k, func(t *testing.T) {
+			expect, expectErr := ast.ParseFile("test", v)
+			got, gotErr := token.ParseFile("test", v)
+
+			// the errors are compared below
+			for _, f := range []*ast.File{expect, got} {
+				ast.Inspect(f, func(n ast.Node) bool {
+					switch n := n.(type) {
+					case *ast.BadStmt:
+						n.Err = nil
+					case *ast.BadExpr:
+						n.Err = nil
+					}
+					return true
+				})
+			}
+
+			if !reflect.DeepEqual(expect, got) {
+				t.Fatalf("expected: %#v\ngot: %#v", expect, got)
+			}
+
+			// the expectations in the messages may differ, since
+			// white space doesn't separate token positions
+			pos := func(err error) []parser.FileInfo {
+				var ret []parser.FileInfo
+				if err != nil {
+					for _, v := range err.(parser.ErrorList) {
+						ret = append(ret, v.Fi)
+					}
+				}
+				return ret
+			}
+
+			assert.Eq(t, pos(expectErr), pos(gotErr))
+		})
+	}
+}
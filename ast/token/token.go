@@ -0,0 +1,136 @@
+// Package token is a lexer for the language. It is an alternative front
+// end to parsing runes directly: the ast parsers run unchanged over the
+// token level cursor returned by NewCursor.
+package token
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/ear7h/lang/ast"
+	"github.com/ear7h/lang/ast/parser"
+)
+
+type Kind int
+
+const (
+	Illegal Kind = iota
+	EOF
+	Ident
+	Keyword
+	Number
+	String
+	Operator
+	Newline
+)
+
+var kindNames = [...]string{
+	Illegal:  "Illegal",
+	EOF:      "EOF",
+	Ident:    "Ident",
+	Keyword:  "Keyword",
+	Number:   "Number",
+	String:   "String",
+	Operator: "Operator",
+	Newline:  "Newline",
+}
+
+func (k Kind) String() string {
+	if int(k) < len(kindNames) {
+		return kindNames[k]
+	}
+
+	return fmt.Sprintf("Kind(%d)", int(k))
+}
+
+// Token is a lexical token, it implements parser.Token.
+//
+// Scanning with trivia keeps the white space around tokens: the
+// horizontal space after a token, up to the end of its line, is its
+// Trailing trivia, and the rest of the white space before a token is
+// its Leading trivia. The source of the tokens, with their trivia, adds
+// up to the scanned text.
+type Token struct {
+	Kind  Kind
+	Value string
+	Fi    parser.FileInfo
+
+	Leading, Trailing string
+}
+
+func (t Token) Text() string {
+	return t.Value
+}
+
+func (t Token) FileInfo() parser.FileInfo {
+	return t.Fi
+}
+
+// Source returns the text of the token with its trivia
+func (t Token) Source() string {
+	return t.Leading + t.Value + t.Trailing
+}
+
+func (t Token) String() string {
+	return fmt.Sprintf("%s %s %q", t.Fi, t.Kind, t.Value)
+}
+
+// endsLine reports whether a newline after t ends a statement, which
+// is the case after an identifier, a literal, a closing bracket or a
+// return. The parser expects the newline as a Newline token.
+func (t Token) endsLine() bool {
+	switch t.Kind {
+	case Ident, Number, String:
+		return true
+	case Keyword:
+		return t.Value == ast.KeywordReturn
+	case Operator:
+		return t.Value == ")" || t.Value == "]" || t.Value == "}"
+	}
+
+	return false
+}
+
+// punctuation are the operator tokens which are not ast operators
+var punctuation = []string{
+	"(", ")", ".", ";", "{", "}", ",", "=",
+}
+
+// operators are all the operator tokens, longest first so they are
+// scanned with maximal munch
+var operators = func() []string {
+	var ret []string
+	seen := map[string]bool{}
+	add := func(s string) {
+		if !seen[s] {
+			seen[s] = true
+			ret = append(ret, s)
+		}
+	}
+
+	for _, v := range ast.BinaryOperators() {
+		add(v)
+	}
+	for _, v := range ast.UnaryOperators() {
+		add(string(v))
+	}
+	for _, v := range punctuation {
+		add(v)
+	}
+
+	sort.SliceStable(ret, func(i, j int) bool {
+		return len(ret[i]) > len(ret[j])
+	})
+
+	return ret
+}()
+
+// keywords match the keyword tokens
+var keywords = func() []parser.Parser {
+	var ret []parser.Parser
+	for _, v := range ast.Keywords() {
+		ret = append(ret, ast.Keyword(v))
+	}
+
+	return ret
+}()
@@ -0,0 +1,112 @@
+package ast
+
+import (
+	"reflect"
+
+	"github.com/ear7h/lang/ast/parser"
+)
+
+var nodeType = reflect.TypeOf((*Node)(nil)).Elem()
+
+// Inspect traverses the tree rooted at n in depth first order, calling
+// fn on each node. The children of a node are only visited if fn
+// returns true.
+func Inspect(n Node, fn func(Node) bool) {
+	if isNil(n) || !fn(n) {
+		return
+	}
+
+	for _, v := range children(n) {
+		Inspect(v, fn)
+	}
+}
+
+// children returns the nodes in the fields of n, in order
+func children(n Node) []Node {
+	var (
+		ret  []Node
+		walk func(v reflect.Value)
+	)
+
+	walk = func(v reflect.Value) {
+		switch v.Kind() {
+		case reflect.Interface:
+			if !v.IsNil() {
+				walk(v.Elem())
+			}
+		case reflect.Ptr:
+			if !v.IsNil() && v.Type().Implements(nodeType) {
+				ret = append(ret, v.Interface().(Node))
+			}
+		case reflect.Slice:
+			for i := 0; i < v.Len(); i++ {
+				walk(v.Index(i))
+			}
+		}
+	}
+
+	v := reflect.ValueOf(n).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		walk(v.Field(i))
+	}
+
+	return ret
+}
+
+func isNil(n Node) bool {
+	if n == nil {
+		return true
+	}
+
+	v := reflect.ValueOf(n)
+	return v.Kind() == reflect.Ptr && v.IsNil()
+}
+
+var (
+	fileInfoType = reflect.TypeOf(parser.FileInfo{})
+	errorType    = reflect.TypeOf(&parser.Error{})
+)
+
+// mapFileInfo replaces each position in the tree rooted at n, including
+// the ends and errors of bad nodes, with its result of fn
+func mapFileInfo(n Node, fn func(parser.FileInfo) parser.FileInfo) {
+	var walk func(v reflect.Value)
+	walk = func(v reflect.Value) {
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Field(i)
+			switch {
+			case f.Type() == fileInfoType:
+				f.Set(reflect.ValueOf(fn(f.Interface().(parser.FileInfo))))
+			case f.Type() == errorType && !f.IsNil():
+				err := *f.Interface().(*parser.Error)
+				err.Fi = fn(err.Fi)
+				f.Set(reflect.ValueOf(&err))
+			case f.Kind() == reflect.Struct:
+				walk(f)
+			}
+		}
+	}
+
+	Inspect(n, func(n Node) bool {
+		walk(reflect.ValueOf(n).Elem())
+		return true
+	})
+}
+
+// badErrors returns the syntax errors of the bad nodes in the tree
+// rooted at n
+func badErrors(n Node) parser.ErrorList {
+	var ret parser.ErrorList
+	Inspect(n, func(n Node) bool {
+		switch n := n.(type) {
+		case *BadStmt:
+			ret = append(ret, n.Err)
+		case *BadExpr:
+			ret = append(ret, n.Err)
+		}
+
+		return true
+	})
+
+	return ret
+}
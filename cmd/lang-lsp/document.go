@@ -0,0 +1,177 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/ear7h/lang/ast"
+	"github.com/ear7h/lang/ast/parser"
+	"github.com/ear7h/lang/diag"
+	"github.com/ear7h/lang/resolve"
+	"github.com/ear7h/lang/types"
+)
+
+// document is an open text document and the result of analyzing it
+type document struct {
+	uri     string
+	version int
+	text    string
+	lines   []string
+
+	file  *ast.File
+	info  *resolve.Info
+	types map[ast.Node]types.Type
+	// syntax reports whether the file has syntax errors
+	syntax bool
+	diags  []*diag.Diagnostic
+}
+
+// newDocument parses, resolves and type checks text. The file is
+// analyzed even with syntax errors, the passes skip the bad nodes.
+func newDocument(uri string, version int, text string) *document {
+	d := &document{
+		uri:     uri,
+		version: version,
+		text:    text,
+		lines:   strings.Split(text, "\n"),
+	}
+
+	var perr, rerr, terr error
+	d.file, perr = ast.ParseFile(uri, text)
+	if d.file == nil {
+		// the parser failed without recovering
+		d.file = &ast.File{Name: uri}
+	}
+
+	d.info, rerr = resolve.Resolve(d.file)
+	d.types, terr = types.Check(d.file, nil)
+	d.syntax = perr != nil
+
+	// the passes may report the same error, like an undefined name
+	seen := map[parser.Error]bool{}
+	for _, err := range []error{perr, rerr, terr} {
+		for _, v := range diag.FromError(err) {
+			key := parser.Error{Fi: v.Span.Start, Msg: v.Message}
+			if seen[key] {
+				continue
+			}
+
+			seen[key] = true
+			d.diags = append(d.diags, v)
+		}
+	}
+
+	sort.SliceStable(d.diags, func(i, j int) bool {
+		a, b := d.diags[i].Span.Start, d.diags[j].Span.Start
+		return a.Line < b.Line || a.Line == b.Line && a.Col < b.Col
+	})
+
+	return d
+}
+
+// position returns the LSP position of fi
+func (d *document) position(fi parser.FileInfo) Position {
+	line := int(fi.Line) - 1
+	if line < 0 {
+		return Position{}
+	}
+	if line >= len(d.lines) {
+		return Position{Line: len(d.lines) - 1, Character: utf16Len(d.lines[len(d.lines)-1])}
+	}
+
+	// the UTF-16 length of the runes before the column
+	s := d.lines[line]
+	for i := int64(1); i < fi.Col && s != ""; i++ {
+		_, n := utf8.DecodeRuneInString(s)
+		s = s[n:]
+	}
+
+	return Position{
+		Line:      line,
+		Character: utf16Len(d.lines[line]) - utf16Len(s),
+	}
+}
+
+// fileInfo returns the position of p
+func (d *document) fileInfo(p Position) parser.FileInfo {
+	fi := parser.FileInfo{Name: d.uri, Line: int64(p.Line) + 1, Col: 1}
+	if p.Line < 0 || p.Line >= len(d.lines) {
+		return fi
+	}
+
+	n := 0
+	for _, r := range d.lines[p.Line] {
+		if n >= p.Character {
+			break
+		}
+
+		n += utf16.RuneLen(r)
+		fi.Col++
+	}
+
+	return fi
+}
+
+// span returns the range of s
+func (d *document) span(s diag.Span) Range {
+	s = s.Clip(d.text)
+	return Range{Start: d.position(s.Start), End: d.position(s.End)}
+}
+
+func (d *document) nodeRange(n ast.Node) Range {
+	return d.span(diag.NodeSpan(n))
+}
+
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		n += utf16.RuneLen(r)
+	}
+
+	return n
+}
+
+func before(a, b parser.FileInfo) bool {
+	return a.Line < b.Line || a.Line == b.Line && a.Col < b.Col
+}
+
+// path returns the nodes enclosing fi, from the file to the innermost
+func (d *document) path(fi parser.FileInfo) []ast.Node {
+	var ret []ast.Node
+	ast.Inspect(d.file, func(n ast.Node) bool {
+		if before(fi, n.FileInfo()) || !before(fi, n.End()) {
+			// the file ends where its last line does, include it
+			_, ok := n.(*ast.File)
+			return ok
+		}
+
+		ret = append(ret, n)
+		return true
+	})
+
+	return ret
+}
+
+// identAt returns the identifier at fi, or nil
+func (d *document) identAt(fi parser.FileInfo) *ast.Ident {
+	path := d.path(fi)
+	if len(path) == 0 {
+		return nil
+	}
+
+	id, _ := path[len(path)-1].(*ast.Ident)
+	return id
+}
+
+// objectAt returns the object the identifier at fi declares or refers
+// to, or nil
+func (d *document) objectAt(fi parser.FileInfo) *resolve.Object {
+	id := d.identAt(fi)
+	if id == nil {
+		return nil
+	}
+
+	return d.info.ObjectOf(id)
+}
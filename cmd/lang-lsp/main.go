@@ -0,0 +1,15 @@
+// Command lang-lsp is a language server for lang, speaking the Language
+// Server Protocol over the standard input and output.
+//
+// It keeps the open documents synced in full and reports their syntax,
+// name and type errors as diagnostics when they change. It provides
+// hovers with the types of expressions, going to the definition of and
+// finding the references to variables, document symbols for let
+// statements, semantic tokens and formatting.
+package main
+
+import "os"
+
+func main() {
+	os.Exit(serve(os.Stdin, os.Stdout))
+}
@@ -0,0 +1,375 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/ear7h/lang/internal/assert"
+)
+
+type request struct {
+	JSONRPC string      `json:"jsonrpc"`
+	ID      int         `json:"id"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params"`
+}
+
+// client is an in-process client of a server
+type client struct {
+	t    *testing.T
+	conn *conn
+	id   int
+	code chan int
+}
+
+func newClient(t *testing.T) *client {
+	sr, cw := io.Pipe()
+	cr, sw := io.Pipe()
+
+	c := &client{
+		t:    t,
+		conn: newConn(cr, cw),
+		code: make(chan int, 1),
+	}
+
+	go func() {
+		c.code <- serve(sr, sw)
+		sw.Close()
+	}()
+
+	return c
+}
+
+func (c *client) notify(method string, params interface{}) {
+	c.t.Helper()
+
+	err := c.conn.write(notification{JSONRPC: "2.0", Method: method, Params: params})
+	if err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// call sends a request and returns its response, skipping the
+// notifications sent before it
+func (c *client) call(method string, params interface{}) *message {
+	c.t.Helper()
+
+	c.id++
+	err := c.conn.write(request{JSONRPC: "2.0", ID: c.id, Method: method, Params: params})
+	if err != nil {
+		c.t.Fatal(err)
+	}
+
+	return c.response()
+}
+
+// response reads messages up to the next response
+func (c *client) response() *message {
+	c.t.Helper()
+
+	for {
+		msg := c.next()
+		if msg.ID != nil {
+			return msg
+		}
+	}
+}
+
+// next reads the next message
+func (c *client) next() *message {
+	c.t.Helper()
+
+	msg, err := c.conn.read()
+	if err != nil {
+		c.t.Fatal(err)
+	}
+
+	return msg
+}
+
+// result calls method and decodes the result into v
+func (c *client) result(method string, params, v interface{}) {
+	c.t.Helper()
+
+	msg := c.call(method, params)
+	if msg.Error != nil {
+		c.t.Fatal(msg.Error)
+	}
+
+	err := json.Unmarshal(msg.Result, v)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// diagnostics reads the next notification, which should publish
+// diagnostics
+func (c *client) diagnostics() PublishDiagnosticsParams {
+	c.t.Helper()
+
+	msg := c.next()
+	assert.Eq(c.t, "textDocument/publishDiagnostics", msg.Method)
+
+	var p PublishDiagnosticsParams
+	err := json.Unmarshal(msg.Params, &p)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+
+	return p
+}
+
+const uri = "file:///a.lang"
+
+func at(line, char int) TextDocumentPositionParams {
+	return TextDocumentPositionParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+		Position:     Position{Line: line, Character: char},
+	}
+}
+
+func rng(l0, c0, l1, c1 int) Range {
+	return Range{Start: Position{l0, c0}, End: Position{l1, c1}}
+}
+
+func TestSession(t *testing.T) {
+	c := newClient(t)
+	doc := TextDocumentIdentifier{URI: uri}
+
+	msg := c.call("textDocument/hover", at(0, 0))
+	assert.Eq(t, codeServerNotInitialized, msg.Error.Code)
+
+	var init InitializeResult
+	c.result("initialize", InitializeParams{}, &init)
+	assert.Eq(t, SyncFull, init.Capabilities.TextDocumentSync)
+	assert.Eq(t, tokenTypes, init.Capabilities.SemanticTokensProvider.Legend.TokenTypes)
+	c.notify("initialized", struct{}{})
+
+	msg = c.call("nope", nil)
+	assert.Eq(t, codeMethodNotFound, msg.Error.Code)
+
+	c.notify("textDocument/didOpen", DidOpenTextDocumentParams{
+		TextDocument: TextDocumentItem{
+			URI:     uri,
+			Version: 1,
+			Text: "let mul = fn(x, y) { return x  * y + 1 }\n" +
+				"let n = mul(1, 2)\n" +
+				"mul(n, \"a\") + b\n",
+		},
+	})
+
+	diags := c.diagnostics()
+	assert.Eq(t, uri, diags.URI)
+	assert.Eq(t, 1, diags.Version)
+	assert.Eq(t, []Diagnostic{
+		{
+			Range:    rng(2, 7, 2, 10),
+			Severity: SeverityError,
+			Source:   "lang",
+			Message:  "cannot use \"a\" (string) as int value in argument to mul",
+			RelatedInformation: []DiagnosticRelatedInformation{{
+				Location: Location{URI: uri, Range: rng(0, 28, 0, 38)},
+				Message:  "y is int because of x * y + 1",
+			}},
+		},
+		{
+			Range:    rng(2, 14, 2, 15),
+			Severity: SeverityError,
+			Source:   "lang",
+			Message:  "undefined: b",
+		},
+	}, diags.Diagnostics)
+
+	var hover Hover
+	c.result("textDocument/hover", at(1, 9), &hover)
+	assert.Eq(t, Hover{
+		Contents: MarkupContent{
+			Kind:  "markdown",
+			Value: "```lang\nvar mul fn(int, int) int\n```",
+		},
+		Range: rng(1, 8, 1, 11),
+	}, hover)
+
+	c.result("textDocument/hover", at(0, 28), &hover)
+	assert.Eq(t, "```lang\nparam x int\n```", hover.Contents.Value)
+
+	c.result("textDocument/hover", at(1, 12), &hover)
+	assert.Eq(t, "```lang\nint\n```", hover.Contents.Value)
+
+	var loc Location
+	c.result("textDocument/definition", at(2, 4), &loc)
+	assert.Eq(t, Location{URI: uri, Range: rng(1, 4, 1, 5)}, loc)
+
+	var locs []Location
+	c.result("textDocument/references", ReferenceParams{
+		TextDocumentPositionParams: at(0, 5),
+		Context:                    ReferenceContext{IncludeDeclaration: true},
+	}, &locs)
+	assert.Eq(t, []Location{
+		{URI: uri, Range: rng(0, 4, 0, 7)},
+		{URI: uri, Range: rng(1, 8, 1, 11)},
+		{URI: uri, Range: rng(2, 0, 2, 3)},
+	}, locs)
+
+	var syms []DocumentSymbol
+	c.result("textDocument/documentSymbol", DocumentSymbolParams{doc}, &syms)
+	assert.Eq(t, []DocumentSymbol{
+		{
+			Name:           "mul",
+			Detail:         "fn(int, int) int",
+			Kind:           SymbolFunction,
+			Range:          rng(0, 0, 0, 40),
+			SelectionRange: rng(0, 4, 0, 7),
+		},
+		{
+			Name:           "n",
+			Detail:         "int",
+			Kind:           SymbolVariable,
+			Range:          rng(1, 0, 1, 17),
+			SelectionRange: rng(1, 4, 1, 5),
+		},
+	}, syms)
+
+	var toks SemanticTokens
+	c.result("textDocument/semanticTokens/full", SemanticTokensParams{doc}, &toks)
+	assert.Eq(t, []int{
+		// let mul = fn(x, y) { return x  * y + 1 }
+		0, 0, 3, tokenKeyword, 0,
+		0, 4, 3, tokenFunction, modDeclaration,
+		0, 4, 1, tokenOperator, 0,
+		0, 2, 2, tokenKeyword, 0,
+		0, 3, 1, tokenParameter, modDeclaration,
+		0, 3, 1, tokenParameter, modDeclaration,
+		0, 5, 6, tokenKeyword, 0,
+		0, 7, 1, tokenParameter, 0,
+		0, 3, 1, tokenOperator, 0,
+		0, 2, 1, tokenParameter, 0,
+		0, 2, 1, tokenOperator, 0,
+		0, 2, 1, tokenNumber, 0,
+		// let n = mul(1, 2)
+		1, 0, 3, tokenKeyword, 0,
+		0, 4, 1, tokenVariable, modDeclaration,
+		0, 2, 1, tokenOperator, 0,
+		0, 2, 3, tokenFunction, 0,
+		0, 4, 1, tokenNumber, 0,
+		0, 3, 1, tokenNumber, 0,
+		// mul(n, "a") + b
+		1, 0, 3, tokenFunction, 0,
+		0, 4, 1, tokenVariable, 0,
+		0, 3, 3, tokenString, 0,
+		0, 5, 1, tokenOperator, 0,
+		0, 2, 1, tokenVariable, 0,
+	}, toks.Data)
+
+	var edits []TextEdit
+	c.result("textDocument/formatting", DocumentFormattingParams{doc}, &edits)
+	assert.Eq(t, []TextEdit{{
+		Range: rng(0, 0, 3, 0),
+		NewText: "let mul = fn(x, y) {\n\treturn x * y + 1\n}\n" +
+			"let n = mul(1, 2)\n" +
+			"mul(n, \"a\") + b\n",
+	}}, edits)
+
+	c.notify("textDocument/didChange", DidChangeTextDocumentParams{
+		TextDocument: VersionedTextDocumentIdentifier{URI: uri, Version: 2},
+		ContentChanges: []TextDocumentContentChangeEvent{
+			{Text: "1 +\n"},
+		},
+	})
+
+	diags = c.diagnostics()
+	assert.Eq(t, 2, diags.Version)
+	assert.Eq(t, 1, len(diags.Diagnostics))
+	assert.Eq(t, rng(0, 3, 0, 3), diags.Diagnostics[0].Range)
+
+	msg = c.call("textDocument/formatting", DocumentFormattingParams{doc})
+	assert.Eq(t, "null", string(msg.Result))
+
+	c.notify("textDocument/didChange", DidChangeTextDocumentParams{
+		TextDocument: VersionedTextDocumentIdentifier{URI: uri, Version: 3},
+		ContentChanges: []TextDocumentContentChangeEvent{
+			{Text: "1 + 2\n"},
+		},
+	})
+
+	diags = c.diagnostics()
+	assert.Eq(t, []Diagnostic{}, diags.Diagnostics)
+
+	c.result("textDocument/formatting", DocumentFormattingParams{doc}, &edits)
+	assert.Eq(t, []TextEdit{}, edits)
+
+	c.notify("textDocument/didClose", DidCloseTextDocumentParams{doc})
+	diags = c.diagnostics()
+	assert.Eq(t, []Diagnostic{}, diags.Diagnostics)
+
+	msg = c.call("textDocument/hover", at(0, 0))
+	assert.Eq(t, codeInvalidParams, msg.Error.Code)
+
+	msg = c.call("shutdown", nil)
+	assert.Eq(t, "null", string(msg.Result))
+	c.notify("exit", nil)
+	assert.Eq(t, 0, <-c.code)
+}
+
+func TestExitWithoutShutdown(t *testing.T) {
+	c := newClient(t)
+	c.notify("exit", nil)
+	assert.Eq(t, 1, <-c.code)
+}
+
+func TestPosition(t *testing.T) {
+	type tcase struct {
+		text string
+		line int64
+		col  int64
+		pos  Position
+	}
+
+	fn := func(tc tcase) func(t *testing.T) {
+		return func(t *testing.T) {
+			d := newDocument(uri, 1, tc.text)
+			fi := d.fileInfo(tc.pos)
+			assert.Eq(t, tc.line, fi.Line)
+			assert.Eq(t, tc.col, fi.Col)
+			assert.Eq(t, tc.pos, d.position(fi))
+		}
+	}
+
+	tcases := map[string]tcase{
+		"start": tcase{
+			text: "a",
+			line: 1,
+			col:  1,
+			pos:  Position{0, 0},
+		},
+		"second line": tcase{
+			text: "a\nbc",
+			line: 2,
+			col:  2,
+			pos:  Position{1, 1},
+		},
+		"end of line": tcase{
+			text: "a\nbc",
+			line: 2,
+			col:  3,
+			pos:  Position{1, 2},
+		},
+		"multibyte": tcase{
+			text: "\"é\" + a",
+			line: 1,
+			col:  7,
+			pos:  Position{0, 6},
+		},
+		"surrogate pair": tcase{
+			text: "\"😀\" + a",
+			line: 1,
+			col:  7,
+			pos:  Position{0, 7},
+		},
+	}
+
+	for k, v := range tcases {
+		t.Run(k, fn(v))
+	}
+}
@@ -0,0 +1,571 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ear7h/lang/ast"
+	"github.com/ear7h/lang/ast/token"
+	"github.com/ear7h/lang/diag"
+	"github.com/ear7h/lang/format"
+	"github.com/ear7h/lang/resolve"
+	"github.com/ear7h/lang/types"
+)
+
+// server is a language server for one client
+type server struct {
+	conn *conn
+	docs map[string]*document
+
+	initialized bool
+	shutdown    bool
+	exited      bool
+}
+
+type handler func(s *server, params json.RawMessage) (interface{}, error)
+
+var handlers = map[string]handler{
+	"initialize":                       (*server).initialize,
+	"initialized":                      (*server).nothing,
+	"shutdown":                         (*server).shutdownRequest,
+	"exit":                             (*server).exit,
+	"textDocument/didOpen":             (*server).didOpen,
+	"textDocument/didChange":           (*server).didChange,
+	"textDocument/didClose":            (*server).didClose,
+	"textDocument/didSave":             (*server).nothing,
+	"textDocument/hover":               (*server).hover,
+	"textDocument/definition":          (*server).definition,
+	"textDocument/references":          (*server).references,
+	"textDocument/documentSymbol":      (*server).documentSymbol,
+	"textDocument/semanticTokens/full": (*server).semanticTokens,
+	"textDocument/formatting":          (*server).formatting,
+}
+
+// serve serves the client reading from r and writing to w until it
+// sends the exit notification or r ends. It returns the exit code,
+// which is 1 unless the client asked for a shutdown first.
+func serve(r io.Reader, w io.Writer) int {
+	s := &server{
+		conn: newConn(r, w),
+		docs: map[string]*document{},
+	}
+
+	for !s.exited {
+		msg, err := s.conn.read()
+		if rerr, ok := err.(*rpcError); ok {
+			s.conn.write(errorResponse{JSONRPC: "2.0", Error: rerr})
+			continue
+		}
+
+		if err != nil {
+			return 1
+		}
+
+		s.handle(msg)
+	}
+
+	if !s.shutdown {
+		return 1
+	}
+
+	return 0
+}
+
+func (s *server) handle(msg *message) {
+	var result interface{}
+	var err error
+
+	h, ok := handlers[msg.Method]
+	switch {
+	case !ok:
+		err = errorf(codeMethodNotFound, "method not found: %s", msg.Method)
+	case !s.initialized && msg.Method != "initialize" && msg.Method != "exit":
+		err = errorf(codeServerNotInitialized, "server not initialized")
+	case s.shutdown && msg.Method != "exit":
+		err = errorf(codeInvalidRequest, "server is shut down")
+	default:
+		result, err = h(s, msg.Params)
+	}
+
+	if msg.ID == nil {
+		// notifications don't have responses
+		return
+	}
+
+	if err != nil {
+		rerr, ok := err.(*rpcError)
+		if !ok {
+			rerr = errorf(codeInternalError, "%v", err)
+		}
+
+		s.conn.write(errorResponse{JSONRPC: "2.0", ID: msg.ID, Error: rerr})
+		return
+	}
+
+	s.conn.write(response{JSONRPC: "2.0", ID: msg.ID, Result: result})
+}
+
+// unmarshal decodes the params of a request into v
+func unmarshal(params json.RawMessage, v interface{}) error {
+	err := json.Unmarshal(params, v)
+	if err != nil {
+		return errorf(codeInvalidParams, "%v", err)
+	}
+
+	return nil
+}
+
+func (s *server) nothing(json.RawMessage) (interface{}, error) {
+	return nil, nil
+}
+
+func (s *server) initialize(params json.RawMessage) (interface{}, error) {
+	var p InitializeParams
+	if err := unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+
+	s.initialized = true
+
+	return InitializeResult{
+		Capabilities: ServerCapabilities{
+			TextDocumentSync:           SyncFull,
+			HoverProvider:              true,
+			DefinitionProvider:         true,
+			ReferencesProvider:         true,
+			DocumentSymbolProvider:     true,
+			DocumentFormattingProvider: true,
+			SemanticTokensProvider: SemanticTokensOptions{
+				Legend: SemanticTokensLegend{
+					TokenTypes:     tokenTypes,
+					TokenModifiers: tokenModifiers,
+				},
+				Full: true,
+			},
+		},
+		ServerInfo: ServerInfo{Name: "lang-lsp"},
+	}, nil
+}
+
+func (s *server) shutdownRequest(json.RawMessage) (interface{}, error) {
+	s.shutdown = true
+	return nil, nil
+}
+
+func (s *server) exit(json.RawMessage) (interface{}, error) {
+	s.exited = true
+	return nil, nil
+}
+
+// document returns the open document uri
+func (s *server) document(uri string) (*document, error) {
+	d, ok := s.docs[uri]
+	if !ok {
+		return nil, errorf(codeInvalidParams, "document not open: %s", uri)
+	}
+
+	return d, nil
+}
+
+// update analyzes the text of the document and publishes its
+// diagnostics
+func (s *server) update(uri string, version int, text string) {
+	d := newDocument(uri, version, text)
+	s.docs[uri] = d
+
+	diags := make([]Diagnostic, len(d.diags))
+	for i, v := range d.diags {
+		diags[i] = Diagnostic{
+			Range:    d.span(v.Span),
+			Severity: severities[v.Severity],
+			Source:   "lang",
+			Message:  v.Message,
+		}
+
+		// the notes about the document are related information, the
+		// others are left in the message
+		for _, n := range v.Notes {
+			if n.Span.Start.Name != uri {
+				diags[i].Message += "\n" + n.Message
+				continue
+			}
+
+			diags[i].RelatedInformation = append(diags[i].RelatedInformation,
+				DiagnosticRelatedInformation{
+					Location: Location{URI: uri, Range: d.span(n.Span)},
+					Message:  n.Message,
+				})
+		}
+	}
+
+	s.conn.write(notification{
+		JSONRPC: "2.0",
+		Method:  "textDocument/publishDiagnostics",
+		Params: PublishDiagnosticsParams{
+			URI:         uri,
+			Version:     version,
+			Diagnostics: diags,
+		},
+	})
+}
+
+var severities = map[diag.Severity]int{
+	diag.Error:   SeverityError,
+	diag.Warning: SeverityWarning,
+	diag.Info:    SeverityInformation,
+}
+
+func (s *server) didOpen(params json.RawMessage) (interface{}, error) {
+	var p DidOpenTextDocumentParams
+	if err := unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+
+	s.update(p.TextDocument.URI, p.TextDocument.Version, p.TextDocument.Text)
+	return nil, nil
+}
+
+func (s *server) didChange(params json.RawMessage) (interface{}, error) {
+	var p DidChangeTextDocumentParams
+	if err := unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+
+	if len(p.ContentChanges) == 0 {
+		return nil, nil
+	}
+
+	// with full syncing the last change has the whole text
+	text := p.ContentChanges[len(p.ContentChanges)-1].Text
+	s.update(p.TextDocument.URI, p.TextDocument.Version, text)
+	return nil, nil
+}
+
+func (s *server) didClose(params json.RawMessage) (interface{}, error) {
+	var p DidCloseTextDocumentParams
+	if err := unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+
+	delete(s.docs, p.TextDocument.URI)
+
+	// clear the diagnostics of the closed document
+	s.conn.write(notification{
+		JSONRPC: "2.0",
+		Method:  "textDocument/publishDiagnostics",
+		Params: PublishDiagnosticsParams{
+			URI:         p.TextDocument.URI,
+			Diagnostics: []Diagnostic{},
+		},
+	})
+
+	return nil, nil
+}
+
+// hover shows the type of the innermost expression at the position,
+// and the kind of the object of an identifier
+func (s *server) hover(params json.RawMessage) (interface{}, error) {
+	var p TextDocumentPositionParams
+	if err := unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+
+	d, err := s.document(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	path := d.path(d.fileInfo(p.Position))
+	for i := len(path) - 1; i >= 0; i-- {
+		n := path[i]
+
+		t, ok := d.types[n]
+		if !ok {
+			continue
+		}
+
+		text := t.String()
+		if id, ok := n.(*ast.Ident); ok {
+			text = id.Name + " " + text
+			if obj := d.info.ObjectOf(id); obj != nil {
+				text = strings.ToLower(obj.Kind.String()) + " " + text
+			}
+		}
+
+		return Hover{
+			Contents: MarkupContent{
+				Kind:  "markdown",
+				Value: "```lang\n" + text + "\n```",
+			},
+			Range: d.nodeRange(n),
+		}, nil
+	}
+
+	return nil, nil
+}
+
+func (s *server) definition(params json.RawMessage) (interface{}, error) {
+	var p TextDocumentPositionParams
+	if err := unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+
+	d, err := s.document(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	obj := d.objectAt(d.fileInfo(p.Position))
+	if obj == nil || obj.Decl == nil {
+		return nil, nil
+	}
+
+	return Location{URI: d.uri, Range: d.nodeRange(obj.Decl)}, nil
+}
+
+func (s *server) references(params json.RawMessage) (interface{}, error) {
+	var p ReferenceParams
+	if err := unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+
+	d, err := s.document(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	obj := d.objectAt(d.fileInfo(p.Position))
+	if obj == nil || obj.Decl == nil {
+		return nil, nil
+	}
+
+	ret := []Location{}
+	if p.Context.IncludeDeclaration {
+		ret = append(ret, Location{URI: d.uri, Range: d.nodeRange(obj.Decl)})
+	}
+
+	for _, v := range obj.Uses {
+		ret = append(ret, Location{URI: d.uri, Range: d.nodeRange(v)})
+	}
+
+	return ret, nil
+}
+
+// documentSymbol returns the let statements, those in functions and
+// blocks are children of the enclosing let
+func (s *server) documentSymbol(params json.RawMessage) (interface{}, error) {
+	var p DocumentSymbolParams
+	if err := unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+
+	d, err := s.document(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.symbols(d.file), nil
+}
+
+// symbols returns the symbols of the let statements in the tree rooted
+// at n, outside of nested let statements
+func (d *document) symbols(n ast.Node) []DocumentSymbol {
+	ret := []DocumentSymbol{}
+	ast.Inspect(n, func(m ast.Node) bool {
+		let, ok := m.(*ast.LetStmt)
+		if !ok || m == n {
+			return true
+		}
+
+		sym := DocumentSymbol{
+			Name:           let.Name.Name,
+			Kind:           SymbolVariable,
+			Range:          d.nodeRange(let),
+			SelectionRange: d.nodeRange(let.Name),
+		}
+
+		if _, ok := let.X.(*ast.FuncLit); ok {
+			sym.Kind = SymbolFunction
+		}
+
+		if t, ok := d.types[let.Name]; ok {
+			sym.Detail = t.String()
+		}
+
+		if x, ok := let.X.(ast.Node); ok {
+			if children := d.symbols(x); len(children) > 0 {
+				sym.Children = children
+			}
+		}
+
+		ret = append(ret, sym)
+		return false
+	})
+
+	return ret
+}
+
+// the semantic token types and modifiers, the indexes are those of the
+// legend
+var (
+	tokenTypes = []string{
+		"keyword",
+		"variable",
+		"parameter",
+		"function",
+		"number",
+		"string",
+		"operator",
+	}
+
+	tokenModifiers = []string{
+		"declaration",
+		"readonly",
+	}
+)
+
+const (
+	tokenKeyword = iota
+	tokenVariable
+	tokenParameter
+	tokenFunction
+	tokenNumber
+	tokenString
+	tokenOperator
+)
+
+const (
+	modDeclaration = 1 << iota
+	modReadonly
+)
+
+func (s *server) semanticTokens(params json.RawMessage) (interface{}, error) {
+	var p SemanticTokensParams
+	if err := unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+
+	d, err := s.document(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	return SemanticTokens{Data: d.semanticTokens()}, nil
+}
+
+// semanticTokens returns the tokens of the document, in the relative
+// encoding of LSP
+func (d *document) semanticTokens() []int {
+	idents := map[Position]*ast.Ident{}
+	ast.Inspect(d.file, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok {
+			idents[d.position(id.FileInfo())] = id
+		}
+
+		return true
+	})
+
+	// scanning errors are reported as diagnostics
+	toks, _ := token.Scan(d.text, d.uri)
+
+	data := []int{}
+	var last Position
+	for _, tok := range toks {
+		typ, mods := 0, 0
+
+		switch tok.Kind {
+		case token.Keyword:
+			typ = tokenKeyword
+		case token.Number:
+			typ = tokenNumber
+		case token.String:
+			typ = tokenString
+		case token.Operator:
+			if strings.Contains("(){},;", tok.Value) {
+				continue
+			}
+
+			typ = tokenOperator
+		case token.Ident:
+			typ, mods = d.identToken(idents[d.position(tok.Fi)])
+		default:
+			continue
+		}
+
+		pos := d.position(tok.Fi)
+		delta := pos.Character
+		if pos.Line == last.Line {
+			delta -= last.Character
+		}
+
+		data = append(data, pos.Line-last.Line, delta, utf16Len(tok.Value),
+			typ, mods)
+		last = pos
+	}
+
+	return data
+}
+
+// identToken returns the token type and modifiers of the identifier id,
+// which may be nil if the parser didn't keep it
+func (d *document) identToken(id *ast.Ident) (int, int) {
+	if id == nil {
+		return tokenVariable, 0
+	}
+
+	mods := 0
+	if d.info.Defs[id] != nil {
+		mods |= modDeclaration
+	}
+
+	obj := d.info.ObjectOf(id)
+	switch {
+	case obj != nil && obj.Kind == resolve.Param:
+		return tokenParameter, mods
+	case obj != nil && obj.Kind == resolve.Const:
+		return tokenVariable, mods | modReadonly
+	}
+
+	if _, ok := d.types[id].(*types.Func); ok {
+		return tokenFunction, mods
+	}
+
+	return tokenVariable, mods
+}
+
+// formatting replaces the document with its canonical source, files
+// with syntax errors aren't formatted
+func (s *server) formatting(params json.RawMessage) (interface{}, error) {
+	var p DocumentFormattingParams
+	if err := unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+
+	d, err := s.document(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	if d.syntax {
+		return nil, nil
+	}
+
+	var b strings.Builder
+	if err := format.Node(&b, d.file); err != nil {
+		return nil, fmt.Errorf("formatting: %v", err)
+	}
+
+	if b.String() == d.text {
+		return []TextEdit{}, nil
+	}
+
+	last := len(d.lines) - 1
+	return []TextEdit{{
+		Range: Range{
+			End: Position{Line: last, Character: utf16Len(d.lines[last])},
+		},
+		NewText: b.String(),
+	}}, nil
+}
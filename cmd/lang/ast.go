@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/ear7h/lang/ast"
+)
+
+// astCmd prints the syntax trees of the files, as JSON with -json
+func astCmd(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	ff := newFileFlags("ast", "[flags] file...", stderr)
+	if !ff.parse(args) {
+		return 2
+	}
+
+	code := 0
+	srcs := map[string]string{}
+	for _, path := range ff.fset.Args() {
+		f, err := ff.parseFile(path, srcs)
+		if err != nil {
+			report(stderr, err, ff.json, srcs)
+			code = 1
+			continue
+		}
+
+		if !ff.json {
+			err = ast.Fprint(stdout, f, ast.NotNilFilter)
+		} else {
+			var b []byte
+			b, err = ast.MarshalJSON(f)
+			if err == nil {
+				fmt.Fprintf(stdout, "%s\n", b)
+			}
+		}
+
+		if err != nil {
+			report(stderr, err, ff.json, srcs)
+			code = 1
+		}
+	}
+
+	return code
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"io"
+
+	"github.com/ear7h/lang/constant"
+	"github.com/ear7h/lang/resolve"
+	"github.com/ear7h/lang/types"
+)
+
+// checkCmd parses, resolves, type checks and folds the constants of the
+// files, reporting all their errors
+func checkCmd(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	ff := newFileFlags("check", "[flags] file...", stderr)
+	if !ff.parse(args) {
+		return 2
+	}
+
+	code := 0
+	srcs := map[string]string{}
+	for _, path := range ff.fset.Args() {
+		f, err := ff.parseFile(path, srcs)
+		if err != nil {
+			// the other passes would only repeat the syntax errors
+			report(stderr, err, ff.json, srcs)
+			code = 1
+			continue
+		}
+
+		_, rerr := resolve.Resolve(f)
+		_, terr := types.Check(f, nil)
+		_, cerr := constant.Fold(f)
+
+		if err := merge(rerr, terr, cerr); err != nil {
+			report(stderr, err, ff.json, srcs)
+			code = 1
+		}
+	}
+
+	return code
+}
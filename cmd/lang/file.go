@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ear7h/lang/ast"
+	"github.com/ear7h/lang/ast/cache"
+	"github.com/ear7h/lang/ast/parser"
+	"github.com/ear7h/lang/diag"
+)
+
+// fileFlags are the flags of the commands taking files
+type fileFlags struct {
+	fset *flag.FlagSet
+	// json reports diagnostics as JSON
+	json bool
+	// cacheDir keeps the parsed files, if not empty
+	cacheDir string
+
+	cache *cache.Cache
+}
+
+func newFileFlags(name, usage string, stderr io.Writer) *fileFlags {
+	ff := &fileFlags{
+		fset: flag.NewFlagSet("lang "+name, flag.ContinueOnError),
+	}
+
+	ff.fset.SetOutput(stderr)
+	ff.fset.BoolVar(&ff.json, "json", false, "print diagnostics as JSON")
+	ff.fset.StringVar(&ff.cacheDir, "cache", "", "cache parsed files in `dir`")
+	ff.fset.Usage = func() {
+		fmt.Fprintf(stderr, "usage: lang %s %s\n", name, usage)
+		ff.fset.PrintDefaults()
+	}
+
+	return ff
+}
+
+// parse parses the arguments, which must include at least one file,
+// and reports whether they're valid
+func (ff *fileFlags) parse(args []string) bool {
+	if err := ff.fset.Parse(args); err != nil {
+		return false
+	}
+
+	if ff.fset.NArg() == 0 {
+		ff.fset.Usage()
+		return false
+	}
+
+	if ff.cacheDir != "" {
+		c, err := cache.New(ff.cacheDir)
+		if err != nil {
+			fmt.Fprintln(ff.fset.Output(), err)
+			return false
+		}
+
+		ff.cache = c
+	}
+
+	return true
+}
+
+// parseFile reads and parses the file at path, adding its source to
+// srcs. With -cache the tree is taken from the cache if the file hasn't
+// changed.
+func (ff *fileFlags) parseFile(path string, srcs map[string]string) (*ast.File, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	srcs[path] = string(src)
+
+	if ff.cache != nil {
+		return ff.cache.ParseFile(path, string(src))
+	}
+
+	return ast.ParseFile(path, string(src))
+}
+
+// jsonSpan is a span as printed by -json, the positions it doesn't have
+// are left out
+type jsonSpan struct {
+	File    string `json:"file,omitempty"`
+	Line    int64  `json:"line,omitempty"`
+	Col     int64  `json:"col,omitempty"`
+	EndLine int64  `json:"end_line,omitempty"`
+	EndCol  int64  `json:"end_col,omitempty"`
+}
+
+func newJSONSpan(s diag.Span) jsonSpan {
+	return jsonSpan{
+		File:    s.Start.Name,
+		Line:    s.Start.Line,
+		Col:     s.Start.Col,
+		EndLine: s.End.Line,
+		EndCol:  s.End.Col,
+	}
+}
+
+// jsonDiagnostic is a diagnostic as printed by -json
+type jsonDiagnostic struct {
+	Severity string `json:"severity"`
+	jsonSpan
+	Message     string           `json:"message"`
+	Notes       []jsonNote       `json:"notes,omitempty"`
+	Suggestions []jsonSuggestion `json:"suggestions,omitempty"`
+}
+
+type jsonNote struct {
+	jsonSpan
+	Message string `json:"message"`
+}
+
+// jsonSuggestion is a suggestion as printed by -json, replacing the
+// source in its span with text
+type jsonSuggestion struct {
+	jsonSpan
+	Message string `json:"message"`
+	Text    string `json:"text"`
+}
+
+// report writes the errors of err to w, rendered with the lines of srcs
+// they're on, or as JSON objects, one per line, if asJSON is true
+func report(w io.Writer, err error, asJSON bool, srcs map[string]string) {
+	ds := diag.FromError(err)
+	if !asJSON {
+		diag.NewRenderer(w, srcs).Render(ds...)
+		return
+	}
+
+	for _, v := range ds {
+		jd := jsonDiagnostic{
+			Severity: v.Severity.String(),
+			jsonSpan: newJSONSpan(v.Span),
+			Message:  v.Message,
+		}
+
+		for _, n := range v.Notes {
+			jd.Notes = append(jd.Notes, jsonNote{
+				jsonSpan: newJSONSpan(n.Span),
+				Message:  n.Message,
+			})
+		}
+
+		for _, s := range v.Suggestions {
+			jd.Suggestions = append(jd.Suggestions, jsonSuggestion{
+				jsonSpan: newJSONSpan(s.Span),
+				Message:  s.Message,
+				Text:     s.Text,
+			})
+		}
+
+		b, _ := json.Marshal(jd)
+		fmt.Fprintf(w, "%s\n", b)
+	}
+}
+
+// merge returns the errors of the lists as a diag.List sorted by
+// position, leaving out repeated ones
+func merge(lists ...error) error {
+	var ds diag.List
+	seen := map[parser.Error]bool{}
+
+	for _, l := range lists {
+		for _, v := range diag.FromError(l) {
+			key := parser.Error{Fi: v.Span.Start, Msg: v.Message}
+			if seen[key] {
+				continue
+			}
+
+			seen[key] = true
+			ds = append(ds, v)
+		}
+	}
+
+	ds.Sort()
+
+	return ds.Err()
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ear7h/lang/format"
+)
+
+// fmtCmd prints the files formatted, or writes them back with -w
+func fmtCmd(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	ff := newFileFlags("fmt", "[flags] file...", stderr)
+	write := ff.fset.Bool("w", false, "write result to the source files")
+	if !ff.parse(args) {
+		return 2
+	}
+
+	code := 0
+	srcs := map[string]string{}
+	for _, path := range ff.fset.Args() {
+		err := formatFile(ff, path, *write, stdout, srcs)
+		if err != nil {
+			report(stderr, err, ff.json, srcs)
+			code = 1
+		}
+	}
+
+	return code
+}
+
+func formatFile(ff *fileFlags, path string, write bool, stdout io.Writer, srcs map[string]string) error {
+	f, err := ff.parseFile(path, srcs)
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	err = format.Node(&b, f)
+	if err != nil {
+		return err
+	}
+
+	if !write {
+		_, err = io.WriteString(stdout, b.String())
+		return err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, []byte(b.String()), info.Mode().Perm())
+}
@@ -0,0 +1,80 @@
+// Command lang runs lang programs.
+//
+// Usage:
+//
+//	lang <command> [arguments]
+//
+// The commands are:
+//
+//	run	evaluate files, printing their value
+//	check	report the syntax, name and type errors of files
+//	fmt	print files formatted, or rewrite them with -w
+//	ast	print the syntax trees of files
+//	repl	read, evaluate and print lines interactively
+//
+// The commands taking files report their errors with the lines of
+// source they're on, or with -json as JSON objects, one per line, with
+// the severity, file, line, col, end_line, end_col and message fields,
+// and the notes and suggested fixes of the error as objects with the
+// same positions in the notes and suggestions fields. A suggestion's
+// text replaces its span. With -cache dir they keep the trees of the
+// files they parse in dir, so unchanged files aren't parsed again. The
+// exit code is 1 if there were errors and 2 if the arguments were
+// invalid.
+//
+// Run evaluates with the tree walking interpreter, or with -vm compiles
+// to bytecode for the virtual machine.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// command runs a subcommand with its arguments, returning the exit code
+type command func(args []string, stdin io.Reader, stdout, stderr io.Writer) int
+
+var commands = map[string]command{
+	"run":   runCmd,
+	"check": checkCmd,
+	"fmt":   fmtCmd,
+	"ast":   astCmd,
+	"repl":  replCmd,
+}
+
+// run runs the command with the arguments args, returning the exit
+// code
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return 2
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "lang: unknown command %q\n", args[0])
+		usage(stderr)
+		return 2
+	}
+
+	return cmd(args[1:], stdin, stdout, stderr)
+}
+
+func usage(w io.Writer) {
+	names := make([]string, 0, len(commands))
+	for k := range commands {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(w, "usage: lang <command> [arguments]\n\ncommands:\n")
+	for _, v := range names {
+		fmt.Fprintf(w, "\t%s\n", v)
+	}
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ear7h/lang/internal/assert"
+)
+
+func TestRun(t *testing.T) {
+	type tcase struct {
+		args   []string
+		stdin  string
+		code   int
+		stdout string
+		stderr string
+	}
+
+	files := map[string]string{
+		"a.lang":     "let a = 2\n{ let b = 3; return a  *b }\n",
+		"unit.lang":  "let a = 1\n",
+		"bad.lang":   "1 +\n",
+		"types.lang": "let f = fn(x) { return x + 1 }\nf(\"a\") + b\n",
+		"div.lang":   "let a = 0\n1 / a\n",
+		"const.lang": "1 / 0\n1 << 70\n",
+		"typo.lang":  "let count = 1\ncount + cout\n",
+	}
+
+	fn := func(tc tcase) func(t *testing.T) {
+		return func(t *testing.T) {
+			dir := t.TempDir()
+			for k, v := range files {
+				os.WriteFile(filepath.Join(dir, k), []byte(v), 0644)
+			}
+
+			args := make([]string, len(tc.args))
+			for i, v := range tc.args {
+				if _, ok := files[v]; ok || v == "missing.lang" {
+					v = filepath.Join(dir, v)
+				}
+				args[i] = v
+			}
+
+			var stdout, stderr strings.Builder
+			code := run(args, strings.NewReader(tc.stdin), &stdout, &stderr)
+			assert.Eq(t, tc.code, code)
+			assert.Eq(t, tc.stdout, strings.ReplaceAll(stdout.String(), dir+"/", ""))
+			if tc.stderr != "" || code == 0 {
+				assert.Eq(t, tc.stderr, strings.ReplaceAll(stderr.String(), dir+"/", ""))
+			}
+		}
+	}
+
+	tcases := map[string]tcase{
+		"no command": tcase{
+			code: 2,
+		},
+		"unknown command": tcase{
+			args: []string{"nope"},
+			code: 2,
+		},
+		"repl arguments": tcase{
+			args: []string{"repl", "a"},
+			code: 2,
+		},
+		"run": tcase{
+			args:   []string{"run", "a.lang", "unit.lang"},
+			stdout: "6\n",
+		},
+		"run vm": tcase{
+			args:   []string{"run", "-vm", "a.lang", "unit.lang"},
+			stdout: "6\n",
+		},
+		"run error": tcase{
+			args:   []string{"run", "div.lang", "a.lang"},
+			code:   1,
+			stdout: "6\n",
+			stderr: "error: division by zero\n" +
+				" --> div.lang:2:1\n" +
+				"  |\n" +
+				"2 | 1 / a\n" +
+				"  | ^^^^^\n" +
+				"\n",
+		},
+		"run vm error": tcase{
+			args: []string{"run", "-vm", "div.lang"},
+			code: 1,
+			stderr: "error: division by zero\n" +
+				" --> div.lang:2:1\n" +
+				"  |\n" +
+				"2 | 1 / a\n" +
+				"  | ^^^^^\n" +
+				"\n",
+		},
+		"run no files": tcase{
+			args: []string{"run"},
+			code: 2,
+		},
+		"check": tcase{
+			args: []string{"check", "a.lang", "unit.lang"},
+		},
+		"check errors": tcase{
+			args: []string{"check", "types.lang", "bad.lang"},
+			code: 1,
+			stderr: "error: cannot use \"a\" (string) as int value in argument to f\n" +
+				" --> types.lang:2:3\n" +
+				"  |\n" +
+				"1 | let f = fn(x) { return x + 1 }\n" +
+				"  |                        ----- x is int because of x + 1\n" +
+				"2 | f(\"a\") + b\n" +
+				"  |   ^^^\n" +
+				"\n" +
+				"error: undefined: b\n" +
+				" --> types.lang:2:10\n" +
+				"  |\n" +
+				"2 | f(\"a\") + b\n" +
+				"  |          ^\n" +
+				"\n" +
+				"error: syntax error: unexpected newline, " +
+				"expected string or number or \"fn\" or identifier or \"(\"\n" +
+				" --> bad.lang:1:4\n" +
+				"  |\n" +
+				"1 | 1 +\n" +
+				"  |    ^\n" +
+				"\n",
+		},
+		"check constants": tcase{
+			args: []string{"check", "const.lang"},
+			code: 1,
+			stderr: "error: division by zero\n" +
+				" --> const.lang:1:1\n" +
+				"  |\n" +
+				"1 | 1 / 0\n" +
+				"  | ^^^^^\n" +
+				"\n" +
+				"error: constant 1180591620717411303424 overflows int\n" +
+				" --> const.lang:2:1\n" +
+				"  |\n" +
+				"2 | 1 << 70\n" +
+				"  | ^^^^^^^\n" +
+				"\n",
+		},
+		"check suggestion": tcase{
+			args: []string{"check", "typo.lang"},
+			code: 1,
+			stderr: "error: undefined: cout\n" +
+				" --> typo.lang:2:9\n" +
+				"  |\n" +
+				"2 | count + cout\n" +
+				"  |         ^^^^\n" +
+				"  = help: did you mean count?\n" +
+				"  |\n" +
+				"2 | count + count\n" +
+				"  |         ~~~~~\n" +
+				"\n",
+		},
+		"check json": tcase{
+			args: []string{"check", "-json", "types.lang", "typo.lang", "missing.lang"},
+			code: 1,
+			stderr: `{"severity":"error","file":"types.lang","line":2,"col":3,"end_line":2,"end_col":6,` +
+				`"message":"cannot use \"a\" (string) as int value in argument to f",` +
+				`"notes":[{"file":"types.lang","line":1,"col":24,"end_line":1,"end_col":29,` +
+				`"message":"x is int because of x + 1"}]}` + "\n" +
+				`{"severity":"error","file":"types.lang","line":2,"col":10,"end_line":2,"end_col":11,` +
+				`"message":"undefined: b"}` + "\n" +
+				`{"severity":"error","file":"typo.lang","line":2,"col":9,"end_line":2,"end_col":13,` +
+				`"message":"undefined: cout",` +
+				`"suggestions":[{"file":"typo.lang","line":2,"col":9,"end_line":2,"end_col":13,` +
+				`"message":"did you mean count?","text":"count"}]}` + "\n" +
+				`{"severity":"error","message":"open missing.lang: no such file or directory"}` + "\n",
+		},
+		"fmt": tcase{
+			args:   []string{"fmt", "a.lang"},
+			stdout: "let a = 2\n{\n\tlet b = 3\n\treturn a * b\n}\n",
+		},
+		"fmt error": tcase{
+			args:   []string{"fmt", "-json", "bad.lang"},
+			code:   1,
+			stderr: `{"severity":"error","file":"bad.lang","line":1,"col":4,"message":"syntax error: unexpected newline, expected string or number or \"fn\" or identifier or \"(\""}` + "\n",
+		},
+		"ast": tcase{
+			args: []string{"ast", "unit.lang"},
+			stdout: "*ast.File {\n" +
+				".  Fi: unit.lang:1:1\n" +
+				".  EndFi: unit.lang:2:1\n" +
+				".  Name: \"unit.lang\"\n" +
+				".  Stmts: []interface {} (len = 1) {\n" +
+				".  .  0: *ast.LetStmt {\n" +
+				".  .  .  Fi: unit.lang:1:1\n" +
+				".  .  .  EndFi: unit.lang:1:10\n" +
+				".  .  .  Name: *ast.Ident {\n" +
+				".  .  .  .  Fi: unit.lang:1:5\n" +
+				".  .  .  .  EndFi: unit.lang:1:6\n" +
+				".  .  .  .  IsExported: false\n" +
+				".  .  .  .  Name: \"a\"\n" +
+				".  .  .  }\n" +
+				".  .  .  X: *ast.NumberLiteral {\n" +
+				".  .  .  .  Fi: unit.lang:1:9\n" +
+				".  .  .  .  EndFi: unit.lang:1:10\n" +
+				".  .  .  .  Orig: \"1\"\n" +
+				".  .  .  .  Parsed: 1\n" +
+				".  .  .  }\n" +
+				".  .  }\n" +
+				".  }\n" +
+				"}\n",
+		},
+		"ast json": tcase{
+			args: []string{"ast", "-json", "bad.lang"},
+			code: 1,
+		},
+		"repl": tcase{
+			args:   []string{"repl"},
+			stdin:  "1 + 2\n\"a\"\n",
+			stdout: "> 3\n> \"a\"\n> \n",
+		},
+		"repl bindings": tcase{
+			args:   []string{"repl"},
+			stdin:  "let a = 2\nlet b = a * a\n\nb + a\n",
+			stdout: "> > > > 6\n> \n",
+		},
+		"repl multi-line": tcase{
+			args:   []string{"repl"},
+			stdin:  "let f = fn(a) {\n\treturn (a +\n\t\t1)\n}\nf(1)\n",
+			stdout: "> ... ... ... > 2\n> \n",
+		},
+		"repl unit": tcase{
+			args:   []string{"repl"},
+			stdin:  "let f = fn() {}\nf()\n",
+			stdout: "> > ()\n> \n",
+		},
+		"repl syntax error": tcase{
+			args:   []string{"repl"},
+			stdin:  "1 +\n1\n",
+			stdout: "> > 1\n> \n",
+			stderr: "error: syntax error: unexpected end of file, " +
+				"expected string or number or \"fn\" or identifier or \"(\"\n" +
+				" --> in1:1:4\n" +
+				"  |\n" +
+				"1 | 1 +\n" +
+				"  |    ^\n" +
+				"\n",
+		},
+		"repl runtime error": tcase{
+			args: []string{"repl"},
+			stdin: "let f = fn(a) {\n\treturn a / 0\n}\n" +
+				"let b = 1\nf(b)\nb\n",
+			stdout: "> ... ... > > > 1\n> \n",
+			stderr: "error: division by zero\n" +
+				" --> in1:2:9\n" +
+				"  |\n" +
+				"2 | \treturn a / 0\n" +
+				"  | \t       ^^^^^\n" +
+				"\n",
+		},
+		"repl unbalanced at end": tcase{
+			args:   []string{"repl"},
+			stdin:  "(1 +\n2",
+			stdout: "> ... ... \n",
+			stderr: "error: syntax error: unexpected newline, expected \")\"\n" +
+				" --> in1:1:5\n" +
+				"  |\n" +
+				"1 | (1 +\n" +
+				"  |     ^\n" +
+				"\n",
+		},
+	}
+
+	for k, v := range tcases {
+		t.Run(k, fn(v))
+	}
+}
+
+func TestDepth(t *testing.T) {
+	assert.Eq(t, 0, depth("f(a) { b }"))
+	assert.Eq(t, 2, depth("fn(a) { (b"))
+	assert.Eq(t, 0, depth(`"(" + "{"`))
+	assert.Eq(t, -1, depth(")"))
+}
+
+func TestCache(t *testing.T) {
+	dir, cacheDir := t.TempDir(), t.TempDir()
+	path := filepath.Join(dir, "a.lang")
+	os.WriteFile(path, []byte("let a = 2\na * 3\n"), 0644)
+
+	for i := 0; i < 2; i++ {
+		var stdout, stderr strings.Builder
+		code := run([]string{"run", "-cache", cacheDir, path}, nil, &stdout, &stderr)
+		assert.Eq(t, 0, code)
+		assert.Eq(t, "6\n", stdout.String())
+		assert.Eq(t, "", stderr.String())
+	}
+
+	entries, err := os.ReadDir(cacheDir)
+	assert.Eq(t, nil, err)
+	assert.Eq(t, 1, len(entries))
+}
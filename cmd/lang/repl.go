@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ear7h/lang/ast"
+	"github.com/ear7h/lang/ast/token"
+	"github.com/ear7h/lang/diag"
+	"github.com/ear7h/lang/eval"
+)
+
+const (
+	prompt     = "> "
+	contPrompt = "... "
+)
+
+// replCmd reads statements and expressions from stdin, evaluating them
+// and printing the values of expressions. The bindings of each input
+// are kept for the next ones, and an input continues on the next line
+// while its parentheses or braces are unbalanced. Errors are rendered
+// with the lines of the inputs they're in.
+func replCmd(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fset := flag.NewFlagSet("lang repl", flag.ContinueOnError)
+	fset.SetOutput(stderr)
+	fset.Usage = func() {
+		fmt.Fprintf(stderr, "usage: lang repl\n")
+		fset.PrintDefaults()
+	}
+
+	if err := fset.Parse(args); err != nil {
+		return 2
+	}
+
+	if fset.NArg() > 0 {
+		fset.Usage()
+		return 2
+	}
+
+	r := &replState{
+		env:    eval.NewEnv(eval.Universe),
+		srcs:   map[string]string{},
+		stdout: stdout,
+		stderr: stderr,
+	}
+
+	sc := bufio.NewScanner(stdin)
+	var input strings.Builder
+
+	for {
+		if input.Len() == 0 {
+			io.WriteString(stdout, prompt)
+		} else {
+			io.WriteString(stdout, contPrompt)
+		}
+
+		if !sc.Scan() {
+			// evaluate the rest of the input, to report what's
+			// missing
+			r.eval(input.String())
+			io.WriteString(stdout, "\n")
+
+			if err := sc.Err(); err != nil {
+				fmt.Fprintln(stderr, err)
+				return 1
+			}
+
+			return 0
+		}
+
+		input.WriteString(sc.Text())
+		input.WriteString("\n")
+
+		if depth(input.String()) > 0 {
+			continue
+		}
+
+		r.eval(input.String())
+		input.Reset()
+	}
+}
+
+type replState struct {
+	env *eval.Env
+	// srcs has the source of the inputs, by their file name
+	srcs           map[string]string
+	stdout, stderr io.Writer
+}
+
+// eval parses and evaluates the input src, printing its value if it
+// ends with an expression
+func (r *replState) eval(src string) {
+	if strings.TrimSpace(src) == "" {
+		return
+	}
+
+	// errors at the end of the input point to its last line
+	src = strings.TrimSuffix(src, "\n")
+
+	// inputs are numbered files, so errors in functions defined by
+	// earlier ones point to the right source
+	name := fmt.Sprintf("in%d", len(r.srcs)+1)
+	r.srcs[name] = src
+
+	f, err := ast.ParseFile(name, src)
+	if err != nil {
+		r.printError(err)
+		return
+	}
+
+	v, err := eval.Eval(f, r.env)
+	if err != nil {
+		r.printError(err)
+		return
+	}
+
+	if len(f.Stmts) == 0 {
+		return
+	}
+
+	if _, ok := f.Stmts[len(f.Stmts)-1].(*ast.ExprStmt); ok {
+		fmt.Fprintln(r.stdout, eval.String(v))
+	}
+}
+
+// printError renders the errors with the inputs they're in
+func (r *replState) printError(err error) {
+	diag.NewRenderer(r.stderr, r.srcs).RenderError(err)
+}
+
+// depth returns how many more parentheses and braces src opens than it
+// closes
+func depth(src string) int {
+	// scanning errors are reported when the input is parsed
+	toks, _ := token.Scan(src, "")
+
+	n := 0
+	for _, v := range toks {
+		if v.Kind != token.Operator {
+			continue
+		}
+
+		switch v.Value {
+		case "(", "{":
+			n++
+		case ")", "}":
+			n--
+		}
+	}
+
+	return n
+}
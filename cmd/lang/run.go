@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/ear7h/lang/compile"
+	"github.com/ear7h/lang/eval"
+	"github.com/ear7h/lang/vm"
+)
+
+// runCmd runs the files, each in its own environment, printing the
+// value of the program unless it's unit
+func runCmd(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	ff := newFileFlags("run", "[flags] file...", stderr)
+	useVM := ff.fset.Bool("vm", false, "run the bytecode on the virtual machine")
+	if !ff.parse(args) {
+		return 2
+	}
+
+	code := 0
+	srcs := map[string]string{}
+	for _, path := range ff.fset.Args() {
+		f, err := ff.parseFile(path, srcs)
+		if err != nil {
+			report(stderr, err, ff.json, srcs)
+			code = 1
+			continue
+		}
+
+		var v interface{}
+		if *useVM {
+			var fn *compile.Func
+			fn, err = compile.Compile(f)
+			if err == nil {
+				v, err = vm.Run(fn, nil)
+			}
+		} else {
+			v, err = eval.Eval(f, nil)
+		}
+
+		if err != nil {
+			report(stderr, err, ff.json, srcs)
+			code = 1
+			continue
+		}
+
+		if v != nil {
+			fmt.Fprintln(stdout, eval.String(v))
+		}
+	}
+
+	return code
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines around each hunk
+const diffContext = 3
+
+type diffOp struct {
+	kind byte // ' ', '-' or '+'
+	line string
+	a, b int // the lines of a and b before the op
+}
+
+// diff returns the unified diff from a to b, the original and formatted
+// source of the file name
+func diff(name, a, b string) string {
+	ops := editScript(lines(a), lines(b))
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s.orig\n+++ %s\n", name, name)
+
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+
+		// extend the hunk while the next change is close enough for
+		// the contexts to touch
+		start := max(i-diffContext, 0)
+		end, equal := i, 0
+		for j := i; j < len(ops) && equal <= 2*diffContext; j++ {
+			if ops[j].kind == ' ' {
+				equal++
+				continue
+			}
+
+			end, equal = j, 0
+		}
+		end = min(end+diffContext+1, len(ops))
+
+		writeHunk(&out, ops[start:end])
+		i = end
+	}
+
+	return out.String()
+}
+
+func writeHunk(out *strings.Builder, ops []diffOp) {
+	na, nb := 0, 0
+	for _, v := range ops {
+		if v.kind != '+' {
+			na++
+		}
+		if v.kind != '-' {
+			nb++
+		}
+	}
+
+	fmt.Fprintf(out, "@@ -%s +%s @@\n",
+		hunkRange(ops[0].a, na), hunkRange(ops[0].b, nb))
+
+	for _, v := range ops {
+		out.WriteByte(v.kind)
+		out.WriteString(v.line)
+		if !strings.HasSuffix(v.line, "\n") {
+			out.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+func hunkRange(start, n int) string {
+	if n == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+
+	return fmt.Sprintf("%d,%d", start+1, n)
+}
+
+// lines splits s after each newline
+func lines(s string) []string {
+	var ret []string
+	for s != "" {
+		i := strings.IndexByte(s, '\n') + 1
+		if i == 0 {
+			i = len(s)
+		}
+
+		ret = append(ret, s[:i])
+		s = s[i:]
+	}
+
+	return ret
+}
+
+// editScript returns the ops turning a into b, from their longest
+// common subsequence
+func editScript(a, b []string) []diffOp {
+	// lcs[i][j] is the length of the longest common subsequence of
+	// a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var ret []diffOp
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			ret = append(ret, diffOp{' ', a[i], i, j})
+			i++
+			j++
+		case j == len(b) || i < len(a) && lcs[i+1][j] >= lcs[i][j+1]:
+			ret = append(ret, diffOp{'-', a[i], i, j})
+			i++
+		default:
+			ret = append(ret, diffOp{'+', b[j], i, j})
+			j++
+		}
+	}
+
+	return ret
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
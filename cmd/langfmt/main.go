@@ -0,0 +1,187 @@
+// Command langfmt formats lang source files.
+//
+// Usage:
+//
+//	langfmt [flags] [path ...]
+//
+// Without paths it formats the standard input to the standard output.
+// Directories are walked for .lang files. By default the formatted
+// files are written to the standard output, the flags are:
+//
+//	-l	list the files whose formatting differs
+//	-w	write the formatted source back to the files
+//	-d	print the diffs of the formatting
+//	-cache dir
+//		keep the parsed files in dir, so unchanged files aren't
+//		parsed again
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ear7h/lang/ast"
+	"github.com/ear7h/lang/ast/cache"
+	"github.com/ear7h/lang/format"
+)
+
+// Ext is the extension of source files
+const Ext = ".lang"
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+type options struct {
+	list, write, diff bool
+
+	// parse parses the source of a file
+	parse func(name, src string) (*ast.File, error)
+}
+
+// run runs the command with the arguments args, returning the exit
+// code
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	var opts options
+
+	fset := flag.NewFlagSet("langfmt", flag.ContinueOnError)
+	fset.SetOutput(stderr)
+	fset.BoolVar(&opts.list, "l", false, "list files whose formatting differs")
+	fset.BoolVar(&opts.write, "w", false, "write result to the source files")
+	fset.BoolVar(&opts.diff, "d", false, "display diffs instead of rewriting files")
+	cacheDir := fset.String("cache", "", "cache parsed files in `dir`")
+	fset.Usage = func() {
+		fmt.Fprintf(stderr, "usage: langfmt [flags] [path ...]\n")
+		fset.PrintDefaults()
+	}
+
+	if err := fset.Parse(args); err != nil {
+		return 2
+	}
+
+	opts.parse = ast.ParseFile
+	if *cacheDir != "" {
+		c, err := cache.New(*cacheDir)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+
+		opts.parse = c.ParseFile
+	}
+
+	if fset.NArg() == 0 {
+		if opts.write {
+			fmt.Fprintln(stderr, "langfmt: cannot use -w with standard input")
+			return 2
+		}
+
+		src, err := io.ReadAll(stdin)
+		if err == nil {
+			err = process(opts, "<standard input>", src, stdout)
+		}
+
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+
+		return 0
+	}
+
+	code := 0
+	for _, v := range fset.Args() {
+		err := filepath.WalkDir(v, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			// the paths given are formatted whatever their name
+			if d.IsDir() || path != v && filepath.Ext(path) != Ext {
+				return nil
+			}
+
+			err = processFile(opts, path, stdout)
+			if err != nil {
+				fmt.Fprintln(stderr, err)
+				code = 1
+			}
+
+			return nil
+		})
+
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			code = 1
+		}
+	}
+
+	return code
+}
+
+func processFile(opts options, path string, stdout io.Writer) error {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	return process(opts, path, src, stdout)
+}
+
+// process formats the source of the file name according to opts
+func process(opts options, name string, src []byte, stdout io.Writer) error {
+	f, err := opts.parse(name, string(src))
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	err = format.Node(&b, f)
+	if err != nil {
+		return err
+	}
+
+	out := b.String()
+
+	if out == string(src) {
+		if !opts.list && !opts.write && !opts.diff {
+			_, err = io.WriteString(stdout, out)
+		}
+
+		return err
+	}
+
+	if opts.list {
+		fmt.Fprintln(stdout, name)
+	}
+
+	if opts.write {
+		info, err := os.Stat(name)
+		if err != nil {
+			return err
+		}
+
+		err = os.WriteFile(name, []byte(out), info.Mode().Perm())
+		if err != nil {
+			return err
+		}
+	}
+
+	if opts.diff {
+		_, err = io.WriteString(stdout, diff(name, string(src), out))
+		if err != nil {
+			return err
+		}
+	}
+
+	if !opts.list && !opts.write && !opts.diff {
+		_, err = io.WriteString(stdout, out)
+	}
+
+	return err
+}
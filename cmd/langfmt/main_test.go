@@ -0,0 +1,151 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ear7h/lang/internal/assert"
+)
+
+func TestRun(t *testing.T) {
+	type tcase struct {
+		args  []string
+		stdin string
+		code  int
+		out   string
+		files map[string]string // the files after running
+	}
+
+	files := map[string]string{
+		"a.lang":   "a+b\n",
+		"b.lang":   "c\n",
+		"d/e.lang": "(e)\n",
+		"d/f.txt":  "f+f\n",
+		"bad.lang": "1 +\n",
+	}
+
+	fn := func(tc tcase) func(t *testing.T) {
+		return func(t *testing.T) {
+			dir := t.TempDir()
+			for k, v := range files {
+				path := filepath.Join(dir, k)
+				os.MkdirAll(filepath.Dir(path), 0755)
+				os.WriteFile(path, []byte(v), 0644)
+			}
+
+			args := make([]string, len(tc.args))
+			for i, v := range tc.args {
+				if _, ok := files[v]; ok || v == "d" || v == "cache" {
+					v = filepath.Join(dir, v)
+				}
+				args[i] = v
+			}
+
+			var stdout, stderr strings.Builder
+			code := run(args, strings.NewReader(tc.stdin), &stdout, &stderr)
+			assert.Eq(t, tc.code, code)
+			assert.Eq(t, tc.out, strings.ReplaceAll(stdout.String(), dir+"/", ""))
+
+			for k, v := range tc.files {
+				b, _ := os.ReadFile(filepath.Join(dir, k))
+				assert.Eq(t, v, string(b))
+			}
+		}
+	}
+
+	tcases := map[string]tcase{
+		"stdin": tcase{
+			stdin: "x  *y",
+			out:   "x * y\n",
+		},
+		"stdout": tcase{
+			args: []string{"a.lang", "b.lang"},
+			out:  "a + b\nc\n",
+		},
+		"list": tcase{
+			args: []string{"-l", "a.lang", "b.lang", "d"},
+			out:  "a.lang\nd/e.lang\n",
+		},
+		"write": tcase{
+			args: []string{"-w", "a.lang", "d"},
+			files: map[string]string{
+				"a.lang":   "a + b\n",
+				"d/e.lang": "e\n",
+				"d/f.txt":  "f+f\n",
+			},
+		},
+		"diff": tcase{
+			args: []string{"-d", "a.lang", "b.lang"},
+			out: "--- a.lang.orig\n+++ a.lang\n" +
+				"@@ -1,1 +1,1 @@\n" +
+				"-a+b\n" +
+				"+a + b\n",
+		},
+		"cache": tcase{
+			args: []string{"-cache", "cache", "-l", "a.lang", "b.lang", "a.lang"},
+			out:  "a.lang\na.lang\n",
+		},
+		"error": tcase{
+			args: []string{"-l", "bad.lang", "a.lang"},
+			code: 1,
+			out:  "a.lang\n",
+		},
+	}
+
+	for k, v := range tcases {
+		t.Run(k, fn(v))
+	}
+}
+
+func TestDiff(t *testing.T) {
+	type tcase struct {
+		a, b string
+		out  string
+	}
+
+	fn := func(tc tcase) func(t *testing.T) {
+		return func(t *testing.T) {
+			assert.Eq(t, "--- x.orig\n+++ x\n"+tc.out, diff("x", tc.a, tc.b))
+		}
+	}
+
+	tcases := map[string]tcase{
+		"same": tcase{
+			a: "a\nb\n",
+			b: "a\nb\n",
+		},
+		"newline": tcase{
+			a: "a",
+			b: "a\n",
+			out: "@@ -1,1 +1,1 @@\n" +
+				"-a\n\\ No newline at end of file\n" +
+				"+a\n",
+		},
+		"empty": tcase{
+			a: "\n",
+			b: "",
+			out: "@@ -1,1 +0,0 @@\n" +
+				"-\n",
+		},
+		"hunks": tcase{
+			a: "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n",
+			b: "0\n2\n3\n4\n5\n6\n7\n8\n9\n10\n12\n",
+			out: "@@ -1,4 +1,4 @@\n" +
+				"-1\n+0\n 2\n 3\n 4\n" +
+				"@@ -8,4 +8,4 @@\n" +
+				" 8\n 9\n 10\n-11\n+12\n",
+		},
+		"close hunks": tcase{
+			a: "1\n2\n3\n4\n5\n6\n7\n8\n",
+			b: "0\n2\n3\n4\n5\n6\n7\n9\n",
+			out: "@@ -1,8 +1,8 @@\n" +
+				"-1\n+0\n 2\n 3\n 4\n 5\n 6\n 7\n-8\n+9\n",
+		},
+	}
+
+	for k, v := range tcases {
+		t.Run(k, fn(v))
+	}
+}
@@ -0,0 +1,437 @@
+// Package compile lowers syntax trees to bytecode for the vm package.
+//
+// Each function literal, and the top level, is compiled to a Func: a
+// stack machine program with its own constants pool and local variable
+// slots. Names are resolved lexically at compile time: to a local slot,
+// to a variable captured by value when the closure is made, or to a
+// global, looked up by name when the code runs. The variables of the top
+// level scope are globals, other variables are locals.
+package compile
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/ear7h/lang/ast"
+	"github.com/ear7h/lang/ast/parser"
+	"github.com/ear7h/lang/constant"
+	"github.com/ear7h/lang/diag"
+)
+
+// MaxOperand is the largest operand of an instruction, it limits the
+// number of constants, locals, captures, functions and arguments of a
+// function and the size of its code
+const MaxOperand = 1<<16 - 1
+
+// Func is a compiled function literal, or the top level of a file
+type Func struct {
+	// Name is the name of the let statement binding the function, if
+	// any, or "main" for the top level
+	Name   string
+	Params int
+	// Locals is the number of local slots, including the parameters
+	Locals int
+	// Self is the local slot holding the function itself, for
+	// recursion, or -1
+	Self     int
+	Code     []byte
+	Consts   []interface{}
+	Funcs    []*Func
+	Captures []Capture
+	// Pos has the positions of the instructions, in increasing order
+	// of PC
+	Pos []Pos
+}
+
+// Capture is where a closure gets a captured variable from, when it is
+// made
+type Capture struct {
+	// Local reports whether Index is a local slot of the enclosing
+	// function, rather than one of its captured variables
+	Local bool
+	Index int
+}
+
+// Pos is the position of the source of the instruction at PC, from Fi
+// to just before End
+type Pos struct {
+	PC      int
+	Fi, End parser.FileInfo
+}
+
+// Span returns the span of the source of the instruction at pc
+func (fn *Func) Span(pc int) diag.Span {
+	i := sort.Search(len(fn.Pos), func(i int) bool {
+		return fn.Pos[i].PC > pc
+	})
+	if i == 0 {
+		return diag.Span{}
+	}
+
+	return diag.Span{Start: fn.Pos[i-1].Fi, End: fn.Pos[i-1].End}
+}
+
+// Operand returns the operand of the instruction at pc
+func (fn *Func) Operand(pc int) int {
+	return int(fn.Code[pc+1])<<8 | int(fn.Code[pc+2])
+}
+
+// Compile compiles n, a file, statement or expression, to the top level
+// function of a program. Like eval.Eval, running it gives the value of
+// the last statement or of the top level return statement which stopped
+// it. The constant expressions are folded first, on a copy of n, see
+// constant.Fold. The error is a diag.List.
+func Compile(n ast.Node) (*Func, error) {
+	n, err := constant.Fold(n)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &compiler{}
+	c.fn = c.newFunc("main", nil)
+
+	switch n := n.(type) {
+	case *ast.File:
+		c.stmts(n.Stmts, true)
+	case *ast.ExprStmt, *ast.ReturnStmt, *ast.LetStmt, *ast.BlockStmt,
+		*ast.BadStmt:
+		c.stmt(n, true)
+	default:
+		c.expr(n)
+	}
+
+	c.emit(OpReturn, 0, nil)
+
+	if len(c.errs) > 0 {
+		return nil, c.errs
+	}
+
+	return c.fn.fn, nil
+}
+
+type compiler struct {
+	fn   *funcState
+	errs diag.List
+}
+
+// funcState is the function being compiled
+type funcState struct {
+	parent *funcState
+	fn     *Func
+	// scope is the innermost scope, nil at the top level, where
+	// variables are globals
+	scope    *scope
+	consts   map[interface{}]int
+	captures map[Capture]int
+	// pos is the position of the last instruction
+	pos Pos
+}
+
+type scope struct {
+	parent *scope
+	names  map[string]int
+}
+
+func (c *compiler) newFunc(name string, parent *funcState) *funcState {
+	return &funcState{
+		parent:   parent,
+		fn:       &Func{Name: name, Self: -1},
+		consts:   map[interface{}]int{},
+		captures: map[Capture]int{},
+	}
+}
+
+func (c *compiler) errorf(n ast.Node, format string, args ...interface{}) {
+	c.errs = append(c.errs, diag.Errorf(n, format, args...))
+}
+
+// operand checks that a fits in an operand, reporting an error at n
+// otherwise
+func (c *compiler) operand(n ast.Node, a int, what string) int {
+	if a > MaxOperand {
+		c.errorf(n, "too many %s in function", what)
+		return 0
+	}
+
+	return a
+}
+
+// emit appends an instruction to the function, positioned at n if it's
+// not nil, and returns its pc
+func (c *compiler) emit(op Op, a int, n ast.Node) int {
+	fs := c.fn
+	pc := len(fs.fn.Code)
+
+	if n != nil && (n.FileInfo() != fs.pos.Fi || n.End() != fs.pos.End) {
+		fs.pos = Pos{PC: pc, Fi: n.FileInfo(), End: n.End()}
+		fs.fn.Pos = append(fs.fn.Pos, fs.pos)
+	}
+
+	fs.fn.Code = append(fs.fn.Code, byte(op))
+	if op.Size() > 1 {
+		fs.fn.Code = append(fs.fn.Code, byte(a>>8), byte(a))
+	}
+
+	return pc
+}
+
+// patch sets the operand of the jump at pc to the current end of the
+// code
+func (c *compiler) patch(n ast.Node, pc int) {
+	code := c.fn.fn.Code
+	a := c.operand(n, len(code), "instructions")
+	code[pc+1], code[pc+2] = byte(a>>8), byte(a)
+}
+
+func (c *compiler) constant(n ast.Node, v interface{}) int {
+	fs := c.fn
+	if i, ok := fs.consts[v]; ok {
+		return i
+	}
+
+	i := c.operand(n, len(fs.fn.Consts), "constants")
+	fs.fn.Consts = append(fs.fn.Consts, v)
+	fs.consts[v] = i
+
+	return i
+}
+
+// declare defines name in the current scope and returns its local slot,
+// or -1 if it's a global
+func (c *compiler) declare(n ast.Node, name string) int {
+	fs := c.fn
+	if fs.scope == nil {
+		return -1
+	}
+
+	slot := c.operand(n, fs.fn.Locals, "local variables")
+	fs.fn.Locals++
+	fs.scope.names[name] = slot
+
+	return slot
+}
+
+func (c *compiler) openScope() {
+	c.fn.scope = &scope{
+		parent: c.fn.scope,
+		names:  map[string]int{},
+	}
+}
+
+func (c *compiler) closeScope() {
+	c.fn.scope = c.fn.scope.parent
+}
+
+// lookup returns the local slot or the captured variable of fs which
+// name refers to, ok is false if it's a global
+func (c *compiler) lookup(n ast.Node, fs *funcState, name string) (slot int, local, ok bool) {
+	for s := fs.scope; s != nil; s = s.parent {
+		if slot, ok := s.names[name]; ok {
+			return slot, true, true
+		}
+	}
+
+	if fs.parent == nil {
+		return 0, false, false
+	}
+
+	slot, local, ok = c.lookup(n, fs.parent, name)
+	if !ok {
+		return 0, false, false
+	}
+
+	capture := Capture{Local: local, Index: slot}
+	i, ok := fs.captures[capture]
+	if !ok {
+		i = c.operand(n, len(fs.fn.Captures), "captured variables")
+		fs.fn.Captures = append(fs.fn.Captures, capture)
+		fs.captures[capture] = i
+	}
+
+	return i, false, true
+}
+
+// stmts compiles the statements, leaving the value of the last one on
+// the stack if keep is true
+func (c *compiler) stmts(stmts []interface{}, keep bool) {
+	if len(stmts) == 0 && keep {
+		c.emit(OpUnit, 0, nil)
+	}
+
+	for i, v := range stmts {
+		c.stmt(v, keep && i == len(stmts)-1)
+	}
+}
+
+// stmt compiles the statement n, leaving its value on the stack if keep
+// is true
+func (c *compiler) stmt(n interface{}, keep bool) {
+	switch n := n.(type) {
+	case *ast.ExprStmt:
+		c.expr(n.X)
+		if !keep {
+			c.emit(OpPop, 0, nil)
+		}
+	case *ast.ReturnStmt:
+		if n.X == nil {
+			c.emit(OpUnit, 0, nil)
+		} else {
+			c.expr(n.X)
+		}
+
+		c.emit(OpReturn, 0, nil)
+
+		if keep {
+			// unreachable, but keeps the stack balanced
+			c.emit(OpUnit, 0, nil)
+		}
+	case *ast.LetStmt:
+		c.let(n)
+		if keep {
+			c.emit(OpUnit, 0, nil)
+		}
+	case *ast.BlockStmt:
+		c.openScope()
+		c.stmts(n.Stmts, keep)
+		c.closeScope()
+	case ast.Node:
+		c.errorf(n, "bad statement")
+	default:
+		panic(fmt.Sprintf("compile: unknown statement %T", n))
+	}
+}
+
+func (c *compiler) let(n *ast.LetStmt) {
+	name := n.Name.Name
+
+	lit, ok := n.X.(*ast.FuncLit)
+	if ok && c.fn.scope != nil {
+		// a local function refers to itself through its Self slot
+		c.funcLit(lit, name, true)
+	} else if ok {
+		// and a global one through the global
+		c.funcLit(lit, name, false)
+	} else {
+		c.expr(n.X)
+	}
+
+	slot := c.declare(n.Name, name)
+	if slot < 0 {
+		c.emit(OpSetGlobal, c.constant(n.Name, name), n.Name)
+		return
+	}
+
+	c.emit(OpSetLocal, slot, n.Name)
+}
+
+func (c *compiler) expr(n interface{}) {
+	switch n := n.(type) {
+	case *ast.NumberLiteral:
+		c.emit(OpConst, c.constant(n, n.Parsed), n)
+	case *ast.StringLiteral:
+		c.emit(OpConst, c.constant(n, n.Parsed), n)
+	case *ast.Ident:
+		c.ident(n)
+	case *ast.UnaryExpr:
+		c.expr(n.Operand)
+		c.emit(unaryOps[n.Op], 0, n)
+	case *ast.BinaryExpr:
+		c.binary(n)
+	case *ast.ObjExpr:
+		c.obj(n)
+	case *ast.FuncLit:
+		c.funcLit(n, "", false)
+	case ast.Node:
+		c.errorf(n, "bad expression")
+	default:
+		panic(fmt.Sprintf("compile: unknown expression %T", n))
+	}
+}
+
+func (c *compiler) ident(n *ast.Ident) {
+	slot, local, ok := c.lookup(n, c.fn, n.Name)
+	switch {
+	case !ok:
+		c.emit(OpGlobal, c.constant(n, n.Name), n)
+	case local:
+		c.emit(OpLocal, slot, n)
+	default:
+		c.emit(OpCapture, slot, n)
+	}
+}
+
+func (c *compiler) binary(n *ast.BinaryExpr) {
+	c.expr(n.Left)
+
+	switch n.Op {
+	case ast.BinaryBoolAnd, ast.BinaryBoolOr:
+		jump, check := OpJumpAnd, OpBoolAnd
+		if n.Op == ast.BinaryBoolOr {
+			jump, check = OpJumpOr, OpBoolOr
+		}
+
+		pc := c.emit(jump, 0, n)
+		c.expr(n.Right)
+		c.emit(check, 0, n)
+		c.patch(n, pc)
+	default:
+		c.expr(n.Right)
+		c.emit(binaryOps[n.Op], 0, n)
+	}
+}
+
+func (c *compiler) obj(n *ast.ObjExpr) {
+	c.expr(n.Object)
+
+	for v := n; v != nil; v, _ = v.Right.(*ast.ObjExpr) {
+		if v.Op == ast.ObjCall {
+			for _, arg := range v.Args {
+				c.expr(arg)
+			}
+
+			c.emit(OpCall, c.operand(v, len(v.Args), "arguments"), v)
+			continue
+		}
+
+		name := v.Arg.(*ast.Ident).Name
+		c.emit(OpField, c.constant(v, name), v)
+	}
+}
+
+// funcLit compiles n to a new function and emits the instruction making
+// its closure, if self is true name refers to the function in its body
+func (c *compiler) funcLit(n *ast.FuncLit, name string, self bool) {
+	parent := c.fn
+	if name == "" {
+		name = "fn"
+	}
+
+	c.fn = c.newFunc(name, parent)
+	c.fn.fn.Params = len(n.Params)
+	c.openScope()
+
+	for _, v := range n.Params {
+		c.declare(v, v.Name)
+	}
+
+	if self {
+		c.fn.fn.Self = c.fn.fn.Locals
+		c.fn.fn.Locals++
+		if _, ok := c.fn.scope.names[name]; !ok {
+			c.fn.scope.names[name] = c.fn.fn.Self
+		}
+	}
+
+	c.stmts(n.Body.Stmts, false)
+
+	// functions without a return statement return unit
+	c.emit(OpUnit, 0, nil)
+	c.emit(OpReturn, 0, nil)
+
+	fn := c.fn.fn
+	c.fn = parent
+
+	i := c.operand(n, len(parent.fn.Funcs), "functions")
+	parent.fn.Funcs = append(parent.fn.Funcs, fn)
+	c.emit(OpClosure, i, n)
+}
@@ -0,0 +1,200 @@
+package compile_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ear7h/lang/ast"
+	"github.com/ear7h/lang/ast/parser"
+	"github.com/ear7h/lang/compile"
+	"github.com/ear7h/lang/diag"
+	"github.com/ear7h/lang/internal/assert"
+)
+
+func TestCompile(t *testing.T) {
+	type tcase struct {
+		str string
+		out string
+	}
+
+	fn := func(tc tcase) func(t *testing.T) {
+		return func(t *testing.T) {
+			f, err := ast.ParseFile("test", tc.str)
+			assert.Eq(t, nil, err)
+
+			fn, err := compile.Compile(f)
+			assert.Eq(t, nil, err)
+
+			var b strings.Builder
+			err = compile.Disassemble(&b, fn)
+			assert.Eq(t, nil, err)
+			assert.Eq(t, tc.out, b.String())
+		}
+	}
+
+	tcases := map[string]tcase{
+		"empty": tcase{
+			str: "",
+			out: "0 main: params 0, locals 0\n" +
+				"\t0000        unit\n" +
+				"\t0001        return\n",
+		},
+		"constants": tcase{
+			str: `f(1, 2, 1); "a"`,
+			out: "0 main: params 0, locals 0\n" +
+				"\t0000 1:1    global 0 (\"f\")\n" +
+				"\t0003 1:3    const 1 (1)\n" +
+				"\t0006 1:6    const 2 (2)\n" +
+				"\t0009 1:9    const 1 (1)\n" +
+				"\t0012 1:1    call 3\n" +
+				"\t0015        pop\n" +
+				"\t0016 1:13   const 3 (\"a\")\n" +
+				"\t0019        return\n",
+		},
+		"folded": tcase{
+			str: `1 + 2 * 1; "a" + "b"`,
+			out: "0 main: params 0, locals 0\n" +
+				"\t0000 1:1    const 0 (3)\n" +
+				"\t0003        pop\n" +
+				"\t0004 1:12   const 1 (\"ab\")\n" +
+				"\t0007        return\n",
+		},
+		"globals": tcase{
+			str: "let a = -b\na.c",
+			out: "0 main: params 0, locals 0\n" +
+				"\t0000 1:10   global 0 (\"b\")\n" +
+				"\t0003 1:9    neg\n" +
+				"\t0004 1:5    setglobal 1 (\"a\")\n" +
+				"\t0007 2:1    global 1 (\"a\")\n" +
+				"\t0010        field 2 (\"c\")\n" +
+				"\t0013        return\n",
+		},
+		"short circuit": tcase{
+			str: "a && b || c",
+			out: "0 main: params 0, locals 0\n" +
+				"\t0000 1:1    global 0 (\"a\")\n" +
+				"\t0003        jumpand 10\n" +
+				"\t0006 1:6    global 1 (\"b\")\n" +
+				"\t0009 1:1    booland\n" +
+				"\t0010        jumpor 17\n" +
+				"\t0013 1:11   global 2 (\"c\")\n" +
+				"\t0016 1:1    boolor\n" +
+				"\t0017        return\n",
+		},
+		"locals": tcase{
+			str: "{ let a = 1; { let a = a; a } }",
+			out: "0 main: params 0, locals 2\n" +
+				"\t0000 1:11   const 0 (1)\n" +
+				"\t0003 1:7    setlocal 0\n" +
+				"\t0006 1:24   local 0\n" +
+				"\t0009 1:20   setlocal 1\n" +
+				"\t0012 1:27   local 1\n" +
+				"\t0015        return\n",
+		},
+		"closures": tcase{
+			str: "let f = fn(a) {\n" +
+				"\tlet g = fn(n) { return g(n) + a }\n" +
+				"\treturn fn() { return g }\n" +
+				"}",
+			out: "0 main: params 0, locals 0\n" +
+				"\t0000 1:9    closure 0 (0.0)\n" +
+				"\t0003 1:5    setglobal 0 (\"f\")\n" +
+				"\t0006        unit\n" +
+				"\t0007        return\n" +
+				"0.0 f: params 1, locals 2\n" +
+				"\t0000 2:10   closure 0 (0.0.0)\n" +
+				"\t0003 2:6    setlocal 1\n" +
+				"\t0006 3:9    closure 1 (0.0.1)\n" +
+				"\t0009        return\n" +
+				"\t0010        unit\n" +
+				"\t0011        return\n" +
+				"0.0.0 g: params 1, locals 2, self 1, captures [local 0]\n" +
+				"\t0000 2:25   local 1\n" +
+				"\t0003 2:27   local 0\n" +
+				"\t0006 2:25   call 1\n" +
+				"\t0009 2:32   capture 0\n" +
+				"\t0012 2:25   add\n" +
+				"\t0013        return\n" +
+				"\t0014        unit\n" +
+				"\t0015        return\n" +
+				"0.0.1 fn: params 0, locals 0, captures [local 1]\n" +
+				"\t0000 3:23   capture 0\n" +
+				"\t0003        return\n" +
+				"\t0004        unit\n" +
+				"\t0005        return\n",
+		},
+	}
+
+	for k, v := range tcases {
+		t.Run(k, fn(v))
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	fi := parser.FileInfo{Name: "test", Line: 1, Col: 5}
+	bad := &ast.BadExpr{BaseNode: ast.BaseNode{Fi: fi}}
+
+	_, err := compile.Compile(&ast.File{
+		Stmts: []interface{}{
+			&ast.ExprStmt{X: bad},
+			&ast.BadStmt{BaseNode: ast.BaseNode{Fi: fi}},
+		},
+	})
+	assert.Eq(t, "test:1:5: bad expression (and 1 more errors)",
+		err.Error())
+
+	f, err := ast.ParseFile("test", "1 / 0\na + (1 << 70)")
+	assert.Eq(t, nil, err)
+
+	_, err = compile.Compile(f)
+	at := func(line, col int64) parser.FileInfo {
+		return parser.FileInfo{Name: "test", Line: line, Col: col}
+	}
+
+	assert.Eq(t, diag.List{
+		{
+			Span:    diag.Span{Start: at(1, 1), End: at(1, 6)},
+			Message: "division by zero",
+		},
+		{
+			Span:    diag.Span{Start: at(2, 6), End: at(2, 13)},
+			Message: "constant 1180591620717411303424 overflows int",
+		},
+	}, err)
+}
+
+func TestCompileCopies(t *testing.T) {
+	src := "let f = fn(x) { return x + 2 * 3 }\nf(1 + 1)"
+	f, err := ast.ParseFile("test", src)
+	assert.Eq(t, nil, err)
+
+	_, err = compile.Compile(f)
+	assert.Eq(t, nil, err)
+
+	// the constants are folded on a copy
+	orig, err := ast.ParseFile("test", src)
+	assert.Eq(t, nil, err)
+	assert.DeepEq(t, orig, f)
+}
+
+func TestSpan(t *testing.T) {
+	f, err := ast.ParseFile("test", "a +\n\tb")
+	assert.Eq(t, nil, err)
+
+	fn, err := compile.Compile(f)
+	assert.Eq(t, nil, err)
+
+	fi := func(line, col int64) parser.FileInfo {
+		return parser.FileInfo{Name: "test", Line: line, Col: col}
+	}
+
+	span := func(start, end parser.FileInfo) diag.Span {
+		return diag.Span{Start: start, End: end}
+	}
+
+	assert.Eq(t, span(fi(1, 1), fi(1, 2)), fn.Span(0))
+	assert.Eq(t, span(fi(2, 2), fi(2, 3)), fn.Span(3))
+	assert.Eq(t, span(fi(1, 1), fi(2, 3)), fn.Span(6))
+	assert.Eq(t, span(fi(1, 1), fi(2, 3)), fn.Span(7))
+	assert.Eq(t, 1, fn.Operand(3))
+}
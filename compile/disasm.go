@@ -0,0 +1,83 @@
+package compile
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ear7h/lang/ast/parser"
+)
+
+// Disassemble writes a listing of fn and the functions it contains to w,
+// one instruction per line with its pc, position, and operand. The
+// functions are numbered by their path from fn, which is 0.
+func Disassemble(w io.Writer, fn *Func) error {
+	return disassemble(w, fn, "0")
+}
+
+func disassemble(w io.Writer, fn *Func, path string) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%s %s: params %d, locals %d", path, fn.Name,
+		fn.Params, fn.Locals)
+	if fn.Self >= 0 {
+		fmt.Fprintf(&b, ", self %d", fn.Self)
+	}
+	if len(fn.Captures) > 0 {
+		caps := make([]string, len(fn.Captures))
+		for i, v := range fn.Captures {
+			caps[i] = fmt.Sprintf("capture %d", v.Index)
+			if v.Local {
+				caps[i] = fmt.Sprintf("local %d", v.Index)
+			}
+		}
+		fmt.Fprintf(&b, ", captures [%s]", strings.Join(caps, ", "))
+	}
+	b.WriteString("\n")
+
+	// only the starts are printed, when they change
+	pos := 0
+	var last parser.FileInfo
+	for pc := 0; pc < len(fn.Code); pc += Op(fn.Code[pc]).Size() {
+		op := Op(fn.Code[pc])
+
+		at := ""
+		if pos < len(fn.Pos) && fn.Pos[pos].PC == pc {
+			if fi := fn.Pos[pos].Fi; fi != last {
+				at = fmt.Sprintf("%d:%d", fi.Line, fi.Col)
+				last = fi
+			}
+			pos++
+		}
+
+		fmt.Fprintf(&b, "\t%04d %-6s %s", pc, at, op)
+
+		if op.Size() > 1 {
+			a := fn.Operand(pc)
+			fmt.Fprintf(&b, " %d", a)
+
+			switch op {
+			case OpConst, OpGlobal, OpSetGlobal, OpField:
+				fmt.Fprintf(&b, " (%#v)", fn.Consts[a])
+			case OpClosure:
+				fmt.Fprintf(&b, " (%s.%d)", path, a)
+			}
+		}
+
+		b.WriteString("\n")
+	}
+
+	_, err := io.WriteString(w, b.String())
+	if err != nil {
+		return err
+	}
+
+	for i, v := range fn.Funcs {
+		err = disassemble(w, v, fmt.Sprintf("%s.%d", path, i))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
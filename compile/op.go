@@ -0,0 +1,149 @@
+package compile
+
+import (
+	"fmt"
+
+	"github.com/ear7h/lang/ast"
+)
+
+// Op is a bytecode instruction. Its operand, if it has one, is the
+// following two bytes, big endian.
+type Op byte
+
+const (
+	OpConst     Op = iota // push Consts[a]
+	OpUnit                // push unit
+	OpPop                 // pop
+	OpLocal               // push local a
+	OpSetLocal            // pop into local a
+	OpCapture             // push captured variable a
+	OpGlobal              // push the global named Consts[a]
+	OpSetGlobal           // pop into the global named Consts[a]
+	OpClosure             // push a closure of Funcs[a]
+
+	OpPos   // +x
+	OpNeg   // -x
+	OpNot   // !x
+	OpAddr  // &x
+	OpDeref // *x
+
+	OpAdd // x + y
+	OpSub // x - y
+	OpMul // x * y
+	OpDiv // x / y
+	OpMod // x % y
+	OpShl // x << y
+	OpShr // x >> y
+	OpAnd // x & y
+	OpOr  // x | y
+	OpXor // x ^ y
+	OpLt  // x < y
+	OpGt  // x > y
+	OpLte // x <= y
+	OpGte // x >= y
+	OpEq  // x == y
+	OpNeq // x != y
+
+	OpJumpAnd // jump to a if the bool x is false, otherwise pop
+	OpJumpOr  // jump to a if the bool x is true, otherwise pop
+	OpBoolAnd // check that the right operand of && is a bool
+	OpBoolOr  // check that the right operand of || is a bool
+
+	OpField  // replace x with its field named Consts[a]
+	OpCall   // call the function below the a arguments
+	OpReturn // return x
+
+	numOps
+)
+
+type opInfo struct {
+	name     string
+	operand  bool
+	operator string
+}
+
+var ops = [numOps]opInfo{
+	OpConst:     {"const", true, ""},
+	OpUnit:      {"unit", false, ""},
+	OpPop:       {"pop", false, ""},
+	OpLocal:     {"local", true, ""},
+	OpSetLocal:  {"setlocal", true, ""},
+	OpCapture:   {"capture", true, ""},
+	OpGlobal:    {"global", true, ""},
+	OpSetGlobal: {"setglobal", true, ""},
+	OpClosure:   {"closure", true, ""},
+
+	OpPos:   {"pos", false, string(ast.UnaryPos)},
+	OpNeg:   {"neg", false, string(ast.UnaryNeg)},
+	OpNot:   {"not", false, string(ast.UnaryNot)},
+	OpAddr:  {"addr", false, string(ast.UnaryAddr)},
+	OpDeref: {"deref", false, string(ast.UnaryDeref)},
+
+	OpAdd: {"add", false, ast.BinaryAdd},
+	OpSub: {"sub", false, ast.BinarySub},
+	OpMul: {"mul", false, ast.BinaryMul},
+	OpDiv: {"div", false, ast.BinaryDiv},
+	OpMod: {"mod", false, ast.BinaryMod},
+	OpShl: {"shl", false, ast.BinaryShl},
+	OpShr: {"shr", false, ast.BinaryShr},
+	OpAnd: {"and", false, ast.BinaryBitAnd},
+	OpOr:  {"or", false, ast.BinaryBitOr},
+	OpXor: {"xor", false, ast.BinaryBitXor},
+	OpLt:  {"lt", false, ast.BinaryLt},
+	OpGt:  {"gt", false, ast.BinaryGt},
+	OpLte: {"lte", false, ast.BinaryLte},
+	OpGte: {"gte", false, ast.BinaryGte},
+	OpEq:  {"eq", false, ast.BinaryEq},
+	OpNeq: {"neq", false, ast.BinaryNeq},
+
+	OpJumpAnd: {"jumpand", true, ast.BinaryBoolAnd},
+	OpJumpOr:  {"jumpor", true, ast.BinaryBoolOr},
+	OpBoolAnd: {"booland", false, ast.BinaryBoolAnd},
+	OpBoolOr:  {"boolor", false, ast.BinaryBoolOr},
+
+	OpField:  {"field", true, ""},
+	OpCall:   {"call", true, ""},
+	OpReturn: {"return", false, ""},
+}
+
+var unaryOps = map[rune]Op{}
+
+var binaryOps = map[string]Op{}
+
+func init() {
+	for op := OpPos; op <= OpDeref; op++ {
+		unaryOps[rune(ops[op].operator[0])] = op
+	}
+
+	for op := OpAdd; op <= OpNeq; op++ {
+		binaryOps[ops[op].operator] = op
+	}
+}
+
+func (op Op) String() string {
+	if op >= numOps {
+		return fmt.Sprintf("op(%d)", byte(op))
+	}
+
+	return ops[op].name
+}
+
+// Size returns the number of bytes of the instruction, including its
+// operand
+func (op Op) Size() int {
+	if op < numOps && ops[op].operand {
+		return 3
+	}
+
+	return 1
+}
+
+// Operator returns the ast operator op implements, or "" if it isn't
+// an operator
+func (op Op) Operator() string {
+	if op >= numOps {
+		return ""
+	}
+
+	return ops[op].operator
+}
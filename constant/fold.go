@@ -0,0 +1,245 @@
+package constant
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/ear7h/lang/ast"
+	"github.com/ear7h/lang/diag"
+)
+
+// Eval returns the value of the expression n, which is unknown if n
+// isn't constant. The error is a diag.List of the divisions by
+// zero and overflows in n.
+func Eval(n interface{}) (Value, error) {
+	f := &folder{}
+	_, v := f.fold(n)
+
+	return v, f.errs.Err()
+}
+
+// Fold returns a copy of the tree rooted at n with its constant int and
+// string expressions replaced by literals, negative ints are the
+// negation of a literal, except the smallest int, which is
+// -9223372036854775807 - 1. The tree of n is left as it is, the copy
+// shares its identifiers and literals. Only whole constant expressions
+// are replaced, their operands may be bigger than an int64. Bool
+// constants aren't replaced since true and false are identifiers,
+// which could be shadowed, but their operands are. The error is a
+// diag.List of the divisions by zero and overflows, and of the
+// constants which don't fit in an int64, those expressions are left as
+// they are.
+func Fold(n ast.Node) (ast.Node, error) {
+	f := &folder{rewrite: true}
+	ret := f.expr(n)
+
+	return ret.(ast.Node), f.errs.Err()
+}
+
+type folder struct {
+	rewrite bool // replace the constant expressions
+	errs    diag.List
+}
+
+func (f *folder) errorf(n ast.Node, format string, args ...interface{}) {
+	f.errs = append(f.errs, diag.Errorf(n, format, args...))
+}
+
+// fold returns a copy of n with its non-constant expressions folded,
+// with the value of n. Constant expressions are left to the caller,
+// which may fold them into a bigger one.
+func (f *folder) fold(n interface{}) (interface{}, Value) {
+	unknown := MakeUnknown()
+
+	switch n := n.(type) {
+	case *ast.File:
+		c := *n
+		c.Stmts = f.stmts(n.Stmts)
+		return &c, unknown
+	case *ast.ExprStmt:
+		c := *n
+		c.X = f.expr(n.X)
+		return &c, unknown
+	case *ast.ReturnStmt:
+		c := *n
+		if n.X != nil {
+			c.X = f.expr(n.X)
+		}
+		return &c, unknown
+	case *ast.LetStmt:
+		c := *n
+		c.X = f.expr(n.X)
+		return &c, unknown
+	case *ast.BlockStmt:
+		return f.block(n), unknown
+	case *ast.FuncLit:
+		c := *n
+		c.Body = f.block(n.Body)
+		return &c, unknown
+	case *ast.ObjExpr:
+		c := f.obj(n)
+		c.Object = f.expr(n.Object)
+		return c, unknown
+	case *ast.NumberLiteral:
+		return n, MakeInt64(n.Parsed)
+	case *ast.StringLiteral:
+		return n, MakeString(n.Parsed)
+	case *ast.UnaryExpr:
+		c := *n
+
+		var x Value
+		c.Operand, x = f.fold(n.Operand)
+		if x.Kind() == Unknown {
+			return &c, unknown
+		}
+
+		v := UnaryOp(n.Op, x)
+		if v.Kind() == Unknown {
+			c.Operand = f.literal(c.Operand, x)
+		}
+
+		return &c, v
+	case *ast.BinaryExpr:
+		c := *n
+
+		var x, y Value
+		c.Left, x = f.fold(n.Left)
+		c.Right, y = f.fold(n.Right)
+
+		v := unknown
+		if x.Kind() != Unknown && y.Kind() != Unknown {
+			var err error
+			v, err = BinaryOp(x, n.Op, y)
+			if err != nil {
+				f.errorf(n, "%v", err)
+				return &c, unknown
+			}
+		}
+
+		if v.Kind() == Unknown {
+			c.Left = f.literal(c.Left, x)
+			c.Right = f.literal(c.Right, y)
+		}
+
+		return &c, v
+	}
+
+	return n, unknown
+}
+
+// stmts returns the folded copies of stmts
+func (f *folder) stmts(stmts []interface{}) []interface{} {
+	if stmts == nil {
+		return nil
+	}
+
+	ret := make([]interface{}, len(stmts))
+	for i, v := range stmts {
+		ret[i] = f.expr(v)
+	}
+
+	return ret
+}
+
+func (f *folder) block(n *ast.BlockStmt) *ast.BlockStmt {
+	c := *n
+	c.Stmts = f.stmts(n.Stmts)
+	return &c
+}
+
+// obj returns a copy of the chain of selections and calls n with their
+// arguments folded, the root object is left to the caller
+func (f *folder) obj(n *ast.ObjExpr) *ast.ObjExpr {
+	c := *n
+	c.Args = f.stmts(n.Args)
+	if right, ok := n.Right.(*ast.ObjExpr); ok {
+		c.Right = f.obj(right)
+	}
+
+	return &c
+}
+
+// expr folds n, which isn't the operand of a constant expression
+func (f *folder) expr(n interface{}) interface{} {
+	ret, v := f.fold(n)
+	return f.literal(ret, v)
+}
+
+// literal returns the replacement of the expression n, whose value is
+// v. Int and string constants are literals, and bool constants have
+// their operands replaced.
+func (f *folder) literal(n interface{}, v Value) interface{} {
+	if !f.rewrite {
+		return n
+	}
+
+	node, ok := n.(ast.Node)
+	if !ok {
+		return n
+	}
+
+	switch n.(type) {
+	case *ast.NumberLiteral, *ast.StringLiteral:
+		return n
+	}
+
+	base := ast.BaseNode{Fi: node.FileInfo(), EndFi: node.End()}
+
+	switch v.Kind() {
+	case String:
+		s := StringVal(v)
+		return &ast.StringLiteral{
+			BaseNode: base,
+			Orig:     ast.Quote(s),
+			Parsed:   s,
+		}
+	case Int:
+		x := IntVal(v)
+		if !x.IsInt64() {
+			f.errorf(node, "constant %v overflows int", v)
+			return n
+		}
+
+		i := x.Int64()
+		lit := func(i int64) *ast.NumberLiteral {
+			return &ast.NumberLiteral{
+				BaseNode: base,
+				Orig:     strconv.FormatInt(i, 10),
+				Parsed:   i,
+			}
+		}
+
+		neg := func(x interface{}) *ast.UnaryExpr {
+			return &ast.UnaryExpr{
+				BaseNode: base,
+				Op:       ast.UnaryNeg,
+				Operand:  x,
+			}
+		}
+
+		switch {
+		case i >= 0:
+			return lit(i)
+		case i == math.MinInt64:
+			// the negation of its magnitude would overflow
+			return &ast.BinaryExpr{
+				BaseNode: base,
+				Left:     neg(lit(math.MaxInt64)),
+				Op:       ast.BinarySub,
+				Right:    lit(1),
+			}
+		}
+
+		return neg(lit(-i))
+	case Bool:
+		switch node := node.(type) {
+		case *ast.UnaryExpr:
+			node.Operand = f.expr(node.Operand)
+		case *ast.BinaryExpr:
+			node.Left = f.expr(node.Left)
+			node.Right = f.expr(node.Right)
+		}
+	}
+
+	return n
+}
@@ -0,0 +1,198 @@
+package constant_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ear7h/lang/ast"
+	"github.com/ear7h/lang/constant"
+	"github.com/ear7h/lang/diag"
+	"github.com/ear7h/lang/format"
+	"github.com/ear7h/lang/internal/assert"
+)
+
+func TestFold(t *testing.T) {
+	type tcase struct {
+		str  string
+		out  string
+		errs []string
+	}
+
+	fn := func(tc tcase) func(t *testing.T) {
+		return func(t *testing.T) {
+			f, err := ast.ParseFile("test", tc.str)
+			assert.Eq(t, nil, err)
+
+			n, err := constant.Fold(f)
+
+			var errs []string
+			if err != nil {
+				for _, v := range err.(diag.List) {
+					errs = append(errs, v.Error())
+				}
+			}
+			assert.Eq(t, tc.errs, errs)
+
+			var b strings.Builder
+			err = format.Node(&b, n)
+			assert.Eq(t, nil, err)
+			assert.Eq(t, tc.out, b.String())
+		}
+	}
+
+	tcases := map[string]tcase{
+		"literal": tcase{
+			str: "1",
+			out: "1\n",
+		},
+		"arith": tcase{
+			str: "1 + 2 * 3 - 4 / 2 % 3",
+			out: "5\n",
+		},
+		"truncated division": tcase{
+			str: "-7 / 2; -7 % 2",
+			out: "-3\n-1\n",
+		},
+		"negative": tcase{
+			str: "1 - 5",
+			out: "-4\n",
+		},
+		"parens": tcase{
+			str: "(1 + 2) * (3)",
+			out: "9\n",
+		},
+		"bits": tcase{
+			str: "1 << 4 | 3 & 6 ^ 1",
+			out: "3\n",
+		},
+		"big operands": tcase{
+			str: "(1 << 100) >> 98",
+			out: "4\n",
+		},
+		"strings": tcase{
+			str: `"a" + "b" + "c"`,
+			out: "\"abc\"\n",
+		},
+		"bool operands": tcase{
+			str: `1 + 2 < 4 && "a" + "b" == "ab"`,
+			out: "3 < 4 && \"ab\" == \"ab\"\n",
+		},
+		"non-constant": tcase{
+			str: "a + 2 * 3",
+			out: "a + 6\n",
+		},
+		"left associative": tcase{
+			str: "a + 2 + 3",
+			out: "a + 2 + 3\n",
+		},
+		"statements": tcase{
+			str: "let a = 2 * 3\n" +
+				"let f = fn(x) { return x + (1 + 1) }\n" +
+				"{ f(2 * 2, -(1 + 1)) }",
+			out: "let a = 6\n" +
+				"let f = fn(x) {\n\treturn x + 2\n}\n" +
+				"{\n\tf(4, -2)\n}\n",
+		},
+		"fields": tcase{
+			str: "(1 + 1).a",
+			out: "2.a\n",
+		},
+		"operator not defined": tcase{
+			str: `(1 + 1) + ("a" + "b")`,
+			out: "2 + \"ab\"\n",
+		},
+		"division by zero": tcase{
+			str: "1 / (2 - 2); a + 1 % 0",
+			out: "1 / (2 - 2)\na + 1 % 0\n",
+			errs: []string{
+				"test:1:1: division by zero",
+				"test:1:18: division by zero",
+			},
+		},
+		"shift overflow": tcase{
+			str: "1 << 513; 1 << (0 - 1); 1 << 512",
+			out: "1 << 513\n1 << (0 - 1)\n1 << 512\n",
+			errs: []string{
+				"test:1:1: constant shift overflow",
+				"test:1:11: constant shift overflow",
+				"test:1:25: constant shift overflow",
+			},
+		},
+		"overflows int": tcase{
+			str: "1 << 63",
+			out: "1 << 63\n",
+			errs: []string{
+				"test:1:1: constant 9223372036854775808 overflows int",
+			},
+		},
+		"min int": tcase{
+			str: "0 - 9223372036854775807 - 1",
+			out: "(-9223372036854775807) - 1\n",
+		},
+		"below min int": tcase{
+			str: "0 - 9223372036854775807 - 2",
+			out: "0 - 9223372036854775807 - 2\n",
+			errs: []string{
+				"test:1:1: constant -9223372036854775809 overflows int",
+			},
+		},
+		"overflow": tcase{
+			str: "(1 << 511) * 4",
+			out: "1 << 511 * 4\n",
+			errs: []string{
+				"test:1:1: constant overflow",
+			},
+		},
+	}
+
+	for k, v := range tcases {
+		t.Run(k, fn(v))
+	}
+}
+
+func TestFoldPositions(t *testing.T) {
+	f, err := ast.ParseFile("test", "a + (1 + 2)")
+	assert.Eq(t, nil, err)
+
+	bin := f.Stmts[0].(*ast.ExprStmt).X.(*ast.BinaryExpr)
+	paren := bin.Right.(ast.Node)
+
+	n, err := constant.Fold(f)
+	assert.Eq(t, nil, err)
+
+	lit := n.(*ast.File).Stmts[0].(*ast.ExprStmt).X.(*ast.BinaryExpr).Right.(*ast.NumberLiteral)
+	assert.Eq(t, int64(3), lit.Parsed)
+	assert.Eq(t, "3", lit.Orig)
+	assert.Eq(t, paren.FileInfo(), lit.FileInfo())
+	assert.Eq(t, paren.End(), lit.End())
+}
+
+func TestFoldCopies(t *testing.T) {
+	src := "let f = fn(x) { return x + 2 * 3 }\n{ f(1 + 1).a }"
+	f, err := ast.ParseFile("test", src)
+	assert.Eq(t, nil, err)
+
+	_, err = constant.Fold(f)
+	assert.Eq(t, nil, err)
+
+	orig, err := ast.ParseFile("test", src)
+	assert.Eq(t, nil, err)
+	assert.DeepEq(t, orig, f)
+}
+
+func TestFoldQuote(t *testing.T) {
+	f, err := ast.ParseFile("test", "\"a\\\\\" + \"\\tb\r\"")
+	assert.Eq(t, nil, err)
+
+	n, err := constant.Fold(f)
+	assert.Eq(t, nil, err)
+
+	// the literal is written with the escapes the parser knows
+	lit := n.(*ast.File).Stmts[0].(*ast.ExprStmt).X.(*ast.StringLiteral)
+	assert.Eq(t, "\"a\\\\\\tb\r\"", lit.Orig)
+
+	g, err := ast.ParseFile("test", lit.Orig)
+	assert.Eq(t, nil, err)
+	assert.Eq(t, lit.Parsed,
+		g.Stmts[0].(*ast.ExprStmt).X.(*ast.StringLiteral).Parsed)
+}
@@ -0,0 +1,240 @@
+// Package constant evaluates constant expressions and folds them into
+// literals.
+//
+// Constants are the number and string literals and the unary and
+// binary expressions over constants. Integer constants have arbitrary
+// precision, up to MaxBits bits, so 1 << 100 >> 98 is the constant 4.
+// Only the folded results need to fit in an int64.
+package constant
+
+import (
+	"errors"
+	"math/big"
+	"strconv"
+
+	"github.com/ear7h/lang/ast"
+)
+
+// MaxBits is the size limit of integer constants
+const MaxBits = 512
+
+// Kind is the kind of a constant value
+type Kind int
+
+const (
+	Unknown Kind = iota // not a constant
+	Bool
+	String
+	Int
+)
+
+// Value is the value of a constant
+type Value interface {
+	Kind() Kind
+	String() string
+}
+
+type (
+	unknownVal struct{}
+	boolVal    bool
+	stringVal  string
+	intVal     struct{ x *big.Int }
+)
+
+func (unknownVal) Kind() Kind { return Unknown }
+func (boolVal) Kind() Kind    { return Bool }
+func (stringVal) Kind() Kind  { return String }
+func (intVal) Kind() Kind     { return Int }
+
+func (unknownVal) String() string  { return "unknown" }
+func (v boolVal) String() string   { return strconv.FormatBool(bool(v)) }
+func (v stringVal) String() string { return strconv.Quote(string(v)) }
+func (v intVal) String() string    { return v.x.String() }
+
+// MakeUnknown returns the value of non-constant expressions
+func MakeUnknown() Value {
+	return unknownVal{}
+}
+
+// MakeBool returns the constant b
+func MakeBool(b bool) Value {
+	return boolVal(b)
+}
+
+// MakeString returns the constant s
+func MakeString(s string) Value {
+	return stringVal(s)
+}
+
+// MakeInt64 returns the constant x
+func MakeInt64(x int64) Value {
+	return intVal{big.NewInt(x)}
+}
+
+// MakeInt returns the constant x, which is copied
+func MakeInt(x *big.Int) Value {
+	return intVal{new(big.Int).Set(x)}
+}
+
+// BoolVal returns the value of the Bool constant v
+func BoolVal(v Value) bool {
+	return bool(v.(boolVal))
+}
+
+// StringVal returns the value of the String constant v
+func StringVal(v Value) string {
+	return string(v.(stringVal))
+}
+
+// Int64Val returns the value of the Int constant v, and whether it fits
+// in an int64
+func Int64Val(v Value) (int64, bool) {
+	x := v.(intVal).x
+	return x.Int64(), x.IsInt64()
+}
+
+// IntVal returns a copy of the value of the Int constant v
+func IntVal(v Value) *big.Int {
+	return new(big.Int).Set(v.(intVal).x)
+}
+
+var (
+	// ErrDivisionByZero is returned for constant divisions by zero
+	ErrDivisionByZero = errors.New("division by zero")
+
+	// ErrShiftOverflow is returned for shifts of integer constants
+	// by negative counts or past MaxBits
+	ErrShiftOverflow = errors.New("constant shift overflow")
+
+	// ErrOverflow is returned for integer constants of more than
+	// MaxBits bits
+	ErrOverflow = errors.New("constant overflow")
+)
+
+// UnaryOp returns op x, it's unknown if op isn't defined on x
+func UnaryOp(op rune, x Value) Value {
+	switch x := x.(type) {
+	case intVal:
+		switch op {
+		case ast.UnaryPos:
+			return x
+		case ast.UnaryNeg:
+			return intVal{new(big.Int).Neg(x.x)}
+		}
+	case boolVal:
+		if op == ast.UnaryNot {
+			return !x
+		}
+	}
+
+	return MakeUnknown()
+}
+
+// BinaryOp returns x op y. It's unknown if the operands are different
+// kinds or op isn't defined on them, and the error is one of
+// ErrDivisionByZero, ErrShiftOverflow and ErrOverflow.
+func BinaryOp(x Value, op string, y Value) (Value, error) {
+	if x.Kind() != y.Kind() {
+		return MakeUnknown(), nil
+	}
+
+	switch x := x.(type) {
+	case intVal:
+		return intOp(x.x, op, y.(intVal).x)
+	case stringVal:
+		y := y.(stringVal)
+		switch op {
+		case ast.BinaryAdd:
+			return x + y, nil
+		case ast.BinaryLt:
+			return boolVal(x < y), nil
+		case ast.BinaryGt:
+			return boolVal(x > y), nil
+		case ast.BinaryLte:
+			return boolVal(x <= y), nil
+		case ast.BinaryGte:
+			return boolVal(x >= y), nil
+		case ast.BinaryEq:
+			return boolVal(x == y), nil
+		case ast.BinaryNeq:
+			return boolVal(x != y), nil
+		}
+	case boolVal:
+		y := y.(boolVal)
+		switch op {
+		case ast.BinaryBoolAnd:
+			return x && y, nil
+		case ast.BinaryBoolOr:
+			return x || y, nil
+		case ast.BinaryEq:
+			return boolVal(x == y), nil
+		case ast.BinaryNeq:
+			return boolVal(x != y), nil
+		}
+	}
+
+	return MakeUnknown(), nil
+}
+
+func intOp(x *big.Int, op string, y *big.Int) (Value, error) {
+	z := new(big.Int)
+
+	switch op {
+	case ast.BinaryAdd:
+		z.Add(x, y)
+	case ast.BinarySub:
+		z.Sub(x, y)
+	case ast.BinaryMul:
+		z.Mul(x, y)
+	case ast.BinaryDiv, ast.BinaryMod:
+		if y.Sign() == 0 {
+			return MakeUnknown(), ErrDivisionByZero
+		}
+
+		// truncated, like Go
+		if op == ast.BinaryDiv {
+			z.Quo(x, y)
+		} else {
+			z.Rem(x, y)
+		}
+	case ast.BinaryShl, ast.BinaryShr:
+		if y.Sign() < 0 || !y.IsInt64() || y.Int64() > MaxBits {
+			return MakeUnknown(), ErrShiftOverflow
+		}
+
+		if op == ast.BinaryShl {
+			z.Lsh(x, uint(y.Int64()))
+			if z.BitLen() > MaxBits {
+				return MakeUnknown(), ErrShiftOverflow
+			}
+		} else {
+			z.Rsh(x, uint(y.Int64()))
+		}
+	case ast.BinaryBitAnd:
+		z.And(x, y)
+	case ast.BinaryBitOr:
+		z.Or(x, y)
+	case ast.BinaryBitXor:
+		z.Xor(x, y)
+	case ast.BinaryLt:
+		return boolVal(x.Cmp(y) < 0), nil
+	case ast.BinaryGt:
+		return boolVal(x.Cmp(y) > 0), nil
+	case ast.BinaryLte:
+		return boolVal(x.Cmp(y) <= 0), nil
+	case ast.BinaryGte:
+		return boolVal(x.Cmp(y) >= 0), nil
+	case ast.BinaryEq:
+		return boolVal(x.Cmp(y) == 0), nil
+	case ast.BinaryNeq:
+		return boolVal(x.Cmp(y) != 0), nil
+	default:
+		return MakeUnknown(), nil
+	}
+
+	if z.BitLen() > MaxBits {
+		return MakeUnknown(), ErrOverflow
+	}
+
+	return intVal{z}, nil
+}
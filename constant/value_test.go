@@ -0,0 +1,209 @@
+package constant_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ear7h/lang/ast"
+	"github.com/ear7h/lang/constant"
+	"github.com/ear7h/lang/internal/assert"
+)
+
+func TestEval(t *testing.T) {
+	type tcase struct {
+		str  string
+		kind constant.Kind
+		out  string
+		err  string
+	}
+
+	fn := func(tc tcase) func(t *testing.T) {
+		return func(t *testing.T) {
+			f, err := ast.ParseFile("test", tc.str)
+			assert.Eq(t, nil, err)
+
+			v, err := constant.Eval(f.Stmts[0].(*ast.ExprStmt).X)
+			if tc.err != "" {
+				assert.Eq(t, tc.err, err.Error())
+			} else {
+				assert.Eq(t, nil, err)
+			}
+
+			assert.Eq(t, tc.kind, v.Kind())
+			assert.Eq(t, tc.out, v.String())
+		}
+	}
+
+	tcases := map[string]tcase{
+		"int": tcase{
+			str:  "-(2 + 3) * 4",
+			kind: constant.Int,
+			out:  "-20",
+		},
+		"big": tcase{
+			str:  "1 << 100",
+			kind: constant.Int,
+			out:  "1267650600228229401496703205376",
+		},
+		"string": tcase{
+			str:  `"a\n" + "b"`,
+			kind: constant.String,
+			out:  `"a\nb"`,
+		},
+		"cmp": tcase{
+			str:  `"a" < "b"`,
+			kind: constant.Bool,
+			out:  "true",
+		},
+		"bool": tcase{
+			str:  "!(1 == 2) && (1 << 70 > 1 << 69 || 1 > 2)",
+			kind: constant.Bool,
+			out:  "true",
+		},
+		"not constant": tcase{
+			str:  "1 + a",
+			kind: constant.Unknown,
+			out:  "unknown",
+		},
+		"true is an identifier": tcase{
+			str:  "!true",
+			kind: constant.Unknown,
+			out:  "unknown",
+		},
+		"mismatched": tcase{
+			str:  `1 + "a"`,
+			kind: constant.Unknown,
+			out:  "unknown",
+		},
+		"not defined": tcase{
+			str:  `-"a"`,
+			kind: constant.Unknown,
+			out:  "unknown",
+		},
+		"division by zero": tcase{
+			str:  "1 + 1 % 0",
+			kind: constant.Unknown,
+			out:  "unknown",
+			err:  "test:1:5: division by zero",
+		},
+	}
+
+	for k, v := range tcases {
+		t.Run(k, fn(v))
+	}
+}
+
+func TestBinaryOp(t *testing.T) {
+	type tcase struct {
+		x   constant.Value
+		op  string
+		y   constant.Value
+		out string
+		err error
+	}
+
+	fn := func(tc tcase) func(t *testing.T) {
+		return func(t *testing.T) {
+			v, err := constant.BinaryOp(tc.x, tc.op, tc.y)
+			assert.Eq(t, tc.err, err)
+			assert.Eq(t, tc.out, v.String())
+		}
+	}
+
+	i := constant.MakeInt64
+	huge := constant.MakeInt(new(big.Int).Lsh(big.NewInt(1), constant.MaxBits))
+
+	tcases := map[string]tcase{
+		"add": tcase{
+			x:   i(1),
+			op:  ast.BinaryAdd,
+			y:   i(2),
+			out: "3",
+		},
+		"bool": tcase{
+			x:   constant.MakeBool(true),
+			op:  ast.BinaryBoolAnd,
+			y:   constant.MakeBool(false),
+			out: "false",
+		},
+		"string eq": tcase{
+			x:   constant.MakeString("a"),
+			op:  ast.BinaryNeq,
+			y:   constant.MakeString("a"),
+			out: "false",
+		},
+		"mismatched kinds": tcase{
+			x:   i(1),
+			op:  ast.BinaryAdd,
+			y:   constant.MakeString("a"),
+			out: "unknown",
+		},
+		"not defined": tcase{
+			x:   constant.MakeString("a"),
+			op:  ast.BinarySub,
+			y:   constant.MakeString("a"),
+			out: "unknown",
+		},
+		"div by zero": tcase{
+			x:   i(1),
+			op:  ast.BinaryDiv,
+			y:   i(0),
+			out: "unknown",
+			err: constant.ErrDivisionByZero,
+		},
+		"mod by zero": tcase{
+			x:   i(1),
+			op:  ast.BinaryMod,
+			y:   i(0),
+			out: "unknown",
+			err: constant.ErrDivisionByZero,
+		},
+		"negative shift": tcase{
+			x:   i(1),
+			op:  ast.BinaryShr,
+			y:   i(-1),
+			out: "unknown",
+			err: constant.ErrShiftOverflow,
+		},
+		"shift overflow": tcase{
+			x:   i(2),
+			op:  ast.BinaryShl,
+			y:   i(constant.MaxBits - 1),
+			out: "unknown",
+			err: constant.ErrShiftOverflow,
+		},
+		"right shift": tcase{
+			x:   huge,
+			op:  ast.BinaryShr,
+			y:   i(constant.MaxBits),
+			out: "1",
+		},
+		"overflow": tcase{
+			x:   huge,
+			op:  ast.BinaryAdd,
+			y:   huge,
+			out: "unknown",
+			err: constant.ErrOverflow,
+		},
+	}
+
+	for k, v := range tcases {
+		t.Run(k, fn(v))
+	}
+}
+
+func TestValues(t *testing.T) {
+	x, ok := constant.Int64Val(constant.MakeInt64(-5))
+	assert.Eq(t, int64(-5), x)
+	assert.Eq(t, true, ok)
+
+	_, ok = constant.Int64Val(constant.UnaryOp(ast.UnaryNeg,
+		constant.MakeInt(new(big.Int).Lsh(big.NewInt(1), 64))))
+	assert.Eq(t, false, ok)
+
+	assert.Eq(t, "ab", constant.StringVal(constant.MakeString("ab")))
+	assert.Eq(t, false, constant.BoolVal(
+		constant.UnaryOp(ast.UnaryNot, constant.MakeBool(true))))
+	assert.Eq(t, constant.Unknown,
+		constant.UnaryOp(ast.UnaryNot, constant.MakeInt64(1)).Kind())
+}
@@ -0,0 +1,183 @@
+// Package diag describes errors and warnings about source code and
+// renders them with snippets of the source, underlining what they're
+// about.
+//
+// The parser reports its errors as parser.ErrorList or *parser.Error,
+// and the stages after it as List, FromError turns them into
+// diagnostics.
+package diag
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ear7h/lang/ast"
+	"github.com/ear7h/lang/ast/parser"
+)
+
+// Severity is how bad a diagnostic is
+type Severity int
+
+const (
+	Error Severity = iota
+	Warning
+	Info
+)
+
+var severityNames = [...]string{
+	Error:   "error",
+	Warning: "warning",
+	Info:    "info",
+}
+
+func (s Severity) String() string {
+	if s >= 0 && int(s) < len(severityNames) {
+		return severityNames[s]
+	}
+
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// Span is a range of source, from Start to just before End. A zero End
+// is the token at Start.
+type Span struct {
+	Start, End parser.FileInfo
+}
+
+// NodeSpan returns the span of the node n
+func NodeSpan(n ast.Node) Span {
+	return Span{Start: n.FileInfo(), End: n.End()}
+}
+
+// IsZero reports whether the span is unknown
+func (s Span) IsZero() bool {
+	return s.Start == parser.FileInfo{}
+}
+
+// Note is more information about a diagnostic, possibly about another
+// span of source
+type Note struct {
+	Span    Span
+	Message string
+}
+
+// Suggestion is a fix for a diagnostic, replacing the source in Span,
+// if it's not zero, with Text
+type Suggestion struct {
+	Span    Span
+	Message string
+	Text    string
+}
+
+// Diagnostic is an error or warning about the source in Span
+type Diagnostic struct {
+	Severity    Severity
+	Span        Span
+	Message     string
+	Notes       []Note
+	Suggestions []Suggestion
+}
+
+// Errorf returns an error about the source of the node n
+func Errorf(n ast.Node, format string, args ...interface{}) *Diagnostic {
+	return &Diagnostic{
+		Span:    NodeSpan(n),
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+// Error returns the diagnostic like a parser.Error, with its notes on
+// the following lines
+func (d *Diagnostic) Error() string {
+	var b strings.Builder
+	if !d.Span.IsZero() {
+		b.WriteString(d.Span.Start.String())
+		b.WriteString(": ")
+	}
+
+	if d.Severity != Error {
+		b.WriteString(d.Severity.String())
+		b.WriteString(": ")
+	}
+
+	b.WriteString(d.Message)
+
+	for _, v := range d.Notes {
+		b.WriteString("\n\t")
+		b.WriteString(noteMessage(v))
+	}
+
+	return b.String()
+}
+
+// List is a list of diagnostics, the stages after the parser report
+// their errors as a List
+type List []*Diagnostic
+
+// Sort sorts the list by position, diagnostics at the same position
+// are kept in the order they were added
+func (l List) Sort() {
+	sort.SliceStable(l, func(i, j int) bool {
+		a, b := l[i].Span.Start, l[j].Span.Start
+		switch {
+		case a.Name != b.Name:
+			return a.Name < b.Name
+		case a.Line != b.Line:
+			return a.Line < b.Line
+		}
+
+		return a.Col < b.Col
+	})
+}
+
+func (l List) Error() string {
+	switch len(l) {
+	case 0:
+		return "no errors"
+	case 1:
+		return l[0].Error()
+	}
+
+	return fmt.Sprintf("%s (and %d more errors)", l[0], len(l)-1)
+}
+
+// Err returns nil if the list is empty, and the list otherwise
+func (l List) Err() error {
+	if len(l) == 0 {
+		return nil
+	}
+
+	return l
+}
+
+// FromError returns the errors of err as diagnostics. Other errors than
+// diagnostics and parser errors are diagnostics without a span.
+func FromError(err error) []*Diagnostic {
+	switch err := err.(type) {
+	case nil:
+		return nil
+	case *Diagnostic:
+		return []*Diagnostic{err}
+	case List:
+		return append([]*Diagnostic(nil), err...)
+	case *parser.Error:
+		return []*Diagnostic{fromParserError(err)}
+	case parser.ErrorList:
+		ret := make([]*Diagnostic, len(err))
+		for i, v := range err {
+			ret[i] = fromParserError(v)
+		}
+
+		return ret
+	}
+
+	return []*Diagnostic{{Message: err.Error()}}
+}
+
+func fromParserError(err *parser.Error) *Diagnostic {
+	return &Diagnostic{
+		Span:    Span{Start: err.Fi},
+		Message: err.Msg,
+	}
+}
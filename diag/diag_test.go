@@ -0,0 +1,326 @@
+package diag_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ear7h/lang/ast"
+	"github.com/ear7h/lang/ast/parser"
+	"github.com/ear7h/lang/diag"
+	"github.com/ear7h/lang/internal/assert"
+)
+
+func fi(line, col int64) parser.FileInfo {
+	return parser.FileInfo{Name: "test", Line: line, Col: col}
+}
+
+func TestRender(t *testing.T) {
+	type tcase struct {
+		src string
+		d   *diag.Diagnostic
+		out string
+	}
+
+	fn := func(tc tcase) func(t *testing.T) {
+		return func(t *testing.T) {
+			var b strings.Builder
+			r := diag.NewRenderer(&b, map[string]string{"test": tc.src})
+			err := r.Render(tc.d)
+			assert.Eq(t, nil, err)
+			assert.Eq(t, tc.out, b.String())
+		}
+	}
+
+	src := "let f = fn(x) {\n\treturn x + 1\n}\nf(\"a\") + b\n"
+
+	tcases := map[string]tcase{
+		"token": tcase{
+			src: src,
+			d: &diag.Diagnostic{
+				Span:    diag.Span{Start: fi(4, 3)},
+				Message: "bad argument",
+			},
+			out: "error: bad argument\n" +
+				" --> test:4:3\n" +
+				"  |\n" +
+				"4 | f(\"a\") + b\n" +
+				"  |   ^^^\n" +
+				"\n",
+		},
+		"span": tcase{
+			src: src,
+			d: &diag.Diagnostic{
+				Severity: diag.Warning,
+				Span:     diag.Span{Start: fi(4, 1), End: fi(4, 7)},
+				Message:  "unused result",
+			},
+			out: "warning: unused result\n" +
+				" --> test:4:1\n" +
+				"  |\n" +
+				"4 | f(\"a\") + b\n" +
+				"  | ^^^^^^\n" +
+				"\n",
+		},
+		"tabs": tcase{
+			src: src,
+			d: &diag.Diagnostic{
+				Span:    diag.Span{Start: fi(2, 9)},
+				Message: "here",
+			},
+			out: "error: here\n" +
+				" --> test:2:9\n" +
+				"  |\n" +
+				"2 | \treturn x + 1\n" +
+				"  | \t       ^\n" +
+				"\n",
+		},
+		"lines": tcase{
+			src: src,
+			d: &diag.Diagnostic{
+				Span:    diag.Span{Start: fi(1, 9), End: fi(3, 2)},
+				Message: "function",
+			},
+			out: "error: function\n" +
+				" --> test:1:9\n" +
+				"  |\n" +
+				"1 | let f = fn(x) {\n" +
+				"  |         ^^^^^^^\n" +
+				"2 | \treturn x + 1\n" +
+				"  | \t^^^^^^^^^^^^\n" +
+				"3 | }\n" +
+				"  | ^\n" +
+				"\n",
+		},
+		"notes": tcase{
+			src: src,
+			d: &diag.Diagnostic{
+				Span:    diag.Span{Start: fi(4, 3)},
+				Message: "mismatched types",
+				Notes: []diag.Note{
+					{Span: diag.Span{Start: fi(2, 9), End: fi(2, 14)}, Message: "x is int"},
+					{Span: diag.Span{Start: fi(4, 1)}, Message: "f is called"},
+					{Message: "plain"},
+					{Span: diag.Span{Start: parser.FileInfo{Name: "other", Line: 1, Col: 1}}, Message: "elsewhere"},
+				},
+			},
+			out: "error: mismatched types\n" +
+				" --> test:4:3\n" +
+				"  |\n" +
+				"2 | \treturn x + 1\n" +
+				"  | \t       ----- x is int\n" +
+				"...\n" +
+				"4 | f(\"a\") + b\n" +
+				"  |   ^^^\n" +
+				"  | - f is called\n" +
+				"  = note: plain\n" +
+				"  = note: elsewhere at other:1:1\n" +
+				"\n",
+		},
+		"suggestions": tcase{
+			src: src,
+			d: &diag.Diagnostic{
+				Span:    diag.Span{Start: fi(4, 3)},
+				Message: "mismatched types",
+				Suggestions: []diag.Suggestion{
+					{Span: diag.Span{Start: fi(4, 3), End: fi(4, 6)}, Message: "use an int", Text: "1"},
+					{Span: diag.Span{Start: fi(4, 7), End: fi(4, 11)}, Message: "remove it"},
+					{Message: "read the manual"},
+				},
+			},
+			out: "error: mismatched types\n" +
+				" --> test:4:3\n" +
+				"  |\n" +
+				"4 | f(\"a\") + b\n" +
+				"  |   ^^^\n" +
+				"  = help: use an int\n" +
+				"  |\n" +
+				"4 | f(1) + b\n" +
+				"  |   ~\n" +
+				"  = help: remove it\n" +
+				"  |\n" +
+				"4 | f(\"a\")\n" +
+				"  |       -\n" +
+				"  = help: read the manual\n" +
+				"\n",
+		},
+		"end of file": tcase{
+			src: "1 +",
+			d: &diag.Diagnostic{
+				Span:    diag.Span{Start: fi(1, 4)},
+				Message: "unexpected end of file",
+			},
+			out: "error: unexpected end of file\n" +
+				" --> test:1:4\n" +
+				"  |\n" +
+				"1 | 1 +\n" +
+				"  |    ^\n" +
+				"\n",
+		},
+		"wide gutter": tcase{
+			src: strings.Repeat("\n", 9) + "a",
+			d: &diag.Diagnostic{
+				Span:    diag.Span{Start: fi(10, 1)},
+				Message: "undefined: a",
+			},
+			out: "error: undefined: a\n" +
+				"  --> test:10:1\n" +
+				"   |\n" +
+				"10 | a\n" +
+				"   | ^\n" +
+				"\n",
+		},
+		"no source": tcase{
+			d: &diag.Diagnostic{
+				Span:    diag.Span{Start: parser.FileInfo{Name: "other", Line: 1, Col: 1}},
+				Message: "undefined: a",
+				Notes:   []diag.Note{{Message: "plain"}},
+			},
+			out: "error: undefined: a\n" +
+				"--> other:1:1\n" +
+				"= note: plain\n" +
+				"\n",
+		},
+		"no span": tcase{
+			d: &diag.Diagnostic{
+				Severity: diag.Info,
+				Message:  "no files",
+			},
+			out: "info: no files\n\n",
+		},
+	}
+
+	for k, v := range tcases {
+		t.Run(k, fn(v))
+	}
+}
+
+func TestRenderColor(t *testing.T) {
+	var b strings.Builder
+	r := diag.NewRenderer(&b, map[string]string{"test": "a"})
+	assert.Eq(t, false, r.Color)
+
+	r.Color = true
+	err := r.Render(&diag.Diagnostic{
+		Span:    diag.Span{Start: fi(1, 1)},
+		Message: "undefined: a",
+	})
+	assert.Eq(t, nil, err)
+	assert.Eq(t, "\x1b[1m\x1b[31merror\x1b[0m\x1b[1m: undefined: a\x1b[0m\n"+
+		" \x1b[34m--> \x1b[0mtest:1:1\n"+
+		"\x1b[34m  |\x1b[0m\n"+
+		"\x1b[34m1 |\x1b[0m a\n"+
+		"\x1b[34m  |\x1b[0m \x1b[31m^\x1b[0m\n"+
+		"\n", b.String())
+}
+
+func TestFromError(t *testing.T) {
+	_, err := ast.ParseFile("test", "1 +\n)")
+	assert.Eq(t, 2, len(err.(parser.ErrorList)))
+
+	ds := diag.FromError(err)
+	assert.Eq(t, 2, len(ds))
+	assert.Eq(t, fi(1, 4), ds[0].Span.Start)
+	assert.Eq(t, diag.Error, ds[0].Severity)
+	assert.Eq(t, err.(parser.ErrorList)[0].Error(), ds[0].Error())
+
+	d := &diag.Diagnostic{
+		Span:    diag.Span{Start: fi(1, 1), End: fi(1, 8)},
+		Message: "mismatched types",
+		Notes: []diag.Note{
+			{Span: diag.Span{Start: fi(2, 1)}, Message: "x is int"},
+			{Message: "y is string"},
+		},
+	}
+	ds = diag.FromError(diag.List{d})
+	assert.Eq(t, []*diag.Diagnostic{d}, ds)
+	assert.Eq(t, "test:1:1: mismatched types\n"+
+		"\tx is int at test:2:1\n"+
+		"\ty is string", ds[0].Error())
+
+	ds = diag.FromError(&parser.Error{Fi: fi(1, 1), Msg: "a\n\tb"})
+	assert.Eq(t, []*diag.Diagnostic{{
+		Span:    diag.Span{Start: fi(1, 1)},
+		Message: "a\n\tb",
+	}}, ds)
+
+	ds = diag.FromError(errors.New("no files"))
+	assert.Eq(t, "no files", ds[0].Error())
+	assert.Eq(t, true, ds[0].Span.IsZero())
+
+	assert.Eq(t, 0, len(diag.FromError(nil)))
+}
+
+func TestNodeSpan(t *testing.T) {
+	f, err := ast.ParseFile("test", "a + (b)")
+	assert.Eq(t, nil, err)
+
+	n := f.Stmts[0].(*ast.ExprStmt).X.(ast.Node)
+	assert.Eq(t, diag.Span{Start: fi(1, 1), End: fi(1, 8)}, diag.NodeSpan(n))
+}
+
+func TestList(t *testing.T) {
+	l := diag.List{
+		{Span: diag.Span{Start: fi(2, 1)}, Message: "b"},
+		{Span: diag.Span{Start: fi(1, 3)}, Message: "a"},
+		{Span: diag.Span{Start: fi(2, 1)}, Message: "c"},
+	}
+	l.Sort()
+	assert.Eq(t, "test:1:3: a (and 2 more errors)", l.Error())
+	assert.Eq(t, "b", l[1].Message)
+	assert.Eq(t, "c", l[2].Message)
+
+	assert.Eq(t, nil, diag.List(nil).Err())
+}
+
+func TestDidYouMean(t *testing.T) {
+	type tcase struct {
+		name  string
+		names []string
+		out   string
+	}
+
+	fn := func(tc tcase) func(t *testing.T) {
+		return func(t *testing.T) {
+			f, err := ast.ParseFile("test", tc.name)
+			assert.Eq(t, nil, err)
+			id := f.Stmts[0].(*ast.ExprStmt).X.(*ast.Ident)
+
+			var out string
+			for _, v := range diag.DidYouMean(id, tc.names) {
+				assert.Eq(t, diag.NodeSpan(id), v.Span)
+				out = v.Text
+			}
+			assert.Eq(t, tc.out, out)
+		}
+	}
+
+	tcases := map[string]tcase{
+		"typo": tcase{
+			name:  "lenght",
+			names: []string{"length", "true", "false"},
+			out:   "length",
+		},
+		"closest": tcase{
+			name:  "counts",
+			names: []string{"count", "counter", "counts2"},
+			out:   "count",
+		},
+		"too far": tcase{
+			name:  "apple",
+			names: []string{"orange"},
+		},
+		"too short": tcase{
+			name:  "ab",
+			names: []string{"a", "b"},
+		},
+		"same name": tcase{
+			name:  "count",
+			names: []string{"count"},
+		},
+	}
+
+	for k, v := range tcases {
+		t.Run(k, fn(v))
+	}
+}
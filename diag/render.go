@@ -0,0 +1,376 @@
+package diag
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ear7h/lang/ast/token"
+)
+
+const (
+	reset  = "\x1b[0m"
+	bold   = "\x1b[1m"
+	red    = "\x1b[31m"
+	yellow = "\x1b[33m"
+	blue   = "\x1b[34m"
+	cyan   = "\x1b[36m"
+)
+
+var severityColors = [...]string{
+	Error:   red,
+	Warning: yellow,
+	Info:    cyan,
+}
+
+// Renderer writes diagnostics like rustc, with the lines of source they
+// are about and carets under their spans
+type Renderer struct {
+	w io.Writer
+	// Color colors the output with ANSI escapes
+	Color bool
+	// Sources has the text of the source files, by name. Diagnostics
+	// about other files are rendered without snippets.
+	Sources map[string]string
+}
+
+// NewRenderer returns a renderer writing to w, which colors its output
+// if w is a terminal
+func NewRenderer(w io.Writer, sources map[string]string) *Renderer {
+	return &Renderer{
+		w:       w,
+		Color:   IsTerminal(w),
+		Sources: sources,
+	}
+}
+
+// IsTerminal reports whether w is a terminal which should be colored,
+// which it isn't if the NO_COLOR environment variable is set
+func IsTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok || os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// Render writes the diagnostics, each followed by an empty line
+func (r *Renderer) Render(ds ...*Diagnostic) error {
+	var b strings.Builder
+	for _, d := range ds {
+		r.render(&b, d)
+	}
+
+	_, err := io.WriteString(r.w, b.String())
+	return err
+}
+
+// RenderError renders the diagnostics of err, see FromError
+func (r *Renderer) RenderError(err error) error {
+	return r.Render(FromError(err)...)
+}
+
+func (r *Renderer) color(b *strings.Builder, color, s string) {
+	if !r.Color || color == "" {
+		b.WriteString(s)
+		return
+	}
+
+	b.WriteString(color)
+	b.WriteString(s)
+	b.WriteString(reset)
+}
+
+// label is a span underlined in a snippet
+type label struct {
+	span    Span
+	mark    rune
+	message string
+	color   string
+}
+
+func (r *Renderer) render(b *strings.Builder, d *Diagnostic) {
+	sevColor := ""
+	if d.Severity >= 0 && int(d.Severity) < len(severityColors) {
+		sevColor = severityColors[d.Severity]
+	}
+
+	r.color(b, bold+sevColor, d.Severity.String())
+	r.color(b, bold, ": "+d.Message)
+	b.WriteString("\n")
+
+	src, ok := r.Sources[d.Span.Start.Name]
+	if d.Span.IsZero() || !ok {
+		if !d.Span.IsZero() {
+			r.color(b, blue, "--> ")
+			b.WriteString(d.Span.Start.String() + "\n")
+		}
+
+		for _, v := range d.Notes {
+			b.WriteString("= note: " + noteMessage(v) + "\n")
+		}
+
+		for _, v := range d.Suggestions {
+			b.WriteString("= help: " + v.Message + "\n")
+		}
+
+		b.WriteString("\n")
+		return
+	}
+
+	labels := []label{{span: d.Span, mark: '^', color: sevColor}}
+	var notes []Note
+	for _, v := range d.Notes {
+		if v.Span.IsZero() || v.Span.Start.Name != d.Span.Start.Name {
+			notes = append(notes, v)
+			continue
+		}
+
+		labels = append(labels, label{
+			span:    v.Span,
+			mark:    '-',
+			message: v.Message,
+			color:   blue,
+		})
+	}
+
+	lines := strings.Split(src, "\n")
+
+	// the gutter fits the largest line number
+	last := int64(0)
+	for _, v := range labels {
+		last = max(last, v.span.Start.Line, v.span.End.Line)
+	}
+	for _, v := range d.Suggestions {
+		last = max(last, v.Span.Start.Line)
+	}
+	gutter := strings.Repeat(" ", len(strconv.FormatInt(last, 10)))
+
+	b.WriteString(gutter)
+	r.color(b, blue, "--> ")
+	b.WriteString(d.Span.Start.String() + "\n")
+
+	r.snippet(b, gutter, lines, labels)
+
+	for _, v := range notes {
+		b.WriteString(gutter)
+		r.color(b, blue, " = ")
+		r.color(b, bold, "note")
+		b.WriteString(": " + noteMessage(v) + "\n")
+	}
+
+	for _, v := range d.Suggestions {
+		r.suggestion(b, gutter, lines, v)
+	}
+
+	b.WriteString("\n")
+}
+
+func noteMessage(n Note) string {
+	if n.Span.IsZero() {
+		return n.Message
+	}
+
+	return fmt.Sprintf("%s at %s", n.Message, n.Span.Start)
+}
+
+// snippet writes the lines the labels span, each followed by the
+// underlines of the labels on it
+func (r *Renderer) snippet(b *strings.Builder, gutter string, lines []string, labels []label) {
+	// the end of each label, on lines which exist
+	for i, v := range labels {
+		labels[i].span = clip(v.span, lines)
+	}
+
+	var nums []int64
+	seen := map[int64]bool{}
+	for _, v := range labels {
+		for n := v.span.Start.Line; n <= v.span.End.Line; n++ {
+			if !seen[n] {
+				seen[n] = true
+				nums = append(nums, n)
+			}
+		}
+	}
+	sort.Slice(nums, func(i, j int) bool { return nums[i] < nums[j] })
+
+	r.gutter(b, gutter, "")
+	b.WriteString("\n")
+
+	for i, n := range nums {
+		if i > 0 && n > nums[i-1]+1 {
+			r.color(b, blue, "...")
+			b.WriteString("\n")
+		}
+
+		line := lines[n-1]
+
+		r.gutter(b, gutter, strconv.FormatInt(n, 10))
+		if line != "" {
+			b.WriteString(" " + line)
+		}
+		b.WriteString("\n")
+
+		for _, v := range labels {
+			if n < v.span.Start.Line || n > v.span.End.Line {
+				continue
+			}
+
+			start, end := int64(1), int64(utf8.RuneCountInString(line))+1
+			if n == v.span.Start.Line {
+				start = v.span.Start.Col
+			} else {
+				// continued lines are underlined from their
+				// indentation
+				start += int64(utf8.RuneCountInString(line) -
+					utf8.RuneCountInString(strings.TrimLeft(line, " \t")))
+			}
+			if n == v.span.End.Line {
+				end = v.span.End.Col
+			}
+
+			r.gutter(b, gutter, "")
+			b.WriteString(" " + pad(line, start))
+			r.color(b, v.color, strings.Repeat(string(v.mark), int(max(end-start, 1))))
+			if n == v.span.End.Line && v.message != "" {
+				r.color(b, v.color, " "+v.message)
+			}
+			b.WriteString("\n")
+		}
+	}
+}
+
+// suggestion writes the suggestion, with the line it changes if it's
+// on a single line
+func (r *Renderer) suggestion(b *strings.Builder, gutter string, lines []string, s Suggestion) {
+	b.WriteString(gutter)
+	r.color(b, blue, " = ")
+	r.color(b, bold, "help")
+	b.WriteString(": " + s.Message + "\n")
+
+	span := clip(s.Span, lines)
+	if s.Span.IsZero() || span.Start.Line != span.End.Line {
+		return
+	}
+
+	line := []rune(lines[span.Start.Line-1])
+	start, end := span.Start.Col-1, span.End.Col-1
+	if start > int64(len(line)) || end > int64(len(line)) {
+		return
+	}
+
+	fixed := string(line[:start]) + s.Text + string(line[end:])
+
+	r.gutter(b, gutter, "")
+	b.WriteString("\n")
+	r.gutter(b, gutter, strconv.FormatInt(span.Start.Line, 10))
+	b.WriteString(" " + fixed + "\n")
+	r.gutter(b, gutter, "")
+	b.WriteString(" " + pad(fixed, span.Start.Col))
+
+	n := utf8.RuneCountInString(s.Text)
+	if n == 0 {
+		// a deletion
+		r.color(b, red, "-")
+	} else {
+		r.color(b, cyan, strings.Repeat("~", n))
+	}
+	b.WriteString("\n")
+}
+
+// gutter writes the gutter with the line number n, which may be empty
+func (r *Renderer) gutter(b *strings.Builder, gutter, n string) {
+	r.color(b, blue, n+gutter[len(n):]+" |")
+}
+
+// Clip returns the span in src, with the end of the token at its start
+// if it doesn't have an end, and its lines within those of src
+func (s Span) Clip(src string) Span {
+	return clip(s, strings.Split(src, "\n"))
+}
+
+// clip returns the span with the end of the token at its start if it
+// doesn't have an end, and its end on the last line if it's after it
+func clip(s Span, lines []string) Span {
+	if s.Start.Line < 1 {
+		s.Start.Line = 1
+	}
+	if s.Start.Line > int64(len(lines)) {
+		s.Start.Line = int64(len(lines))
+	}
+
+	if s.End.Line == 0 {
+		s.End = s.Start
+		s.End.Col = tokenEnd(lines[s.Start.Line-1], s.Start.Col)
+	}
+
+	if s.End.Line > int64(len(lines)) {
+		s.End.Line = int64(len(lines))
+		s.End.Col = int64(utf8.RuneCountInString(lines[len(lines)-1])) + 1
+	}
+
+	return s
+}
+
+// tokenEnd returns the column after the token of line at col, or after
+// col if there isn't one
+func tokenEnd(line string, col int64) int64 {
+	rest := []rune(line)
+	if col < 1 || col > int64(len(rest)) {
+		return col + 1
+	}
+
+	toks, err := token.Scan(string(rest[col-1:]), "")
+	if err != nil || len(toks) == 0 || toks[0].Fi.Col != 1 ||
+		toks[0].Kind == token.EOF || toks[0].Kind == token.Newline {
+		return col + 1
+	}
+
+	return col + int64(utf8.RuneCountInString(toks[0].Value))
+}
+
+// pad returns the white space up to col of line, with tabs where line
+// has them so carets line up
+func pad(line string, col int64) string {
+	var b strings.Builder
+
+	i := int64(1)
+	for _, c := range line {
+		if i >= col {
+			break
+		}
+
+		if c == '\t' {
+			b.WriteRune('\t')
+		} else {
+			b.WriteRune(' ')
+		}
+		i++
+	}
+
+	for ; i < col; i++ {
+		b.WriteRune(' ')
+	}
+
+	return b.String()
+}
+
+func max(v int64, vs ...int64) int64 {
+	for _, w := range vs {
+		if w > v {
+			v = w
+		}
+	}
+
+	return v
+}
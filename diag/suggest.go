@@ -0,0 +1,75 @@
+package diag
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/ear7h/lang/ast"
+)
+
+// DidYouMean suggests replacing the identifier id with the one of names
+// most like it, if any is close enough to be a likely typo. Names
+// shorter than 3 runes are too short to tell.
+func DidYouMean(id *ast.Ident, names []string) []Suggestion {
+	names = append([]string(nil), names...)
+	sort.Strings(names)
+
+	best, dist := "", len([]rune(id.Name))/3
+	for _, v := range names {
+		if v == id.Name {
+			continue
+		}
+
+		if d := distance(id.Name, v); d <= dist && (best == "" || d < dist) {
+			best, dist = v, d
+		}
+	}
+
+	if best == "" {
+		return nil
+	}
+
+	return []Suggestion{{
+		Span:    NodeSpan(id),
+		Message: fmt.Sprintf("did you mean %s?", best),
+		Text:    best,
+	}}
+}
+
+// distance returns the number of runes which have to be inserted,
+// deleted or substituted to turn a into b
+func distance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+
+	prev := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := range ra {
+		cur := make([]int, len(rb)+1)
+		cur[0] = i + 1
+		for j := range rb {
+			cost := 1
+			if ra[i] == rb[j] {
+				cost = 0
+			}
+
+			cur[j+1] = min(prev[j]+cost, prev[j+1]+1, cur[j]+1)
+		}
+
+		prev = cur
+	}
+
+	return prev[len(rb)]
+}
+
+func min(v int, vs ...int) int {
+	for _, w := range vs {
+		if w < v {
+			v = w
+		}
+	}
+
+	return v
+}
@@ -0,0 +1,55 @@
+package eval
+
+// Env maps variable names to their values, falling back to the parent
+// environment for names it doesn't define
+type Env struct {
+	parent *Env
+	vars   map[string]interface{}
+}
+
+// NewEnv returns an empty environment nested in parent, which may be
+// nil
+func NewEnv(parent *Env) *Env {
+	return &Env{
+		parent: parent,
+		vars:   map[string]interface{}{},
+	}
+}
+
+// Universe is the outermost environment, of the predeclared names
+var Universe = &Env{
+	vars: map[string]interface{}{
+		"true":  true,
+		"false": false,
+	},
+}
+
+// Define defines name in env as v, shadowing any definition in the
+// parents and replacing one in env
+func (env *Env) Define(name string, v interface{}) {
+	env.vars[name] = v
+}
+
+// Lookup returns the value of name in env or the closest of its parents
+// which defines it
+func (env *Env) Lookup(name string) (interface{}, bool) {
+	for ; env != nil; env = env.parent {
+		if v, ok := env.vars[name]; ok {
+			return v, true
+		}
+	}
+
+	return nil, false
+}
+
+// names returns the names defined in env and its parents
+func (env *Env) names() []string {
+	var names []string
+	for ; env != nil; env = env.parent {
+		for k := range env.vars {
+			names = append(names, k)
+		}
+	}
+
+	return names
+}
@@ -0,0 +1,47 @@
+package eval
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ear7h/lang/constant"
+)
+
+// the errors of running a program, the vm reports the same ones
+
+var (
+	// ErrDivisionByZero is returned for integer divisions by zero, it's
+	// the error of constant divisions by zero too
+	ErrDivisionByZero = constant.ErrDivisionByZero
+
+	// ErrNegativeShift is returned for shifts by negative counts
+	ErrNegativeShift = errors.New("negative shift amount")
+
+	// ErrStackOverflow is returned for calls nested deeper than
+	// MaxDepth
+	ErrStackOverflow = errors.New("stack overflow")
+)
+
+// UndefinedError returns the error for a use of the undefined variable
+// name
+func UndefinedError(name string) error {
+	return fmt.Errorf("undefined: %s", name)
+}
+
+// NotDefinedError returns the error for the operator op on x
+func NotDefinedError(op string, x interface{}) error {
+	return fmt.Errorf("operator %s not defined on %s", op, TypeName(x))
+}
+
+// ArgumentsError returns the error for a call with have arguments of a
+// function with want parameters
+func ArgumentsError(have, want int) error {
+	return fmt.Errorf("wrong number of arguments: have %d, want %d",
+		have, want)
+}
+
+// NotFunctionError returns the error for a call of x, which isn't a
+// function
+func NotFunctionError(x interface{}) error {
+	return fmt.Errorf("cannot call non-function %s", TypeName(x))
+}
@@ -0,0 +1,534 @@
+// Package eval interprets syntax trees by walking them.
+//
+// Values are Go values: int64 for ints, float64, string and bool, nil
+// for unit, Record for records, *Pointer, and *Func and GoFunc for
+// functions. Ints wrap around like Go's, and && and || only evaluate
+// their right operand when the left one doesn't decide the result.
+package eval
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ear7h/lang/ast"
+	"github.com/ear7h/lang/constant"
+	"github.com/ear7h/lang/diag"
+)
+
+// MaxDepth is the limit of nested calls
+const MaxDepth = 10000
+
+// Record is a value with named fields, selected by ObjExpr
+type Record map[string]interface{}
+
+// Pointer is the address of a value
+type Pointer struct {
+	Elem interface{}
+}
+
+// Func is a function literal with the environment it was evaluated in
+type Func struct {
+	Lit *ast.FuncLit
+	Env *Env
+}
+
+// GoFunc is a function implemented in Go, its error is reported at the
+// position of the call
+type GoFunc func(args []interface{}) (interface{}, error)
+
+// Eval evaluates n, a file, statement or expression, in env, or in a
+// new environment nested in Universe if env is nil. The value of a file
+// or block is the value of its last statement, if it's an expression,
+// or of the top level return statement which stopped it. Like
+// compile.Compile, the constant expressions are folded first, on a copy
+// of n, so ints too big for an int64 are errors rather than wrapping
+// around. The error is the diag.List of constant.Fold, or a
+// *diag.Diagnostic spanning the node which failed.
+func Eval(n ast.Node, env *Env) (interface{}, error) {
+	if env == nil {
+		env = NewEnv(Universe)
+	}
+
+	n, err := constant.Fold(n)
+	if err != nil {
+		return nil, err
+	}
+
+	in := &interp{}
+	return in.run(n, env)
+}
+
+type interp struct {
+	depth int
+}
+
+func errorf(n ast.Node, format string, args ...interface{}) *diag.Diagnostic {
+	return diag.Errorf(n, format, args...)
+}
+
+func (in *interp) run(n ast.Node, env *Env) (interface{}, error) {
+	switch n := n.(type) {
+	case *ast.File:
+		v, _, err := in.stmts(n.Stmts, env)
+		return v, err
+	case *ast.ExprStmt, *ast.ReturnStmt, *ast.LetStmt, *ast.BlockStmt,
+		*ast.BadStmt:
+		v, _, err := in.stmt(n, env)
+		return v, err
+	default:
+		return in.expr(n, env)
+	}
+}
+
+// stmts executes the statements, and reports whether a return
+// statement stopped them
+func (in *interp) stmts(stmts []interface{}, env *Env) (interface{}, bool, error) {
+	var v interface{}
+	for _, s := range stmts {
+		var ret bool
+		var err error
+
+		v, ret, err = in.stmt(s, env)
+		if err != nil || ret {
+			return v, ret, err
+		}
+	}
+
+	return v, false, nil
+}
+
+// stmt executes the statement n, its value is the value of its
+// expression, if it's an expression or return statement
+func (in *interp) stmt(n interface{}, env *Env) (interface{}, bool, error) {
+	switch n := n.(type) {
+	case *ast.ExprStmt:
+		v, err := in.expr(n.X, env)
+		return v, false, err
+	case *ast.ReturnStmt:
+		if n.X == nil {
+			return nil, true, nil
+		}
+
+		v, err := in.expr(n.X, env)
+		return v, true, err
+	case *ast.LetStmt:
+		v, err := in.expr(n.X, env)
+		if err != nil {
+			return nil, false, err
+		}
+
+		env.Define(n.Name.Name, v)
+		return nil, false, nil
+	case *ast.BlockStmt:
+		return in.stmts(n.Stmts, NewEnv(env))
+	case ast.Node:
+		return nil, false, errorf(n, "bad statement")
+	}
+
+	return nil, false, fmt.Errorf("eval: unknown statement %T", n)
+}
+
+func (in *interp) expr(n interface{}, env *Env) (interface{}, error) {
+	switch n := n.(type) {
+	case *ast.NumberLiteral:
+		return n.Parsed, nil
+	case *ast.StringLiteral:
+		return n.Parsed, nil
+	case *ast.Ident:
+		v, ok := env.Lookup(n.Name)
+		if !ok {
+			d := errorf(n, "%v", UndefinedError(n.Name))
+			d.Suggestions = diag.DidYouMean(n, env.names())
+			return nil, d
+		}
+
+		return v, nil
+	case *ast.UnaryExpr:
+		return in.unary(n, env)
+	case *ast.BinaryExpr:
+		return in.binary(n, env)
+	case *ast.ObjExpr:
+		return in.obj(n, env)
+	case *ast.FuncLit:
+		return &Func{Lit: n, Env: env}, nil
+	case ast.Node:
+		return nil, errorf(n, "bad expression")
+	}
+
+	return nil, fmt.Errorf("eval: unknown expression %T", n)
+}
+
+func (in *interp) unary(n *ast.UnaryExpr, env *Env) (interface{}, error) {
+	x, err := in.expr(n.Operand, env)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := UnaryOp(n.Op, x)
+	if err != nil {
+		return nil, errorf(n, "%v", err)
+	}
+
+	return v, nil
+}
+
+// UnaryOp returns op x, where op is one of the ast.Unary operators
+func UnaryOp(op rune, x interface{}) (interface{}, error) {
+	switch op {
+	case ast.UnaryPos:
+		switch x.(type) {
+		case int64, float64:
+			return x, nil
+		}
+	case ast.UnaryNeg:
+		switch x := x.(type) {
+		case int64:
+			return -x, nil
+		case float64:
+			return -x, nil
+		}
+	case ast.UnaryNot:
+		if x, ok := x.(bool); ok {
+			return !x, nil
+		}
+	case ast.UnaryAddr:
+		// variables can't change, the address of one holds its value
+		return &Pointer{Elem: x}, nil
+	case ast.UnaryDeref:
+		if p, ok := x.(*Pointer); ok {
+			return p.Elem, nil
+		}
+
+		return nil, fmt.Errorf("invalid indirect of %s", TypeName(x))
+	}
+
+	return nil, NotDefinedError(string(op), x)
+}
+
+func (in *interp) binary(n *ast.BinaryExpr, env *Env) (interface{}, error) {
+	x, err := in.expr(n.Left, env)
+	if err != nil {
+		return nil, err
+	}
+
+	if n.Op == ast.BinaryBoolAnd || n.Op == ast.BinaryBoolOr {
+		b, ok := x.(bool)
+		if !ok {
+			return nil, errorf(n, "%v", NotDefinedError(n.Op, x))
+		}
+
+		// short circuit
+		if b == (n.Op == ast.BinaryBoolOr) {
+			return b, nil
+		}
+
+		y, err := in.expr(n.Right, env)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, ok := y.(bool); !ok {
+			return nil, errorf(n, "%v", NotDefinedError(n.Op, y))
+		}
+
+		return y, nil
+	}
+
+	y, err := in.expr(n.Right, env)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := BinaryOp(x, n.Op, y)
+	if err != nil {
+		return nil, errorf(n, "%v", err)
+	}
+
+	return v, nil
+}
+
+// BinaryOp returns x op y, where op is one of the ast.Binary operators
+// other than && and ||, which short circuit
+func BinaryOp(x interface{}, op string, y interface{}) (interface{}, error) {
+	if TypeName(x) != TypeName(y) {
+		return nil, fmt.Errorf("mismatched types %s and %s",
+			TypeName(x), TypeName(y))
+	}
+
+	switch x := x.(type) {
+	case int64:
+		return intOp(x, op, y.(int64))
+	case float64:
+		return floatOp(x, op, y.(float64))
+	case string:
+		return stringOp(x, op, y.(string))
+	}
+
+	return equalOp(x, op, y)
+}
+
+func intOp(x int64, op string, y int64) (interface{}, error) {
+	switch op {
+	case ast.BinaryAdd:
+		return x + y, nil
+	case ast.BinarySub:
+		return x - y, nil
+	case ast.BinaryMul:
+		return x * y, nil
+	case ast.BinaryDiv, ast.BinaryMod:
+		if y == 0 {
+			return nil, ErrDivisionByZero
+		}
+
+		if op == ast.BinaryDiv {
+			return x / y, nil
+		}
+
+		return x % y, nil
+	case ast.BinaryShl, ast.BinaryShr:
+		if y < 0 {
+			return nil, ErrNegativeShift
+		}
+
+		if op == ast.BinaryShl {
+			return x << uint64(y), nil
+		}
+
+		return x >> uint64(y), nil
+	case ast.BinaryBitAnd:
+		return x & y, nil
+	case ast.BinaryBitOr:
+		return x | y, nil
+	case ast.BinaryBitXor:
+		return x ^ y, nil
+	case ast.BinaryLt:
+		return x < y, nil
+	case ast.BinaryGt:
+		return x > y, nil
+	case ast.BinaryLte:
+		return x <= y, nil
+	case ast.BinaryGte:
+		return x >= y, nil
+	case ast.BinaryEq:
+		return x == y, nil
+	case ast.BinaryNeq:
+		return x != y, nil
+	}
+
+	return nil, NotDefinedError(op, x)
+}
+
+func floatOp(x float64, op string, y float64) (interface{}, error) {
+	switch op {
+	case ast.BinaryAdd:
+		return x + y, nil
+	case ast.BinarySub:
+		return x - y, nil
+	case ast.BinaryMul:
+		return x * y, nil
+	case ast.BinaryDiv:
+		return x / y, nil
+	case ast.BinaryLt:
+		return x < y, nil
+	case ast.BinaryGt:
+		return x > y, nil
+	case ast.BinaryLte:
+		return x <= y, nil
+	case ast.BinaryGte:
+		return x >= y, nil
+	case ast.BinaryEq:
+		return x == y, nil
+	case ast.BinaryNeq:
+		return x != y, nil
+	}
+
+	return nil, NotDefinedError(op, x)
+}
+
+func stringOp(x string, op string, y string) (interface{}, error) {
+	switch op {
+	case ast.BinaryAdd:
+		return x + y, nil
+	case ast.BinaryLt:
+		return x < y, nil
+	case ast.BinaryGt:
+		return x > y, nil
+	case ast.BinaryLte:
+		return x <= y, nil
+	case ast.BinaryGte:
+		return x >= y, nil
+	case ast.BinaryEq:
+		return x == y, nil
+	case ast.BinaryNeq:
+		return x != y, nil
+	}
+
+	return nil, NotDefinedError(op, x)
+}
+
+// equalOp compares the other values, records and Go functions can't be
+// compared
+func equalOp(x interface{}, op string, y interface{}) (interface{}, error) {
+	if op != ast.BinaryEq && op != ast.BinaryNeq {
+		return nil, NotDefinedError(op, x)
+	}
+
+	switch x.(type) {
+	case Record, GoFunc:
+		return nil, fmt.Errorf("%s values cannot be compared", TypeName(x))
+	}
+
+	return (x == y) == (op == ast.BinaryEq), nil
+}
+
+// obj evaluates a chain of field selections and calls
+func (in *interp) obj(n *ast.ObjExpr, env *Env) (interface{}, error) {
+	x, err := in.expr(n.Object, env)
+	if err != nil {
+		return nil, err
+	}
+
+	for v := n; v != nil; v, _ = v.Right.(*ast.ObjExpr) {
+		if v.Op == ast.ObjCall {
+			x, err = in.call(v, x, env)
+			if err != nil {
+				return nil, err
+			}
+
+			continue
+		}
+
+		x, err = Field(x, v.Arg.(*ast.Ident).Name)
+		if err != nil {
+			return nil, errorf(v, "%v", err)
+		}
+	}
+
+	return x, nil
+}
+
+// Field returns the field name of the record x
+func Field(x interface{}, name string) (interface{}, error) {
+	r, ok := x.(Record)
+	if !ok {
+		return nil, fmt.Errorf("%s has no field %s", TypeName(x), name)
+	}
+
+	v, ok := r[name]
+	if !ok {
+		return nil, fmt.Errorf("record has no field %s", name)
+	}
+
+	return v, nil
+}
+
+func (in *interp) call(n *ast.ObjExpr, fn interface{}, env *Env) (interface{}, error) {
+	args := make([]interface{}, len(n.Args))
+	for i, v := range n.Args {
+		var err error
+		args[i], err = in.expr(v, env)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	switch fn := fn.(type) {
+	case GoFunc:
+		v, err := fn(args)
+		if err != nil {
+			return nil, errorf(n, "%v", err)
+		}
+
+		return v, nil
+	case *Func:
+		params := fn.Lit.Params
+		if len(params) != len(args) {
+			return nil, errorf(n, "%v", ArgumentsError(len(args), len(params)))
+		}
+
+		if in.depth >= MaxDepth {
+			return nil, errorf(n, "%v", ErrStackOverflow)
+		}
+
+		in.depth++
+		defer func() { in.depth-- }()
+
+		local := NewEnv(fn.Env)
+		for i, v := range params {
+			local.Define(v.Name, args[i])
+		}
+
+		v, ret, err := in.stmts(fn.Lit.Body.Stmts, local)
+		if err != nil || !ret {
+			// functions without a return statement return unit
+			return nil, err
+		}
+
+		return v, nil
+	}
+
+	return nil, errorf(n, "%v", NotFunctionError(fn))
+}
+
+// TypeNamer is implemented by values of other packages, like the
+// closures of a virtual machine, to name their type
+type TypeNamer interface {
+	TypeName() string
+}
+
+// TypeName returns the name of the type of the value v
+func TypeName(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "unit"
+	case int64:
+		return "int"
+	case float64:
+		return "float"
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	case Record:
+		return "record"
+	case *Pointer:
+		return "pointer"
+	case *Func, GoFunc:
+		return "func"
+	case TypeNamer:
+		return v.TypeName()
+	}
+
+	return fmt.Sprintf("%T", v)
+}
+
+// String returns v for printing, strings are quoted and the fields of
+// records are in the order of their names
+func String(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "()"
+	case string:
+		return fmt.Sprintf("%q", v)
+	case Record:
+		names := make([]string, 0, len(v))
+		for k := range v {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+
+		fields := make([]string, len(names))
+		for i, k := range names {
+			fields[i] = k + ": " + String(v[k])
+		}
+
+		return "{" + strings.Join(fields, ", ") + "}"
+	case *Pointer:
+		return "&" + String(v.Elem)
+	case *Func, GoFunc:
+		return "func"
+	}
+
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,217 @@
+// Package format prints syntax trees as canonical source.
+//
+// The canonical form has one statement per line, keeping at most one
+// blank line between statements, blocks indented by tabs, single spaces
+// around binary operators and after commas, and only the parentheses
+// the precedence of the operators needs.
+// Number and string literals are spelled out from their values.
+package format
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/ear7h/lang/ast"
+)
+
+// Source formats src, the source of the file name. Files with syntax
+// errors are not formatted, the error is the one from ast.ParseFile.
+func Source(name, src string) (string, error) {
+	f, err := ast.ParseFile(name, src)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	err = Node(&b, f)
+	if err != nil {
+		return "", err
+	}
+
+	return b.String(), nil
+}
+
+// Node writes the canonical source of the node n, which is an *ast.File,
+// a statement or an expression. Trees with bad nodes can't be formatted.
+func Node(w io.Writer, n interface{}) error {
+	p := &printer{}
+	p.node(n)
+	if p.err != nil {
+		return p.err
+	}
+
+	_, err := io.WriteString(w, p.b.String())
+	return err
+}
+
+// operandPrec is the precedence of the operands of binary expressions,
+// higher than any binary operator
+const operandPrec = 100
+
+// unaryPrec is the precedence of unary expressions, their operand
+// extends as far right as it can so they bind looser than any binary
+// operator
+const unaryPrec = 0
+
+type printer struct {
+	b      strings.Builder
+	indent int
+	err    error
+}
+
+func (p *printer) errorf(format string, args ...interface{}) {
+	if p.err == nil {
+		p.err = fmt.Errorf("format: "+format, args...)
+	}
+}
+
+func (p *printer) node(n interface{}) {
+	switch n := n.(type) {
+	case *ast.File:
+		p.file(n)
+	case *ast.ExprStmt, *ast.ReturnStmt, *ast.LetStmt, *ast.BlockStmt,
+		*ast.BadStmt:
+		p.stmt(n)
+	default:
+		p.expr(n, unaryPrec)
+	}
+}
+
+func (p *printer) file(n *ast.File) {
+	p.stmts(n.Stmts)
+}
+
+// stmts prints each statement on its own line, at the current indent
+func (p *printer) stmts(stmts []interface{}) {
+	line := int64(0)
+	for i, v := range stmts {
+		// keep one blank line where there were any
+		fi := v.(ast.Node).FileInfo()
+		if i > 0 && fi.Line > line+1 {
+			p.b.WriteString("\n")
+		}
+		line = v.(ast.Node).End().Line
+
+		p.b.WriteString(strings.Repeat("\t", p.indent))
+		p.stmt(v)
+		p.b.WriteString("\n")
+	}
+}
+
+func (p *printer) block(n *ast.BlockStmt) {
+	if len(n.Stmts) == 0 {
+		p.b.WriteString("{}")
+		return
+	}
+
+	p.b.WriteString("{\n")
+	p.indent++
+	p.stmts(n.Stmts)
+	p.indent--
+	p.b.WriteString(strings.Repeat("\t", p.indent) + "}")
+}
+
+// list prints the expressions separated by commas
+func (p *printer) list(exprs []interface{}) {
+	for i, v := range exprs {
+		if i > 0 {
+			p.b.WriteString(", ")
+		}
+
+		p.expr(v, unaryPrec)
+	}
+}
+
+func (p *printer) stmt(n interface{}) {
+	switch n := n.(type) {
+	case *ast.ExprStmt:
+		p.expr(n.X, unaryPrec)
+	case *ast.ReturnStmt:
+		p.b.WriteString(ast.KeywordReturn)
+		if n.X != nil {
+			p.b.WriteString(" ")
+			p.expr(n.X, unaryPrec)
+		}
+	case *ast.LetStmt:
+		p.b.WriteString(ast.KeywordLet + " " + n.Name.Name + " = ")
+		p.expr(n.X, unaryPrec)
+	case *ast.BlockStmt:
+		p.block(n)
+	case *ast.BadStmt:
+		p.errorf("%v: bad statement", n.FileInfo())
+	default:
+		p.errorf("unknown statement %T", n)
+	}
+}
+
+// expr prints the expression n, in parentheses if its precedence is
+// lower than prec
+func (p *printer) expr(n interface{}, prec int) {
+	switch n := n.(type) {
+	case *ast.BinaryExpr:
+		opPrec := ast.BinaryPrecedence(n.Op)
+		p.paren(opPrec < prec, func() {
+			// left associative, the right operand needs parentheses
+			// at the same precedence
+			p.expr(n.Left, opPrec)
+			p.b.WriteString(" " + n.Op + " ")
+			p.expr(n.Right, opPrec+1)
+		})
+	case *ast.UnaryExpr:
+		p.paren(unaryPrec < prec, func() {
+			p.b.WriteRune(n.Op)
+			// nested unary operators could scan as one binary
+			// operator, like & &x
+			p.expr(n.Operand, unaryPrec+1)
+		})
+	case *ast.ObjExpr:
+		p.expr(n.Object, operandPrec)
+		for v := n; v != nil; {
+			if v.Op == ast.ObjCall {
+				p.b.WriteString("(")
+				p.list(v.Args)
+				p.b.WriteString(")")
+			} else {
+				p.b.WriteString(".")
+				p.expr(v.Arg, operandPrec)
+			}
+
+			v, _ = v.Right.(*ast.ObjExpr)
+		}
+	case *ast.FuncLit:
+		p.b.WriteString(ast.KeywordFn + "(")
+		for i, v := range n.Params {
+			if i > 0 {
+				p.b.WriteString(", ")
+			}
+
+			p.b.WriteString(v.Name)
+		}
+		p.b.WriteString(") ")
+		p.block(n.Body)
+	case *ast.Ident:
+		p.b.WriteString(n.Name)
+	case *ast.NumberLiteral:
+		p.b.WriteString(strconv.FormatInt(n.Parsed, 10))
+	case *ast.StringLiteral:
+		p.b.WriteString(ast.Quote(n.Parsed))
+	case *ast.BadExpr:
+		p.errorf("%v: bad expression", n.FileInfo())
+	default:
+		p.errorf("unknown expression %T", n)
+	}
+}
+
+func (p *printer) paren(paren bool, fn func()) {
+	if paren {
+		p.b.WriteString("(")
+	}
+
+	fn()
+
+	if paren {
+		p.b.WriteString(")")
+	}
+}
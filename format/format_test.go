@@ -0,0 +1,236 @@
+package format_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ear7h/lang/ast"
+	"github.com/ear7h/lang/format"
+	"github.com/ear7h/lang/internal/assert"
+)
+
+func TestSource(t *testing.T) {
+	type tcase struct {
+		str string
+		out string
+	}
+
+	fn := func(tc tcase) func(t *testing.T) {
+		return func(t *testing.T) {
+			out, err := format.Source("test", tc.str)
+			assert.Eq(t, nil, err)
+			assert.Eq(t, tc.out, out)
+		}
+	}
+
+	tcases := map[string]tcase{
+		"empty": tcase{
+			str: " \n\n",
+			out: "",
+		},
+		"spacing": tcase{
+			str: "a+b*c",
+			out: "a + b * c\n",
+		},
+		"stmts": tcase{
+			str: "a;b\n\n\n\nreturn   \n  return c;",
+			out: "a\nb\n\nreturn\nreturn c\n",
+		},
+		"redundant parens": tcase{
+			str: "((a + b)) + (c * d) + (e.f)",
+			out: "a + b + c * d + e.f\n",
+		},
+		"needed parens": tcase{
+			str: "(a + b) * c - (d - e) + (f.g).h",
+			out: "(a + b) * c - (d - e) + f.g.h\n",
+		},
+		"paren object": tcase{
+			str: "(a + b).c",
+			out: "(a + b).c\n",
+		},
+		"unary": tcase{
+			str: "-(a + b)\n(-a) + b\n!(!a)",
+			out: "-a + b\n(-a) + b\n!(!a)\n",
+		},
+		"literals": tcase{
+			str: "007 + \"a\tb\"",
+			out: "7 + \"a\\tb\"\n",
+		},
+		"lines": tcase{
+			str: "a +\n  b ==\n c",
+			out: "a + b == c\n",
+		},
+		"let": tcase{
+			str: "let   x=(1)",
+			out: "let x = 1\n",
+		},
+		"blocks": tcase{
+			str: "{1;{}\n\n\n{ 2 }}",
+			out: "{\n\t1\n\t{}\n\n\t{\n\t\t2\n\t}\n}\n",
+		},
+		"funcs": tcase{
+			str: "let f = fn( a,b ) { return a(b ,1).c }\nf(fn(){})",
+			out: "let f = fn(a, b) {\n\treturn a(b, 1).c\n}\nf(fn() {})\n",
+		},
+	}
+
+	for k, v := range tcases {
+		t.Run(k, fn(v))
+	}
+}
+
+// TestIdempotent checks that formatted source formats to itself, and
+// parses to the same tree as the original
+func TestIdempotent(t *testing.T) {
+	tcases := map[string]string{
+		"arith":   "1 + 2 * 3 - 4 / 5 % 6",
+		"assoc":   "a - (b - c) - d; (a - b) - (c - d)",
+		"groups":  "a << 1 * 2 + 3 < 4 && b || c & (d | e)",
+		"lowest":  "(a || b) << (c && d)",
+		"unary":   "-a * b; &(*x); (!a) == (!b)",
+		"objects": "a.b.c + (d + e).f + (1).g",
+		"return":  "return\nreturn (1 + 2) * 3\n\nreturn -(x)",
+		"strings": `"\\ \n" + "\t"`,
+		"funcs":   "let f = fn(x) {\nreturn fn() { { x } }\n}\nf(1)(2).a(3 + 4, -5)",
+	}
+
+	for k, v := range tcases {
+		src := v
+		t.Run(k, func(t *testing.T) {
+			once, err := format.Source("test", src)
+			assert.Eq(t, nil, err)
+
+			twice, err := format.Source("test", once)
+			assert.Eq(t, nil, err)
+			assert.Eq(t, once, twice)
+
+			assert.Eq(t, canonical(t, src), canonical(t, once))
+		})
+	}
+}
+
+// canonical returns the statements of src fully parenthesized, so
+// sources with the same tree have the same result
+func canonical(t *testing.T, src string) []string {
+	t.Helper()
+
+	f, err := ast.ParseFile("test", src)
+	assert.Eq(t, nil, err)
+
+	var ret []string
+	for _, v := range f.Stmts {
+		ret = append(ret, paren(v))
+	}
+
+	return ret
+}
+
+func paren(n interface{}) string {
+	switch n := n.(type) {
+	case *ast.ExprStmt:
+		return paren(n.X)
+	case *ast.ReturnStmt:
+		return "return " + paren(n.X)
+	case *ast.LetStmt:
+		return "let " + n.Name.Name + "=" + paren(n.X)
+	case *ast.BlockStmt:
+		return "{" + parens(n.Stmts) + "}"
+	case *ast.FuncLit:
+		s := "fn("
+		for _, v := range n.Params {
+			s += v.Name + ","
+		}
+		return s + ")" + paren(n.Body)
+	case *ast.BinaryExpr:
+		return "(" + paren(n.Left) + n.Op + paren(n.Right) + ")"
+	case *ast.UnaryExpr:
+		return "(" + string(n.Op) + paren(n.Operand) + ")"
+	case *ast.ObjExpr:
+		s := paren(n.Object)
+		for v := n; v != nil; v, _ = v.Right.(*ast.ObjExpr) {
+			if v.Op == ast.ObjCall {
+				s += "(" + parens(v.Args) + ")"
+			} else {
+				s += "." + paren(v.Arg)
+			}
+		}
+		return s
+	case *ast.Ident:
+		return n.Name
+	case *ast.NumberLiteral:
+		return n.Orig
+	case *ast.StringLiteral:
+		return n.Orig
+	}
+
+	return ""
+}
+
+func parens(ns []interface{}) string {
+	s := ""
+	for _, v := range ns {
+		s += paren(v) + ";"
+	}
+
+	return s
+}
+
+func TestNode(t *testing.T) {
+	type tcase struct {
+		n   interface{}
+		out string
+		err string
+	}
+
+	fn := func(tc tcase) func(t *testing.T) {
+		return func(t *testing.T) {
+			var b strings.Builder
+			err := format.Node(&b, tc.n)
+			if tc.err != "" {
+				assert.Eq(t, tc.err, err.Error())
+				return
+			}
+
+			assert.Eq(t, nil, err)
+			assert.Eq(t, tc.out, b.String())
+		}
+	}
+
+	ident := func(name string) *ast.Ident {
+		return &ast.Ident{Name: name}
+	}
+
+	tcases := map[string]tcase{
+		"binary": tcase{
+			n: &ast.BinaryExpr{
+				Op: "*",
+				Left: &ast.BinaryExpr{
+					Op:    "+",
+					Left:  ident("a"),
+					Right: ident("b"),
+				},
+				Right: &ast.UnaryExpr{Op: '-', Operand: ident("c")},
+			},
+			out: "(a + b) * (-c)",
+		},
+		"stmt": tcase{
+			n:   &ast.ReturnStmt{X: &ast.NumberLiteral{Parsed: 12}},
+			out: "return 12",
+		},
+		"file": tcase{
+			n: &ast.File{Stmts: []interface{}{
+				&ast.ExprStmt{X: &ast.StringLiteral{Parsed: "a\n"}},
+				&ast.ReturnStmt{},
+			}},
+			out: "\"a\\n\"\nreturn\n",
+		},
+		"bad": tcase{
+			n:   &ast.ExprStmt{X: &ast.BadExpr{}},
+			err: "format: :0:0: bad expression",
+		},
+	}
+
+	for k, v := range tcases {
+		t.Run(k, fn(v))
+	}
+}
@@ -1,3 +1,3 @@
 module github.com/ear7h/lang
 
-go 1.13
+go 1.18
@@ -0,0 +1,33 @@
+//go:build ignore
+
+// gen writes the generated grammars, see the package doc
+package main
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/ear7h/lang/highlight"
+)
+
+func main() {
+	for _, v := range highlight.Files {
+		var b bytes.Buffer
+		err := v.Write(&b)
+		if err != nil {
+			log.Fatalf("%s: %v", v.Name, err)
+		}
+
+		err = os.MkdirAll(filepath.Dir(v.Name), 0755)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		err = os.WriteFile(v.Name, b.Bytes(), 0644)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+}
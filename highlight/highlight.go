@@ -0,0 +1,198 @@
+// Package highlight generates grammars for highlighting lang in editors,
+// a TextMate grammar and a tree-sitter grammar with its highlight
+// queries. They are built from the keywords, operators and lexical forms
+// of the ast package, and the terminals of its grammar, so highlighting
+// doesn't disagree with the parser.
+//
+// The generated files are kept in this directory, run go generate after
+// changing the parser to update them.
+package highlight
+
+//go:generate go run gen.go
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/ear7h/lang/ast"
+	"github.com/ear7h/lang/ast/parser"
+	"github.com/ear7h/lang/resolve"
+)
+
+// Files are the generated files, by their path in this directory, and
+// the functions writing them
+var Files = []struct {
+	Name  string
+	Write func(w io.Writer) error
+}{
+	{"lang.tmLanguage.json", WriteTextMate},
+	{"tree-sitter/grammar.js", WriteTreeSitter},
+	{"tree-sitter/queries/highlights.scm", WriteTreeSitterHighlights},
+}
+
+// operators returns the unary and binary operators, longest first so
+// an alternation of them matches like the parser
+func operators() []string {
+	seen := map[string]bool{}
+	var ret []string
+	add := func(op string) {
+		if !seen[op] {
+			seen[op] = true
+			ret = append(ret, op)
+		}
+	}
+
+	for _, v := range ast.UnaryOperators() {
+		add(string(v))
+	}
+	for _, v := range ast.BinaryOperators() {
+		add(v)
+	}
+
+	return longestFirst(ret)
+}
+
+// punctuation returns the terminals of the grammar which are neither
+// keywords nor operators, longest first
+func punctuation() []string {
+	skip := map[string]bool{}
+	for _, v := range ast.Keywords() {
+		skip[v] = true
+	}
+	for _, v := range operators() {
+		skip[v] = true
+	}
+
+	seen := map[string]bool{}
+	var ret []string
+	var walk func(s *parser.Syntax)
+	walk = func(s *parser.Syntax) {
+		if s.Op == parser.SyntaxString && !skip[s.Text] && !seen[s.Text] {
+			seen[s.Text] = true
+			ret = append(ret, s.Text)
+		}
+
+		for _, v := range s.Args {
+			walk(v)
+		}
+	}
+
+	for _, v := range ast.Grammar().Rules {
+		walk(v.Syntax)
+	}
+
+	return longestFirst(ret)
+}
+
+func longestFirst(strs []string) []string {
+	sort.SliceStable(strs, func(i, j int) bool {
+		if len(strs[i]) != len(strs[j]) {
+			return len(strs[i]) > len(strs[j])
+		}
+
+		return strs[i] < strs[j]
+	})
+
+	return strs
+}
+
+// constants returns the predeclared constants, sorted
+func constants() []string {
+	var ret []string
+	for k, v := range resolve.Universe.Objects {
+		if v.Kind == resolve.Const {
+			ret = append(ret, k)
+		}
+	}
+
+	sort.Strings(ret)
+	return ret
+}
+
+// isBracket reports whether the punctuation p opens or closes a group
+func isBracket(p string) bool {
+	return p == "(" || p == ")" || p == "{" || p == "}"
+}
+
+// the regular expressions below are in the syntax Go, Oniguruma and
+// JavaScript have in common
+
+// class returns a character class matching the runes of cl
+func class(cl ast.RuneClass) string {
+	var b strings.Builder
+	b.WriteString("[")
+
+	for _, v := range cl.Tables {
+		b.WriteString(`\p{` + tableName(v) + `}`)
+	}
+
+	for _, v := range cl.Runes {
+		if strings.ContainsRune(`\]^-[`, v) {
+			b.WriteString(`\`)
+		}
+		b.WriteRune(v)
+	}
+
+	b.WriteString("]")
+	return b.String()
+}
+
+// tableName returns the name of the unicode category or script t, it
+// panics if t isn't one, as then the parser changed in a way the
+// generators don't know about
+func tableName(t *unicode.RangeTable) string {
+	for _, m := range []map[string]*unicode.RangeTable{
+		unicode.Categories,
+		unicode.Scripts,
+	} {
+		names := make([]string, 0, len(m))
+		for k := range m {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+
+		for _, k := range names {
+			if m[k] == t {
+				return k
+			}
+		}
+	}
+
+	panic(fmt.Sprintf("highlight: unnamed unicode table %p", t))
+}
+
+// identPattern matches identifiers, and keywords
+func identPattern() string {
+	start, tail := ast.IdentClasses()
+	return class(start) + class(tail) + "*"
+}
+
+// numberPattern matches number literals
+func numberPattern() string {
+	return class(ast.NumberClass()) + "+"
+}
+
+// escapePattern matches the escapes of string literals
+func escapePattern() string {
+	var runes []string
+	for k := range ast.StringEscapes() {
+		runes = append(runes, string(k))
+	}
+	sort.Strings(runes)
+
+	return `\\` + class(ast.RuneClass{Runes: []rune(strings.Join(runes, ""))})
+}
+
+// alt returns a group matching one of strs
+func alt(strs []string) string {
+	quoted := make([]string, len(strs))
+	for i, v := range strs {
+		quoted[i] = regexp.QuoteMeta(v)
+	}
+
+	return "(?:" + strings.Join(quoted, "|") + ")"
+}
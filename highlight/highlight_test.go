@@ -0,0 +1,140 @@
+package highlight_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/ear7h/lang/ast/token"
+	"github.com/ear7h/lang/highlight"
+	"github.com/ear7h/lang/internal/assert"
+)
+
+// TestGenerated checks the generated files are up to date, run go
+// generate to update them
+func TestGenerated(t *testing.T) {
+	for _, v := range highlight.Files {
+		v := v
+		t.Run(v.Name, func(t *testing.T) {
+			var b bytes.Buffer
+			err := v.Write(&b)
+			assert.Eq(t, nil, err)
+
+			file, err := os.ReadFile(v.Name)
+			assert.Eq(t, nil, err)
+			assert.Eq(t, string(file), b.String())
+		})
+	}
+}
+
+type tmRule struct {
+	Match    string   `json:"match"`
+	Begin    string   `json:"begin"`
+	End      string   `json:"end"`
+	Patterns []tmRule `json:"patterns"`
+}
+
+// matches returns the regular expressions of the rule, without the
+// lookaheads Go doesn't have
+func (r tmRule) matches() []*regexp.Regexp {
+	var ret []*regexp.Regexp
+	if r.Match != "" {
+		match, _, _ := strings.Cut(r.Match, "(?!")
+		ret = append(ret, regexp.MustCompile("^"+match))
+	}
+
+	for _, v := range r.Patterns {
+		ret = append(ret, v.matches()...)
+	}
+
+	return ret
+}
+
+// TestTextMate checks the patterns of the TextMate grammar match the
+// tokens the scanner does
+func TestTextMate(t *testing.T) {
+	type tcase struct {
+		rules []string
+	}
+
+	file, err := os.ReadFile("lang.tmLanguage.json")
+	assert.Eq(t, nil, err)
+
+	var g struct {
+		Repository map[string]tmRule `json:"repository"`
+	}
+	err = json.Unmarshal(file, &g)
+	assert.Eq(t, nil, err)
+
+	// strings are matched from begin to end, with the escapes between
+	str := g.Repository["strings"]
+	g.Repository["strings"] = tmRule{
+		Match: str.Begin + `(?:` + str.Patterns[0].Match + `|[^\\` +
+			str.End + `])*` + str.End,
+	}
+
+	src := "let f = fn(x, y) { return x*y + 1 }\n" +
+		"let s = \"a\\tb\\\\c\\n\" ; let é_2 = f(1, 23).b\n" +
+		"-a << 3 >> 1 & 2 | 3 ^ 4 % 5 / 6 - 7 + *b\n" +
+		"!true && false || 1 <= 2 >= 3 == 4 != 5 < 6 > &a\n" +
+		"letter + fnord + returned + true_ + Abc\n"
+
+	toks, err := token.Scan(src, "")
+	assert.Eq(t, nil, err)
+
+	fn := func(kind token.Kind, tc tcase) func(t *testing.T) {
+		return func(t *testing.T) {
+			var res []*regexp.Regexp
+			for _, v := range tc.rules {
+				rule, ok := g.Repository[v]
+				assert.Eq(t, true, ok)
+				res = append(res, rule.matches()...)
+			}
+
+			n := 0
+			for _, tok := range toks {
+				if tok.Kind != kind {
+					continue
+				}
+
+				n++
+				ok := false
+				for _, re := range res {
+					ok = ok || re.FindString(tok.Value) == tok.Value
+				}
+				if !ok {
+					t.Errorf("%s: %q doesn't match", tok.Fi, tok.Value)
+				}
+			}
+
+			if n == 0 {
+				t.Fatalf("no %s tokens", kind)
+			}
+		}
+	}
+
+	tcases := map[token.Kind]tcase{
+		token.Keyword: tcase{
+			rules: []string{"keywords"},
+		},
+		token.Ident: tcase{
+			rules: []string{"constants", "identifiers"},
+		},
+		token.Number: tcase{
+			rules: []string{"numbers"},
+		},
+		token.String: tcase{
+			rules: []string{"strings"},
+		},
+		token.Operator: tcase{
+			rules: []string{"operators", "punctuation"},
+		},
+	}
+
+	for k, v := range tcases {
+		t.Run(k.String(), fn(k, v))
+	}
+}
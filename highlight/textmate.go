@@ -0,0 +1,159 @@
+package highlight
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/ear7h/lang/ast"
+)
+
+type tmGrammar struct {
+	Comment    string            `json:"comment"`
+	Name       string            `json:"name"`
+	ScopeName  string            `json:"scopeName"`
+	FileTypes  []string          `json:"fileTypes"`
+	Patterns   []tmRule          `json:"patterns"`
+	Repository map[string]tmRule `json:"repository"`
+}
+
+type tmRule struct {
+	Include       string            `json:"include,omitempty"`
+	Name          string            `json:"name,omitempty"`
+	Match         string            `json:"match,omitempty"`
+	Begin         string            `json:"begin,omitempty"`
+	End           string            `json:"end,omitempty"`
+	BeginCaptures map[string]tmRule `json:"beginCaptures,omitempty"`
+	EndCaptures   map[string]tmRule `json:"endCaptures,omitempty"`
+	Patterns      []tmRule          `json:"patterns,omitempty"`
+}
+
+// keywordScopes are the scopes of the keywords, others are
+// keyword.other
+var keywordScopes = map[string]string{
+	ast.KeywordReturn: "keyword.control.return",
+	ast.KeywordLet:    "storage.type",
+	ast.KeywordFn:     "storage.type.function",
+}
+
+// punctuationScopes are the scopes of the punctuation, others are
+// punctuation.other
+var punctuationScopes = map[string]string{
+	"=": "keyword.operator.assignment",
+	";": "punctuation.terminator.statement",
+	",": "punctuation.separator",
+	".": "punctuation.accessor",
+	"(": "punctuation.section.parens.begin",
+	")": "punctuation.section.parens.end",
+	"{": "punctuation.section.block.begin",
+	"}": "punctuation.section.block.end",
+}
+
+// textMateRepository returns the rules of the TextMate grammar, by the
+// class of tokens they match
+func textMateRepository() map[string]tmRule {
+	_, tail := ast.IdentClasses()
+	word := func(words []string) string {
+		// a word isn't the start of a longer identifier
+		return alt(words) + "(?!" + class(tail) + ")"
+	}
+
+	var keywords []tmRule
+	for _, v := range ast.Keywords() {
+		scope, ok := keywordScopes[v]
+		if !ok {
+			scope = "keyword.other"
+		}
+
+		keywords = append(keywords, tmRule{
+			Name:  scope + ".lang",
+			Match: word([]string{v}),
+		})
+	}
+
+	var puncts []tmRule
+	for _, v := range punctuation() {
+		scope, ok := punctuationScopes[v]
+		if !ok {
+			scope = "punctuation.other"
+		}
+
+		puncts = append(puncts, tmRule{
+			Name:  scope + ".lang",
+			Match: alt([]string{v}),
+		})
+	}
+
+	return map[string]tmRule{
+		"keywords": {Patterns: keywords},
+		"constants": {
+			Name:  "constant.language.lang",
+			Match: word(constants()),
+		},
+		"identifiers": {
+			Name:  "variable.other.lang",
+			Match: identPattern(),
+		},
+		"numbers": {
+			Name:  "constant.numeric.integer.lang",
+			Match: numberPattern(),
+		},
+		"strings": {
+			Name:  "string.quoted.double.lang",
+			Begin: string(ast.StringQuote),
+			End:   string(ast.StringQuote),
+			BeginCaptures: map[string]tmRule{
+				"0": {Name: "punctuation.definition.string.begin.lang"},
+			},
+			EndCaptures: map[string]tmRule{
+				"0": {Name: "punctuation.definition.string.end.lang"},
+			},
+			Patterns: []tmRule{
+				{
+					Name:  "constant.character.escape.lang",
+					Match: escapePattern(),
+				},
+				{
+					// the parser rejects other escapes
+					Name:  "invalid.illegal.escape.lang",
+					Match: `\\.`,
+				},
+			},
+		},
+		"operators": {
+			Name:  "keyword.operator.lang",
+			Match: alt(operators()),
+		},
+		"punctuation": {Patterns: puncts},
+	}
+}
+
+// WriteTextMate writes the TextMate grammar, in JSON
+func WriteTextMate(w io.Writer) error {
+	g := tmGrammar{
+		Comment:    "Code generated by go generate in the highlight package; DO NOT EDIT.",
+		Name:       "lang",
+		ScopeName:  "source.lang",
+		FileTypes:  []string{"lang"},
+		Repository: textMateRepository(),
+	}
+
+	// of the rules matching where a token starts the first wins, so
+	// keywords and constants go before the identifiers they look like,
+	// and operators before the punctuation they start with
+	for _, v := range []string{
+		"keywords",
+		"constants",
+		"identifiers",
+		"numbers",
+		"strings",
+		"operators",
+		"punctuation",
+	} {
+		g.Patterns = append(g.Patterns, tmRule{Include: "#" + v})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "\t")
+	return enc.Encode(g)
+}
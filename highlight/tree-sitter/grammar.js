@@ -0,0 +1,140 @@
+// Code generated by go generate in the highlight package; DO NOT EDIT.
+
+const PREC = {
+  unary: 1,
+  binary0: 6, // >> << & | ^
+  binary1: 5, // * / %
+  binary2: 4, // + -
+  binary3: 3, // < > <= >= == !=
+  binary4: 2, // && ||
+  call: 7,
+};
+
+const BINARY = [
+  [PREC.binary0, ['>>', '<<', '&', '|', '^']],
+  [PREC.binary1, ['*', '/', '%']],
+  [PREC.binary2, ['+', '-']],
+  [PREC.binary3, ['<', '>', '<=', '>=', '==', '!=']],
+  [PREC.binary4, ['&&', '||']],
+];
+
+const UNARY = ['+', '-', '*', '&', '!'];
+
+function commaSep(rule) {
+  return optional(seq(rule, repeat(seq(',', rule))));
+}
+
+// statements are terminated like Go's, a newline is a terminator where
+// one is expected and white space elsewhere
+function statements($) {
+  return seq(
+    repeat(seq($._statement, $._terminator)),
+    optional($._statement),
+  );
+}
+
+module.exports = grammar({
+  name: 'lang',
+
+  extras: $ => [/\s/],
+
+  word: $ => $.identifier,
+
+  rules: {
+    source_file: $ => statements($),
+
+    _terminator: $ => choice(';', '\n'),
+
+    _statement: $ => choice(
+      $.let_statement,
+      $.return_statement,
+      $.block,
+      $.expression_statement,
+    ),
+
+    let_statement: $ => seq(
+      'let',
+      field('name', $.identifier),
+      '=',
+      field('value', $._expression),
+    ),
+
+    return_statement: $ => seq(
+      'return',
+      optional(field('value', $._expression)),
+    ),
+
+    block: $ => seq('{', statements($), '}'),
+
+    expression_statement: $ => $._expression,
+
+    _expression: $ => choice(
+      $.unary_expression,
+      $.binary_expression,
+      $._operand,
+    ),
+
+    unary_expression: $ => prec(PREC.unary, seq(
+      field('operator', choice(...UNARY)),
+      field('operand', $._expression),
+    )),
+
+    binary_expression: $ => choice(...BINARY.map(([p, ops]) =>
+      prec.left(p, seq(
+        field('left', $._binary_operand),
+        field('operator', choice(...ops)),
+        field('right', $._binary_operand),
+      )),
+    )),
+
+    _binary_operand: $ => choice($.binary_expression, $._operand),
+
+    _operand: $ => choice(
+      $.string,
+      $.number,
+      $.function_literal,
+      $.identifier,
+      $.parenthesized_expression,
+      $.call_expression,
+      $.selector_expression,
+    ),
+
+    parenthesized_expression: $ => seq('(', $._expression, ')'),
+
+    call_expression: $ => prec(PREC.call, seq(
+      field('function', $._operand),
+      field('arguments', $.arguments),
+    )),
+
+    arguments: $ => seq('(', commaSep($._expression), ')'),
+
+    selector_expression: $ => prec(PREC.call, seq(
+      field('operand', $._operand),
+      '.',
+      field('field', $.identifier),
+    )),
+
+    function_literal: $ => seq(
+      'fn',
+      field('parameters', $.parameters),
+      field('body', $.block),
+    ),
+
+    parameters: $ => seq('(', commaSep($.identifier), ')'),
+
+    identifier: $ => /[\p{Ll}\p{Lu}_][\p{Ll}\p{Lu}\p{Nd}_]*/,
+
+    number: $ => /[\p{Nd}]+/,
+
+    string: $ => seq(
+      '"',
+      repeat(choice(
+        token.immediate(prec(1, /[^"\\]+/)),
+        $.escape_sequence,
+      )),
+      token.immediate('"'),
+    ),
+
+    escape_sequence: $ => token.immediate(/\\[\\nt]/),
+  },
+});
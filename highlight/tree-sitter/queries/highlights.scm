@@ -0,0 +1,60 @@
+; Code generated by go generate in the highlight package; DO NOT EDIT.
+
+((identifier) @constant.builtin
+  (#match? @constant.builtin "^(false|true)$"))
+
+(let_statement
+  name: (identifier) @function
+  value: (function_literal))
+
+(call_expression function: (identifier) @function.call)
+
+(parameters (identifier) @variable.parameter)
+
+(selector_expression field: (identifier) @property)
+
+(identifier) @variable
+
+"return" @keyword.return
+"let" @keyword
+"fn" @keyword.function
+
+[
+  "!="
+  "&&"
+  "<<"
+  "<="
+  "=="
+  ">="
+  ">>"
+  "||"
+  "!"
+  "%"
+  "&"
+  "*"
+  "+"
+  "-"
+  "/"
+  "<"
+  ">"
+  "^"
+  "|"
+  "="
+] @operator
+
+[
+  "("
+  ")"
+  "{"
+  "}"
+] @punctuation.bracket
+
+[
+  ","
+  "."
+  ";"
+] @punctuation.delimiter
+
+(number) @number
+(string) @string
+(escape_sequence) @string.escape
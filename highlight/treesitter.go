@@ -0,0 +1,281 @@
+package highlight
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ear7h/lang/ast"
+)
+
+// js returns s as a JavaScript string
+func js(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `'`, `\'`)
+	return "'" + s + "'"
+}
+
+// jsList returns strs as JavaScript strings separated by sep
+func jsList(strs []string, sep string) string {
+	quoted := make([]string, len(strs))
+	for i, v := range strs {
+		quoted[i] = js(v)
+	}
+
+	return strings.Join(quoted, sep)
+}
+
+// binaryLevels returns the binary operators grouped by precedence, from
+// the highest
+func binaryLevels() [][]string {
+	var ret [][]string
+	last := -1
+	for _, v := range ast.BinaryOperators() {
+		p := ast.BinaryPrecedence(v)
+		if p != last {
+			ret = append(ret, nil)
+			last = p
+		}
+
+		ret[len(ret)-1] = append(ret[len(ret)-1], v)
+	}
+
+	return ret
+}
+
+// WriteTreeSitter writes the tree-sitter grammar, grammar.js
+//
+// Like the parser, a newline ends a statement where it could end, unary
+// operators only start expressions and bind looser than the binary
+// operators, so -a + b is -(a + b), and calls and field selections bind
+// tighter than both.
+func WriteTreeSitter(w io.Writer) error {
+	var b strings.Builder
+	p := func(format string, args ...interface{}) {
+		fmt.Fprintf(&b, format+"\n", args...)
+	}
+
+	levels := binaryLevels()
+	var unary []string
+	for _, v := range ast.UnaryOperators() {
+		unary = append(unary, string(v))
+	}
+
+	p("// Code generated by go generate in the highlight package; DO NOT EDIT.")
+	p("")
+	p("const PREC = {")
+	p("  unary: 1,")
+	for i, v := range levels {
+		p("  binary%d: %d, // %s", i, len(levels)-i+1, strings.Join(v, " "))
+	}
+	p("  call: %d,", len(levels)+2)
+	p("};")
+	p("")
+	p("const BINARY = [")
+	for i, v := range levels {
+		p("  [PREC.binary%d, [%s]],", i, jsList(v, ", "))
+	}
+	p("];")
+	p("")
+	p("const UNARY = [%s];", jsList(unary, ", "))
+	p("")
+	p("function commaSep(rule) {")
+	p("  return optional(seq(rule, repeat(seq(',', rule))));")
+	p("}")
+	p("")
+	p("// statements are terminated like Go's, a newline is a terminator where")
+	p("// one is expected and white space elsewhere")
+	p("function statements($) {")
+	p("  return seq(")
+	p("    repeat(seq($._statement, $._terminator)),")
+	p("    optional($._statement),")
+	p("  );")
+	p("}")
+	p("")
+	p("module.exports = grammar({")
+	p("  name: 'lang',")
+	p("")
+	p("  extras: $ => [/\\s/],")
+	p("")
+	p("  word: $ => $.identifier,")
+	p("")
+	p("  rules: {")
+	p("    source_file: $ => statements($),")
+	p("")
+	p("    _terminator: $ => choice(';', '\\n'),")
+	p("")
+	p("    _statement: $ => choice(")
+	p("      $.let_statement,")
+	p("      $.return_statement,")
+	p("      $.block,")
+	p("      $.expression_statement,")
+	p("    ),")
+	p("")
+	p("    let_statement: $ => seq(")
+	p("      %s,", js(ast.KeywordLet))
+	p("      field('name', $.identifier),")
+	p("      '=',")
+	p("      field('value', $._expression),")
+	p("    ),")
+	p("")
+	p("    return_statement: $ => seq(")
+	p("      %s,", js(ast.KeywordReturn))
+	p("      optional(field('value', $._expression)),")
+	p("    ),")
+	p("")
+	p("    block: $ => seq('{', statements($), '}'),")
+	p("")
+	p("    expression_statement: $ => $._expression,")
+	p("")
+	p("    _expression: $ => choice(")
+	p("      $.unary_expression,")
+	p("      $.binary_expression,")
+	p("      $._operand,")
+	p("    ),")
+	p("")
+	p("    unary_expression: $ => prec(PREC.unary, seq(")
+	p("      field('operator', choice(...UNARY)),")
+	p("      field('operand', $._expression),")
+	p("    )),")
+	p("")
+	p("    binary_expression: $ => choice(...BINARY.map(([p, ops]) =>")
+	p("      prec.left(p, seq(")
+	p("        field('left', $._binary_operand),")
+	p("        field('operator', choice(...ops)),")
+	p("        field('right', $._binary_operand),")
+	p("      )),")
+	p("    )),")
+	p("")
+	p("    _binary_operand: $ => choice($.binary_expression, $._operand),")
+	p("")
+	p("    _operand: $ => choice(")
+	p("      $.string,")
+	p("      $.number,")
+	p("      $.function_literal,")
+	p("      $.identifier,")
+	p("      $.parenthesized_expression,")
+	p("      $.call_expression,")
+	p("      $.selector_expression,")
+	p("    ),")
+	p("")
+	p("    parenthesized_expression: $ => seq('(', $._expression, ')'),")
+	p("")
+	p("    call_expression: $ => prec(PREC.call, seq(")
+	p("      field('function', $._operand),")
+	p("      field('arguments', $.arguments),")
+	p("    )),")
+	p("")
+	p("    arguments: $ => seq('(', commaSep($._expression), ')'),")
+	p("")
+	p("    selector_expression: $ => prec(PREC.call, seq(")
+	p("      field('operand', $._operand),")
+	p("      '.',")
+	p("      field('field', $.identifier),")
+	p("    )),")
+	p("")
+	p("    function_literal: $ => seq(")
+	p("      %s,", js(ast.KeywordFn))
+	p("      field('parameters', $.parameters),")
+	p("      field('body', $.block),")
+	p("    ),")
+	p("")
+	p("    parameters: $ => seq('(', commaSep($.identifier), ')'),")
+	p("")
+	p("    identifier: $ => /%s/,", identPattern())
+	p("")
+	p("    number: $ => /%s/,", numberPattern())
+	p("")
+	p("    string: $ => seq(")
+	p("      %s,", js(string(ast.StringQuote)))
+	p("      repeat(choice(")
+	p("        token.immediate(prec(1, /[^%s\\\\]+/)),", string(ast.StringQuote))
+	p("        $.escape_sequence,")
+	p("      )),")
+	p("      token.immediate(%s),", js(string(ast.StringQuote)))
+	p("    ),")
+	p("")
+	p("    escape_sequence: $ => token.immediate(/%s/),", escapePattern())
+	p("  },")
+	p("});")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// keywordCaptures are the captures of the keywords in the highlight
+// queries, others are keyword
+var keywordCaptures = map[string]string{
+	ast.KeywordReturn: "keyword.return",
+	ast.KeywordFn:     "keyword.function",
+}
+
+// WriteTreeSitterHighlights writes the highlight queries of the
+// tree-sitter grammar, queries/highlights.scm
+func WriteTreeSitterHighlights(w io.Writer) error {
+	var b strings.Builder
+	p := func(format string, args ...interface{}) {
+		fmt.Fprintf(&b, format+"\n", args...)
+	}
+
+	list := func(strs []string, capture string) {
+		if len(strs) == 0 {
+			return
+		}
+
+		p("[")
+		for _, v := range strs {
+			p("  %q", v)
+		}
+		p("] @%s", capture)
+		p("")
+	}
+
+	var brackets, delimiters []string
+	for _, v := range punctuation() {
+		switch {
+		case isBracket(v):
+			brackets = append(brackets, v)
+		case v == "=":
+			// assignment
+		default:
+			delimiters = append(delimiters, v)
+		}
+	}
+
+	p("; Code generated by go generate in the highlight package; DO NOT EDIT.")
+	p("")
+	// the first pattern matching a node wins
+	p("((identifier) @constant.builtin")
+	p("  (#match? @constant.builtin \"^(%s)$\"))", strings.Join(constants(), "|"))
+	p("")
+	p("(let_statement")
+	p("  name: (identifier) @function")
+	p("  value: (function_literal))")
+	p("")
+	p("(call_expression function: (identifier) @function.call)")
+	p("")
+	p("(parameters (identifier) @variable.parameter)")
+	p("")
+	p("(selector_expression field: (identifier) @property)")
+	p("")
+	p("(identifier) @variable")
+	p("")
+	for _, v := range ast.Keywords() {
+		capture, ok := keywordCaptures[v]
+		if !ok {
+			capture = "keyword"
+		}
+
+		p("%q @%s", v, capture)
+	}
+	p("")
+	list(append(operators(), "="), "operator")
+	list(brackets, "punctuation.bracket")
+	list(delimiters, "punctuation.delimiter")
+	p("(number) @number")
+	p("(string) @string")
+	p("(escape_sequence) @string.escape")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -1,31 +1,24 @@
-package parser_test
+// Package assert has the assertions shared by the tests. Values are
+// compared like reflect.DeepEqual, except the positions of ast nodes are
+// skipped, so expected nodes can be parsed from strings or written as
+// struct literals without positions.
+package assert
 
 import (
 	"errors"
 	"reflect"
+	"strings"
 	"testing"
 	"unsafe"
 
-	"github.com/ear7h/lang/ast/parser"
+	"github.com/ear7h/lang/ast"
 )
 
-func init() {
-	defaultFi := parser.NewCursorString("", "").FileInfo()
-
-	if reflect.DeepEqual(defaultFi, parser.FileInfo{}) {
-		// the default file info should not be the zero
-		// value. Firstly, it should be start on line 1
-		// col 1. Secondly, a non-zero value as the
-		// initial cursor FileInfo ensures that Parse
-		// is properly initalizing the file info
-		panic("default file info is zero value")
-	}
-}
-
-func assertEq(t *testing.T, expect, got interface{}) {
+// Eq fails the test if got isn't equal to expect
+func Eq(t testing.TB, expect, got interface{}) {
 	t.Helper()
 
-	if expect ==  nil || got == nil {
+	if expect == nil || got == nil {
 		if expect != got {
 			t.Fatalf("expected: %v (%[1]T)\ngot: %[2]v (%[2]T)", expect, got)
 		}
@@ -36,19 +29,29 @@ func assertEq(t *testing.T, expect, got interface{}) {
 	av := reflect.ValueOf(expect)
 	bv := reflect.ValueOf(got)
 
-	av.Type()
-	bv.Type()
-
 	if av.Type() != bv.Type() {
 		t.Fatalf("expected: %v (%[1]T)\ngot: %[2]v (%[2]T)", expect, got)
 	}
 
-	if !astDeepValueEqual(av, bv, make(map[visit]bool), 0) {
-		t.Fatalf("expected: %#v (%[1]T)\ngot: %#[2]v (%[2]T)", expect, got)
+	if !deepValueEqual(av, bv, make(map[visit]bool), 0) {
+		a, b := dumps(expect, got)
+		t.Fatalf("expected: %s\ngot: %s", a, b)
 	}
 }
 
-func assertErrIs(t *testing.T, expect, got error) {
+// DeepEq fails the test if got isn't equal to expect, including the
+// positions of ast nodes
+func DeepEq(t testing.TB, expect, got interface{}) {
+	t.Helper()
+
+	if !reflect.DeepEqual(expect, got) {
+		a, b := dumps(expect, got)
+		t.Fatalf("expected: %s\ngot: %s", a, b)
+	}
+}
+
+// ErrIs fails the test if errors.Is(expect, got) is false
+func ErrIs(t testing.TB, expect, got error) {
 	t.Helper()
 
 	if !errors.Is(expect, got) {
@@ -56,6 +59,24 @@ func assertErrIs(t *testing.T, expect, got error) {
 	}
 }
 
+// dumps returns readable dumps of expect and got. The positions are left
+// out, since the nodes are compared without them, unless the values
+// only differ in the positions which are compared.
+func dumps(expect, got interface{}) (string, string) {
+	dump := func(v interface{}, f ast.FieldFilter) string {
+		var b strings.Builder
+		ast.Fprint(&b, v, f)
+		return b.String()
+	}
+
+	a, b := dump(expect, ast.NoPositions), dump(got, ast.NoPositions)
+	if a == b {
+		a, b = dump(expect, nil), dump(got, nil)
+	}
+
+	return a, b
+}
+
 // the following was mostly taken from then Go
 // source tree, commit 872bbc
 
@@ -69,8 +90,9 @@ type visit struct {
 	typ reflect.Type
 }
 
-// astDeepValueEqual works like reflect.DeepEqual, but with
-func astDeepValueEqual(v1, v2 reflect.Value,
+// deepValueEqual works like reflect.DeepEqual, but skips the BaseNode
+// of ast nodes
+func deepValueEqual(v1, v2 reflect.Value,
 	visited map[visit]bool, depth int) bool {
 
 	if !v1.IsValid() || !v2.IsValid() {
@@ -130,7 +152,7 @@ func astDeepValueEqual(v1, v2 reflect.Value,
 	switch v1.Kind() {
 	case reflect.Array:
 		for i := 0; i < v1.Len(); i++ {
-			if !astDeepValueEqual(v1.Index(i), v2.Index(i), visited, depth+1) {
+			if !deepValueEqual(v1.Index(i), v2.Index(i), visited, depth+1) {
 				return false
 			}
 		}
@@ -148,7 +170,7 @@ func astDeepValueEqual(v1, v2 reflect.Value,
 			return true
 		}
 		for i := 0; i < v1.Len(); i++ {
-			if !astDeepValueEqual(v1.Index(i), v2.Index(i), visited, depth+1) {
+			if !deepValueEqual(v1.Index(i), v2.Index(i), visited, depth+1) {
 				return false
 			}
 		}
@@ -158,36 +180,22 @@ func astDeepValueEqual(v1, v2 reflect.Value,
 		if v1.IsNil() || v2.IsNil() {
 			return v1.IsNil() == v2.IsNil()
 		}
-		return astDeepValueEqual(v1.Elem(), v2.Elem(), visited, depth+1)
+		return deepValueEqual(v1.Elem(), v2.Elem(), visited, depth+1)
 
 	case reflect.Ptr:
 		if v1.Pointer() == v2.Pointer() {
 			return true
 		}
-		return astDeepValueEqual(v1.Elem(), v2.Elem(), visited, depth+1)
+		return deepValueEqual(v1.Elem(), v2.Elem(), visited, depth+1)
 
 	case reflect.Struct:
 		for i, n := 0, v1.NumField(); i < n; i++ {
-
-			// ear7h modification, skip file info
-			// in BaseNode. In the test suite the ast nodes
-			// are better created with existing functions
-			// rather than struct literals, ex:
-			/*
-				out: &ast.UnaryExpr{
-					Op: '+',
-					Operand: ast.MustParseString(
-						&ast.NumberLiteral{},
-						"123",
-					),
-				},
-			*/
-			if v1.Type().Name() == "BaseNode" &&
-				v1.Type().Field(i).Name == "Fi" {
+			// skip the positions in BaseNode
+			if v1.Type().Name() == "BaseNode" {
 				continue
 			}
 
-			if !astDeepValueEqual(v1.Field(i), v2.Field(i), visited, depth+1) {
+			if !deepValueEqual(v1.Field(i), v2.Field(i), visited, depth+1) {
 				return false
 			}
 		}
@@ -206,7 +214,7 @@ func astDeepValueEqual(v1, v2 reflect.Value,
 		for _, k := range v1.MapKeys() {
 			val1 := v1.MapIndex(k)
 			val2 := v2.MapIndex(k)
-			if !val1.IsValid() || !val2.IsValid() || !astDeepValueEqual(val1, val2, visited, depth+1) {
+			if !val1.IsValid() || !val2.IsValid() || !deepValueEqual(val1, val2, visited, depth+1) {
 				return false
 			}
 		}
@@ -0,0 +1,301 @@
+// Package resolve binds the identifiers of a file to the objects they
+// name.
+//
+// Files, functions and blocks each have a Scope. A let statement
+// declares its name in the enclosing scope after its value, so the value
+// refers to any outer variable of the same name, except that a function
+// literal value can refer to itself to recurse, though a variable only
+// used by its own function is still unused. Function parameters and
+// the statements of the body share the function's scope. The field
+// names of object expressions are not resolved, they depend on the
+// object.
+package resolve
+
+import (
+	"fmt"
+
+	"github.com/ear7h/lang/ast"
+	"github.com/ear7h/lang/ast/parser"
+	"github.com/ear7h/lang/diag"
+)
+
+// ObjKind is the kind of an object
+type ObjKind int
+
+const (
+	Bad   ObjKind = iota
+	Const         // a predeclared constant
+	Var           // a variable declared by let
+	Param         // a function parameter
+)
+
+var objKindNames = []string{
+	Bad:   "bad",
+	Const: "const",
+	Var:   "var",
+	Param: "param",
+}
+
+func (k ObjKind) String() string {
+	if k < 0 || int(k) >= len(objKindNames) {
+		return fmt.Sprintf("ObjKind(%d)", int(k))
+	}
+
+	return objKindNames[k]
+}
+
+// Object is a named variable, parameter or constant
+type Object struct {
+	Name string
+	Kind ObjKind
+	Decl *ast.Ident   // the declaring identifier, nil if predeclared
+	Uses []*ast.Ident // the uses, not kept for predeclared objects
+}
+
+// Pos returns the position of the declaration of obj, or the zero
+// position if it is predeclared
+func (obj *Object) Pos() parser.FileInfo {
+	if obj.Decl == nil {
+		return parser.FileInfo{}
+	}
+
+	return obj.Decl.FileInfo()
+}
+
+// Scope holds the objects declared in a file, function or block
+type Scope struct {
+	Parent   *Scope
+	Children []*Scope
+	Node     ast.Node // the file, function or block, nil for Universe
+	Objects  map[string]*Object
+}
+
+// NewScope returns a new scope for the node n, nested in parent
+func NewScope(parent *Scope, n ast.Node) *Scope {
+	s := &Scope{
+		Parent:  parent,
+		Node:    n,
+		Objects: map[string]*Object{},
+	}
+
+	if parent != nil {
+		parent.Children = append(parent.Children, s)
+	}
+
+	return s
+}
+
+// Lookup returns the object name is declared as in s, or nil
+func (s *Scope) Lookup(name string) *Object {
+	return s.Objects[name]
+}
+
+// LookupParent returns the object name is declared as in s or the
+// closest of its parents that declares it, and that scope
+func (s *Scope) LookupParent(name string) (*Scope, *Object) {
+	for ; s != nil; s = s.Parent {
+		if obj := s.Lookup(name); obj != nil {
+			return s, obj
+		}
+	}
+
+	return nil, nil
+}
+
+// Insert declares obj in s, unless an object of the same name already
+// is. It returns the object that was there, or nil.
+func (s *Scope) Insert(obj *Object) *Object {
+	if alt := s.Objects[obj.Name]; alt != nil {
+		return alt
+	}
+
+	s.Objects[obj.Name] = obj
+
+	return nil
+}
+
+// Universe is the outermost scope, of the predeclared objects
+var Universe = func() *Scope {
+	s := NewScope(nil, nil)
+	for _, v := range []string{"true", "false"} {
+		s.Insert(&Object{Name: v, Kind: Const})
+	}
+
+	return s
+}()
+
+// Info is the result of resolving a file
+type Info struct {
+	// the scopes of the file, function literals and blocks, the body
+	// of a function literal maps to the scope of the function
+	Scopes map[ast.Node]*Scope
+
+	// the objects declared by identifiers
+	Defs map[*ast.Ident]*Object
+
+	// the objects identifiers refer to
+	Uses map[*ast.Ident]*Object
+}
+
+// ObjectOf returns the object id declares or refers to, or nil
+func (info *Info) ObjectOf(id *ast.Ident) *Object {
+	if obj := info.Defs[id]; obj != nil {
+		return obj
+	}
+
+	return info.Uses[id]
+}
+
+// Resolve resolves the identifiers of the file f. The error is a
+// diag.List of the undefined names, redeclarations and unused
+// variables, sorted by position. The variables declared at the top
+// level of the file may be left unused.
+func Resolve(f *ast.File) (*Info, error) {
+	r := &resolver{
+		info: &Info{
+			Scopes: map[ast.Node]*Scope{},
+			Defs:   map[*ast.Ident]*Object{},
+			Uses:   map[*ast.Ident]*Object{},
+		},
+		selfUses: map[*Object]int{},
+	}
+
+	// the universe is shared, it doesn't keep the file scopes
+	r.scope = &Scope{Parent: Universe, Node: f, Objects: map[string]*Object{}}
+	r.info.Scopes[f] = r.scope
+	r.stmts(f.Stmts)
+
+	r.errs.Sort()
+
+	return r.info, r.errs.Err()
+}
+
+type resolver struct {
+	info  *Info
+	scope *Scope
+	errs  diag.List
+
+	// the uses of function variables from their own bodies, which don't
+	// count as uses
+	selfUses map[*Object]int
+}
+
+func (r *resolver) errorf(n ast.Node, format string, args ...interface{}) *diag.Diagnostic {
+	d := diag.Errorf(n, format, args...)
+	r.errs = append(r.errs, d)
+	return d
+}
+
+// names returns the names of the objects in the current scope and its
+// parents
+func (r *resolver) names() []string {
+	var names []string
+	for s := r.scope; s != nil; s = s.Parent {
+		for k := range s.Objects {
+			names = append(names, k)
+		}
+	}
+
+	return names
+}
+
+// open opens the scope of n
+func (r *resolver) open(n ast.Node) {
+	r.scope = NewScope(r.scope, n)
+	r.info.Scopes[n] = r.scope
+}
+
+// close closes the current scope, reporting its unused variables
+func (r *resolver) close() {
+	for _, v := range r.scope.Objects {
+		if v.Kind == Var && len(v.Uses) == r.selfUses[v] {
+			r.errorf(v.Decl, "%s declared and not used", v.Name)
+		}
+	}
+
+	r.scope = r.scope.Parent
+}
+
+func (r *resolver) declare(id *ast.Ident, kind ObjKind) *Object {
+	obj := &Object{Name: id.Name, Kind: kind, Decl: id}
+	r.info.Defs[id] = obj
+
+	if alt := r.scope.Insert(obj); alt != nil {
+		d := r.errorf(id, "%s redeclared in this scope", id.Name)
+		if alt.Decl != nil {
+			d.Notes = append(d.Notes, diag.Note{
+				Span:    diag.NodeSpan(alt.Decl),
+				Message: "previous declaration",
+			})
+		}
+	}
+
+	return obj
+}
+
+func (r *resolver) stmts(stmts []interface{}) {
+	for _, v := range stmts {
+		r.stmt(v)
+	}
+}
+
+func (r *resolver) stmt(n interface{}) {
+	switch n := n.(type) {
+	case *ast.ExprStmt:
+		r.expr(n.X)
+	case *ast.ReturnStmt:
+		r.expr(n.X)
+	case *ast.LetStmt:
+		if _, ok := n.X.(*ast.FuncLit); ok {
+			obj := r.declare(n.Name, Var)
+			uses := len(obj.Uses)
+			r.expr(n.X)
+			r.selfUses[obj] = len(obj.Uses) - uses
+		} else {
+			r.expr(n.X)
+			r.declare(n.Name, Var)
+		}
+	case *ast.BlockStmt:
+		r.open(n)
+		r.stmts(n.Stmts)
+		r.close()
+	}
+}
+
+func (r *resolver) expr(n interface{}) {
+	switch n := n.(type) {
+	case *ast.Ident:
+		s, obj := r.scope.LookupParent(n.Name)
+		if obj == nil {
+			d := r.errorf(n, "undefined: %s", n.Name)
+			d.Suggestions = diag.DidYouMean(n, r.names())
+			return
+		}
+
+		// the universe is shared by all files
+		if s != Universe {
+			obj.Uses = append(obj.Uses, n)
+		}
+		r.info.Uses[n] = obj
+	case *ast.UnaryExpr:
+		r.expr(n.Operand)
+	case *ast.BinaryExpr:
+		r.expr(n.Left)
+		r.expr(n.Right)
+	case *ast.ObjExpr:
+		r.expr(n.Object)
+		for v := n; v != nil; v, _ = v.Right.(*ast.ObjExpr) {
+			for _, arg := range v.Args {
+				r.expr(arg)
+			}
+		}
+	case *ast.FuncLit:
+		r.open(n)
+		r.info.Scopes[n.Body] = r.scope
+		for _, v := range n.Params {
+			r.declare(v, Param)
+		}
+		r.stmts(n.Body.Stmts)
+		r.close()
+	}
+}
@@ -0,0 +1,226 @@
+package resolve_test
+
+import (
+	"testing"
+
+	"github.com/ear7h/lang/ast"
+	"github.com/ear7h/lang/ast/parser"
+	"github.com/ear7h/lang/diag"
+	"github.com/ear7h/lang/internal/assert"
+	"github.com/ear7h/lang/resolve"
+)
+
+func TestResolve(t *testing.T) {
+	type tcase struct {
+		str  string
+		errs []string
+	}
+
+	fn := func(tc tcase) func(t *testing.T) {
+		return func(t *testing.T) {
+			f, err := ast.ParseFile("test", tc.str)
+			assert.Eq(t, nil, err)
+
+			_, err = resolve.Resolve(f)
+			if tc.errs == nil {
+				assert.Eq(t, nil, err)
+				return
+			}
+
+			var errs []string
+			for _, v := range err.(diag.List) {
+				errs = append(errs, v.Error())
+			}
+
+			assert.Eq(t, tc.errs, errs)
+		}
+	}
+
+	tcases := map[string]tcase{
+		"empty": tcase{
+			str: "",
+		},
+		"predeclared": tcase{
+			str: "true && false",
+		},
+		"let": tcase{
+			str: "let a = 1\na + 1",
+		},
+		"unused top level": tcase{
+			str: "let a = 1",
+		},
+		"undefined": tcase{
+			str: "a + b",
+			errs: []string{
+				"test:1:1: undefined: a",
+				"test:1:5: undefined: b",
+			},
+		},
+		"use before let": tcase{
+			str: "a\nlet a = 1",
+			errs: []string{
+				"test:1:1: undefined: a",
+			},
+		},
+		"let refers to outer": tcase{
+			str: "let a = 1\n{\n\tlet a = a + 1\n\ta\n}",
+		},
+		"let refers to itself": tcase{
+			str: "let a = a",
+			errs: []string{
+				"test:1:9: undefined: a",
+			},
+		},
+		"redeclared": tcase{
+			str: "let a = 1\nlet a = 2",
+			errs: []string{
+				"test:2:5: a redeclared in this scope\n" +
+					"\tprevious declaration at test:1:5",
+			},
+		},
+		"shadowing": tcase{
+			str: "let a = 1\n{ let a = 2; a }\nfn(a) { a }",
+		},
+		"unused in block": tcase{
+			str: "{\n\tlet b = 1\n\tlet a = 2\n}",
+			errs: []string{
+				"test:2:6: b declared and not used",
+				"test:3:6: a declared and not used",
+			},
+		},
+		"unused in func": tcase{
+			str: "fn() { let a = 1 }",
+			errs: []string{
+				"test:1:12: a declared and not used",
+			},
+		},
+		"unused param": tcase{
+			str: "fn(a, b) { a }",
+		},
+		"duplicate param": tcase{
+			str: "fn(a, a) { a }",
+			errs: []string{
+				"test:1:7: a redeclared in this scope\n" +
+					"\tprevious declaration at test:1:4",
+			},
+		},
+		"param redeclared in body": tcase{
+			str: "fn(a) { let a = 1; a }",
+			errs: []string{
+				"test:1:13: a redeclared in this scope\n" +
+					"\tprevious declaration at test:1:4",
+			},
+		},
+		"recursion": tcase{
+			str: "let f = fn(n) { return f(n - 1) }",
+		},
+		"recursion unused": tcase{
+			str: "{\n\tlet f = fn(n) { f(n) }\n}",
+			errs: []string{
+				"test:2:6: f declared and not used",
+			},
+		},
+		"recursion used": tcase{
+			str: "{\n\tlet f = fn(n) { f(n) }\n\tf(1)\n}",
+		},
+		"recursion in nested func unused": tcase{
+			str: "{\n\tlet f = fn() { let g = fn() { f() }; g() }\n}",
+			errs: []string{
+				"test:2:6: f declared and not used",
+			},
+		},
+		"block scope ends": tcase{
+			str: "{ let a = 1; a }\na",
+			errs: []string{
+				"test:2:1: undefined: a",
+			},
+		},
+		"closure": tcase{
+			str: "let add = fn(a) { return fn(b) { return a + b } }\nadd(1)(2)",
+		},
+		"calls": tcase{
+			str: "f(a, b.c)",
+			errs: []string{
+				"test:1:1: undefined: f",
+				"test:1:3: undefined: a",
+				"test:1:6: undefined: b",
+			},
+		},
+		"fields": tcase{
+			str: "let a = 1\na.b.c",
+		},
+	}
+
+	for k, v := range tcases {
+		t.Run(k, fn(v))
+	}
+}
+
+func TestResolveInfo(t *testing.T) {
+	f, err := ast.ParseFile("test", "let f = fn(a) { return f(a) }\nf(true)")
+	assert.Eq(t, nil, err)
+
+	info, err := resolve.Resolve(f)
+	assert.Eq(t, nil, err)
+
+	let := f.Stmts[0].(*ast.LetStmt)
+	lit := let.X.(*ast.FuncLit)
+	ret := lit.Body.Stmts[0].(*ast.ReturnStmt).X.(*ast.ObjExpr)
+	call := f.Stmts[1].(*ast.ExprStmt).X.(*ast.ObjExpr)
+
+	obj := info.Defs[let.Name]
+	assert.Eq(t, resolve.Var, obj.Kind)
+	assert.Eq(t, "var", obj.Kind.String())
+	assert.Eq(t, true, info.Uses[ret.Object.(*ast.Ident)] == obj)
+	assert.Eq(t, true, info.Uses[call.Object.(*ast.Ident)] == obj)
+	assert.Eq(t, 2, len(obj.Uses))
+
+	param := info.Defs[lit.Params[0]]
+	assert.Eq(t, resolve.Param, param.Kind)
+	assert.Eq(t, true, info.ObjectOf(ret.Args[0].(*ast.Ident)) == param)
+
+	tru := info.Uses[call.Args[0].(*ast.Ident)]
+	assert.Eq(t, resolve.Const, tru.Kind)
+	assert.Eq(t, true, resolve.Universe.Lookup("true") == tru)
+	assert.Eq(t, 0, len(tru.Uses))
+
+	fileScope := info.Scopes[f]
+	assert.Eq(t, true, fileScope.Parent == resolve.Universe)
+	assert.Eq(t, true, fileScope.Lookup("f") == obj)
+	assert.Eq(t, true, info.Scopes[lit] == info.Scopes[lit.Body])
+	assert.Eq(t, true, info.Scopes[lit].Parent == fileScope)
+
+	scope, got := info.Scopes[lit].LookupParent("f")
+	assert.Eq(t, true, scope == fileScope)
+	assert.Eq(t, true, got == obj)
+}
+
+func TestResolveDiagnostics(t *testing.T) {
+	fi := func(line, col int64) parser.FileInfo {
+		return parser.FileInfo{Name: "test", Line: line, Col: col}
+	}
+
+	f, err := ast.ParseFile("test", "let count = 1\nlet count = cout + 1")
+	assert.Eq(t, nil, err)
+
+	_, err = resolve.Resolve(f)
+	assert.Eq(t, diag.List{
+		{
+			Span:    diag.Span{Start: fi(2, 5), End: fi(2, 10)},
+			Message: "count redeclared in this scope",
+			Notes: []diag.Note{{
+				Span:    diag.Span{Start: fi(1, 5), End: fi(1, 10)},
+				Message: "previous declaration",
+			}},
+		},
+		{
+			Span:    diag.Span{Start: fi(2, 13), End: fi(2, 17)},
+			Message: "undefined: cout",
+			Suggestions: []diag.Suggestion{{
+				Span:    diag.Span{Start: fi(2, 13), End: fi(2, 17)},
+				Message: "did you mean count?",
+				Text:    "count",
+			}},
+		},
+	}, err)
+}
@@ -0,0 +1,449 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ear7h/lang/ast"
+	"github.com/ear7h/lang/diag"
+	"github.com/ear7h/lang/format"
+)
+
+// Check type checks the tree rooted at n, a file, statement or
+// expression, and returns the types of its expressions and of the
+// identifiers declared by let statements and function parameters. The
+// env gives the types of the free identifiers other than true and
+// false. The error is a diag.List of the type errors,
+// expressions with errors have the Invalid type and don't cause more
+// errors. Bad nodes are skipped, the parser reported them.
+func Check(n ast.Node, env map[string]Type) (map[ast.Node]Type, error) {
+	c := &checker{
+		types: map[ast.Node]Type{},
+		scope: &scope{names: map[string]*scheme{
+			"true":  {t: Typ[Bool]},
+			"false": {t: Typ[Bool]},
+		}},
+	}
+
+	for k, v := range env {
+		c.scope.names[k] = &scheme{t: v}
+	}
+
+	c.node(n)
+
+	for k, v := range c.types {
+		c.types[k] = resolve(v)
+	}
+
+	return c.types, c.errs.Err()
+}
+
+type scope struct {
+	parent *scope
+	names  map[string]*scheme
+}
+
+func (s *scope) lookup(name string) *scheme {
+	for ; s != nil; s = s.parent {
+		if t, ok := s.names[name]; ok {
+			return t
+		}
+	}
+
+	return nil
+}
+
+// all returns the names in s and its parents
+func (s *scope) all() []string {
+	var names []string
+	for ; s != nil; s = s.parent {
+		for k := range s.names {
+			names = append(names, k)
+		}
+	}
+
+	return names
+}
+
+type checker struct {
+	types map[ast.Node]Type
+	scope *scope
+	errs  diag.List
+	vars  int // the number of type variables made
+
+	// the result type of the function being checked, nil at the top
+	// level, and the number of its return statements so far
+	result  Type
+	returns int
+}
+
+func (c *checker) errorf(n ast.Node, format string, args ...interface{}) *diag.Diagnostic {
+	d := diag.Errorf(n, format, args...)
+	c.errs = append(c.errs, d)
+	return d
+}
+
+// report reports the failed unification err, with the message format
+// saying what was being checked
+func (c *checker) report(n ast.Node, err *unifyError, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	if err.msg != "" {
+		msg += " (" + err.msg + ")"
+	}
+
+	c.errorf(n, "%s", msg).Notes = err.notes
+}
+
+func (c *checker) openScope() {
+	c.scope = &scope{parent: c.scope, names: map[string]*scheme{}}
+}
+
+func (c *checker) closeScope() {
+	c.scope = c.scope.parent
+}
+
+func (c *checker) node(n ast.Node) {
+	switch n := n.(type) {
+	case *ast.File:
+		c.stmts(n.Stmts)
+	case *ast.ExprStmt, *ast.ReturnStmt, *ast.LetStmt, *ast.BlockStmt,
+		*ast.BadStmt:
+		c.stmt(n)
+	default:
+		c.expr(n)
+	}
+}
+
+func (c *checker) stmts(stmts []interface{}) {
+	for _, v := range stmts {
+		c.stmt(v)
+	}
+}
+
+func (c *checker) stmt(n interface{}) {
+	switch n := n.(type) {
+	case *ast.ExprStmt:
+		c.expr(n.X)
+	case *ast.ReturnStmt:
+		c.returnStmt(n)
+	case *ast.LetStmt:
+		c.let(n)
+	case *ast.BlockStmt:
+		c.openScope()
+		c.stmts(n.Stmts)
+		c.closeScope()
+	}
+}
+
+func (c *checker) returnStmt(n *ast.ReturnStmt) {
+	var t Type = Typ[Unit]
+	if n.X != nil {
+		t = c.expr(n.X)
+	}
+
+	if c.result == nil {
+		return
+	}
+
+	c.returns++
+
+	want := display(c.result)
+	if n.X == nil {
+		if err := c.unify(n, c.result, t); err != nil {
+			c.report(n, err, "missing return value, want %s", want)
+		}
+
+		return
+	}
+
+	x := n.X.(ast.Node)
+	got := display(t)
+	if err := c.unify(x, c.result, t); err != nil {
+		c.report(x, err, "cannot use %s (%s) as %s value in return statement",
+			exprString(x), got, want)
+	}
+}
+
+func (c *checker) let(n *ast.LetStmt) {
+	name := n.Name.Name
+
+	var t Type
+	if lit, ok := n.X.(*ast.FuncLit); ok {
+		// the function can call itself, at the one type it has in its
+		// own body
+		self := c.fresh(n.Name)
+		c.scope.names[name] = &scheme{t: self}
+
+		t = c.expr(lit)
+
+		used := display(self)
+		if err := c.unify(lit, self, t); err != nil {
+			c.report(n.Name, err, "invalid recursive use of %s "+
+				"(used as %s, defined as %s)", name, used, display(t))
+		}
+	} else {
+		t = c.expr(n.X)
+	}
+
+	// the name being declared isn't part of the environment
+	delete(c.scope.names, name)
+	c.scope.names[name] = c.generalize(t)
+	c.types[n.Name] = t
+}
+
+// expr records and returns the type of the expression n
+func (c *checker) expr(n interface{}) Type {
+	t := c.exprType(n)
+	if node, ok := n.(ast.Node); ok {
+		c.types[node] = t
+	}
+
+	return t
+}
+
+func (c *checker) exprType(n interface{}) Type {
+	invalid := Typ[Invalid]
+
+	switch n := n.(type) {
+	case *ast.NumberLiteral:
+		return Typ[Int]
+	case *ast.StringLiteral:
+		return Typ[String]
+	case *ast.Ident:
+		s := c.scope.lookup(n.Name)
+		if s == nil {
+			d := c.errorf(n, "undefined: %s", n.Name)
+			d.Suggestions = diag.DidYouMean(n, c.scope.all())
+			return invalid
+		}
+
+		return c.instantiate(s, n)
+	case *ast.UnaryExpr:
+		return c.unary(n)
+	case *ast.BinaryExpr:
+		return c.binary(n)
+	case *ast.ObjExpr:
+		return c.obj(n)
+	case *ast.FuncLit:
+		return c.funcLit(n)
+	}
+
+	// bad expressions
+	return invalid
+}
+
+func (c *checker) funcLit(n *ast.FuncLit) Type {
+	c.openScope()
+	defer c.closeScope()
+
+	params := make([]Type, len(n.Params))
+	for i, v := range n.Params {
+		t := c.fresh(v)
+		params[i] = t
+		c.scope.names[v.Name] = &scheme{t: t}
+		c.types[v] = t
+	}
+
+	result, returns := c.result, c.returns
+	defer func() {
+		c.result, c.returns = result, returns
+	}()
+
+	c.result, c.returns = c.fresh(nil), 0
+	c.stmts(n.Body.Stmts)
+	if c.returns == 0 {
+		c.unify(n, c.result, Typ[Unit])
+	}
+
+	return &Func{Params: params, Result: c.result}
+}
+
+func (c *checker) unary(n *ast.UnaryExpr) Type {
+	invalid := Typ[Invalid]
+
+	x := c.expr(n.Operand)
+	if isInvalid(x) {
+		return invalid
+	}
+
+	var cl *class
+
+	switch n.Op {
+	case ast.UnaryPos, ast.UnaryNeg:
+		cl = numeric
+	case ast.UnaryNot:
+		cl = boolOnly
+	case ast.UnaryDeref:
+		got := display(x)
+		elem := c.fresh(nil)
+		if err := c.unify(n, x, &Pointer{Elem: elem}); err != nil {
+			c.report(n, err, "invalid operation: cannot indirect %s (%s)",
+				exprString(n.Operand), got)
+			return invalid
+		}
+
+		return elem
+	case ast.UnaryAddr:
+		if _, ok := n.Operand.(*ast.Ident); ok {
+			return &Pointer{Elem: x}
+		}
+
+		c.errorf(n, "invalid operation: cannot take address of %s (%s)",
+			exprString(n.Operand), display(x))
+		return invalid
+	}
+
+	got := display(x)
+	if err := c.restrict(n, x, cl); err != nil {
+		c.report(n, err, "invalid operation: operator %c not defined on %s (%s)",
+			n.Op, exprString(n.Operand), got)
+		return invalid
+	}
+
+	return x
+}
+
+func (c *checker) binary(n *ast.BinaryExpr) Type {
+	invalid := Typ[Invalid]
+
+	x, y := c.expr(n.Left), c.expr(n.Right)
+	if isInvalid(x) || isInvalid(y) {
+		return invalid
+	}
+
+	dx, dy := display(x), display(y)
+	if err := c.unify(n, x, y); err != nil {
+		c.report(n, err, "invalid operation: %s (mismatched types %s and %s)",
+			exprString(n), dx, dy)
+		return invalid
+	}
+
+	var cl *class
+	result := x
+
+	switch n.Op {
+	case ast.BinaryAdd:
+		cl = ordered
+	case ast.BinarySub, ast.BinaryMul, ast.BinaryDiv:
+		cl = numeric
+	case ast.BinaryMod,
+		ast.BinaryShr, ast.BinaryShl,
+		ast.BinaryBitAnd, ast.BinaryBitOr, ast.BinaryBitXor:
+		cl = intOnly
+	case ast.BinaryBoolAnd, ast.BinaryBoolOr:
+		cl = boolOnly
+	case ast.BinaryLt, ast.BinaryGt, ast.BinaryLte, ast.BinaryGte:
+		cl = ordered
+		result = Typ[Bool]
+	case ast.BinaryEq, ast.BinaryNeq:
+		// all the types are comparable
+		result = Typ[Bool]
+	}
+
+	if cl != nil {
+		got := display(x)
+		if err := c.restrict(n, x, cl); err != nil {
+			c.report(n, err, "invalid operation: operator %s not defined on %s (%s)",
+				n.Op, exprString(n.Left), got)
+			return invalid
+		}
+	}
+
+	return result
+}
+
+// obj checks a chain of field selections and calls, none of the types
+// have fields
+func (c *checker) obj(n *ast.ObjExpr) Type {
+	invalid := Typ[Invalid]
+
+	t := c.expr(n.Object)
+	callee := exprString(n.Object)
+
+	for v := n; v != nil; v, _ = v.Right.(*ast.ObjExpr) {
+		if v.Op == ast.ObjCall {
+			t = c.call(v, callee, t)
+
+			args := make([]string, len(v.Args))
+			for i, arg := range v.Args {
+				args[i] = exprString(arg)
+			}
+			callee += "(" + strings.Join(args, ", ") + ")"
+
+			continue
+		}
+
+		if !isInvalid(t) {
+			c.errorf(v, "%s.%s undefined (type %s has no field %[2]s)",
+				callee, exprString(v.Arg), display(t))
+		}
+
+		t = invalid
+		callee += "." + exprString(v.Arg)
+	}
+
+	return t
+}
+
+// call checks the call n of callee, a function of type t
+func (c *checker) call(n *ast.ObjExpr, callee string, t Type) Type {
+	invalid := Typ[Invalid]
+
+	args := make([]Type, len(n.Args))
+	for i, v := range n.Args {
+		args[i] = c.expr(v)
+	}
+
+	if isInvalid(t) {
+		return invalid
+	}
+
+	got := display(t)
+
+	fn, ok := prune(t).(*Func)
+	if v, isVar := prune(t).(*Var); isVar && v.class == nil {
+		// calling an unknown function gives it a type
+		params := make([]Type, len(n.Args))
+		for i, arg := range n.Args {
+			params[i] = c.fresh(arg.(ast.Node))
+		}
+
+		fn = &Func{Params: params, Result: c.fresh(nil)}
+		c.unify(n, v, fn)
+		ok = true
+	}
+
+	if !ok {
+		c.errorf(n, "invalid operation: cannot call non-function %s (%s)",
+			callee, got)
+		return invalid
+	}
+
+	if len(fn.Params) != len(args) {
+		c.errorf(n, "wrong number of arguments in call to %s: have %d, want %d",
+			callee, len(args), len(fn.Params))
+		return invalid
+	}
+
+	for i, v := range n.Args {
+		arg := v.(ast.Node)
+		got, want := display(args[i]), display(fn.Params[i])
+		if err := c.unify(arg, fn.Params[i], args[i]); err != nil {
+			c.report(arg, err, "cannot use %s (%s) as %s value in argument to %s",
+				exprString(arg), got, want, callee)
+		}
+	}
+
+	return fn.Result
+}
+
+// exprString returns the source of the expression n for error messages
+func exprString(n interface{}) string {
+	var b strings.Builder
+	err := format.Node(&b, n)
+	if err != nil {
+		return "?"
+	}
+
+	return b.String()
+}
@@ -0,0 +1,458 @@
+package types_test
+
+import (
+	"testing"
+
+	"github.com/ear7h/lang/ast"
+	"github.com/ear7h/lang/ast/parser"
+	"github.com/ear7h/lang/diag"
+	"github.com/ear7h/lang/internal/assert"
+	"github.com/ear7h/lang/types"
+)
+
+func TestCheck(t *testing.T) {
+	type tcase struct {
+		str  string
+		env  map[string]types.Type
+		typ  string // type of the last expression statement
+		errs []string
+	}
+
+	fn := func(tc tcase) func(t *testing.T) {
+		return func(t *testing.T) {
+			f, err := ast.ParseFile("test", tc.str)
+			assert.Eq(t, nil, err)
+
+			m, err := types.Check(f, tc.env)
+
+			var errs []string
+			if err != nil {
+				for _, v := range err.(diag.List) {
+					errs = append(errs, v.Error())
+				}
+			}
+			assert.Eq(t, tc.errs, errs)
+
+			if tc.typ != "" {
+				last := f.Stmts[len(f.Stmts)-1].(*ast.ExprStmt)
+				assert.Eq(t, tc.typ, m[last.X.(ast.Node)].String())
+			}
+		}
+	}
+
+	float := types.Typ[types.Float]
+	intPtr := &types.Pointer{Elem: types.Typ[types.Int]}
+
+	tcases := map[string]tcase{
+		"number": tcase{
+			str: "1",
+			typ: "int",
+		},
+		"string": tcase{
+			str: `"a"`,
+			typ: "string",
+		},
+		"bool": tcase{
+			str: "true",
+			typ: "bool",
+		},
+		"env": tcase{
+			str: "x",
+			env: map[string]types.Type{"x": float},
+			typ: "float",
+		},
+		"arith": tcase{
+			str: "1 + 2 * 3 / 4 - 5 % 6",
+			typ: "int",
+		},
+		"float arith": tcase{
+			str: "x * x - x / x",
+			env: map[string]types.Type{"x": float},
+			typ: "float",
+		},
+		"string concat": tcase{
+			str: `"a" + "b"`,
+			typ: "string",
+		},
+		"bits": tcase{
+			str: "1 << 2 | 3 & 4 ^ 5 >> 6",
+			typ: "int",
+		},
+		"cmp": tcase{
+			str: `1 < 2 && "a" >= "b" || true == false`,
+			typ: "bool",
+		},
+		"unary": tcase{
+			str: "-(1 + (+2))",
+			typ: "int",
+		},
+		"not": tcase{
+			str: "!(1 != 2)",
+			typ: "bool",
+		},
+		"address": tcase{
+			str: "&x",
+			env: map[string]types.Type{"x": types.Typ[types.Int]},
+			typ: "*int",
+		},
+		"deref": tcase{
+			str: "(*p) + 1",
+			env: map[string]types.Type{"p": intPtr},
+			typ: "int",
+		},
+		"pointer equality": tcase{
+			str: "p == (&x)",
+			env: map[string]types.Type{
+				"p": intPtr,
+				"x": types.Typ[types.Int],
+			},
+			typ: "bool",
+		},
+		"let": tcase{
+			str: `let a = "a"` + "\na + a",
+			typ: "string",
+		},
+		"block scope": tcase{
+			str: "let a = 1\n{ let a = true; !a }\na",
+			typ: "int",
+		},
+		"mismatched": tcase{
+			str: `"a" + 1`,
+			errs: []string{
+				`test:1:1: invalid operation: "a" + 1 ` +
+					`(mismatched types string and int)`,
+			},
+		},
+		"int and float": tcase{
+			str: "1 + x",
+			env: map[string]types.Type{"x": float},
+			errs: []string{
+				"test:1:1: invalid operation: 1 + x " +
+					"(mismatched types int and float)",
+			},
+		},
+		"operator not defined": tcase{
+			str: `"a" - "b"; x % x; true + true; 1 && 2`,
+			env: map[string]types.Type{"x": float},
+			errs: []string{
+				`test:1:1: invalid operation: operator - not defined on "a" (string)`,
+				`test:1:12: invalid operation: operator % not defined on x (float)`,
+				`test:1:19: invalid operation: operator + not defined on true (bool)`,
+				`test:1:32: invalid operation: operator && not defined on 1 (int)`,
+			},
+		},
+		"float bits": tcase{
+			str: "x << x",
+			env: map[string]types.Type{"x": float},
+			errs: []string{
+				"test:1:1: invalid operation: operator << not defined on x (float)",
+			},
+		},
+		"unordered": tcase{
+			str: "true < false",
+			errs: []string{
+				"test:1:1: invalid operation: operator < not defined on true (bool)",
+			},
+		},
+		"unary not defined": tcase{
+			str: `-"a"; !1`,
+			errs: []string{
+				`test:1:1: invalid operation: operator - not defined on "a" (string)`,
+				`test:1:7: invalid operation: operator ! not defined on 1 (int)`,
+			},
+		},
+		"indirect": tcase{
+			str: "*1",
+			errs: []string{
+				"test:1:1: invalid operation: cannot indirect 1 (int)",
+			},
+		},
+		"address of literal": tcase{
+			str: "&(1 + 2)",
+			errs: []string{
+				"test:1:1: invalid operation: cannot take address of 1 + 2 (int)",
+			},
+		},
+		"no cascade": tcase{
+			str: `("a" + 1) * 2 + y`,
+			errs: []string{
+				`test:1:2: invalid operation: "a" + 1 ` +
+					`(mismatched types string and int)`,
+				"test:1:17: undefined: y",
+			},
+		},
+		"undefined": tcase{
+			str: "a",
+			errs: []string{
+				"test:1:1: undefined: a",
+			},
+		},
+		"field": tcase{
+			str: "let a = 1\na.b",
+			errs: []string{
+				"test:2:1: a.b undefined (type int has no field b)",
+			},
+		},
+		"call": tcase{
+			str: "let a = 1\na(b)",
+			errs: []string{
+				"test:2:3: undefined: b",
+				"test:2:1: invalid operation: cannot call non-function a (int)",
+			},
+		},
+		"func": tcase{
+			str: "fn(a) { a }",
+			typ: "fn(t1) unit",
+		},
+		"func result": tcase{
+			str: "fn(a, b) { return a + 1 == 2 && b }",
+			typ: "fn(int, bool) bool",
+		},
+		"polymorphic let": tcase{
+			str: "let id = fn(x) { return x }\n" +
+				`let a = id(1) + 2; let b = id("s") + "t"` + "\n" +
+				"id",
+			typ: "fn(t6) t6",
+		},
+		"restricted variables": tcase{
+			str: "let sub = fn(a, b) { return a - b }\nsub(x, x)\nsub",
+			env: map[string]types.Type{"x": float},
+			typ: "fn(t6, t6) t6",
+		},
+		"restricted to one type": tcase{
+			str: "fn(a) { return a % 2 }",
+			typ: "fn(int) int",
+		},
+		"compare": tcase{
+			str: "fn(a, b) { return a < b }",
+			typ: "fn(t2, t2) bool",
+		},
+		"higher order": tcase{
+			str: "let compose = fn(f, g) { return fn(x) { return f(g(x)) } }\n" +
+				"compose",
+			typ: "fn(fn(t11) t12, fn(t13) t11) fn(t13) t12",
+		},
+		"curried": tcase{
+			str: "let add = fn(a) { return fn(b) { return a + b } }\nadd(1)(2)",
+			typ: "int",
+		},
+		"unknown callee": tcase{
+			str: "fn(x) { let y = x; return y(1) }",
+			typ: "fn(fn(int) t4) t4",
+		},
+		"recursion": tcase{
+			str: "let f = fn(n) { return f(n - 1) }\nf",
+			typ: "fn(int) t6",
+		},
+		"pointer param": tcase{
+			str: "fn(p) { return (*p) + 1 }",
+			typ: "fn(*int) int",
+		},
+		"bare return": tcase{
+			str: "fn() { return }",
+			typ: "fn() unit",
+		},
+		"argument mismatch": tcase{
+			str: "let f = fn(x) { return x + 1 }\n" + `f("a")`,
+			errs: []string{
+				`test:2:3: cannot use "a" (string) as int value in argument to f` +
+					"\n\tx is int because of x + 1 at test:1:24",
+			},
+		},
+		"argument not in class": tcase{
+			str: "let neg = fn(a) { return -a }\n" + `neg("x")`,
+			errs: []string{
+				`test:2:5: cannot use "x" (string) as numeric value in argument to neg` +
+					"\n\ta of neg at test:2:1 is numeric because of -a at test:1:26",
+			},
+		},
+		"instantiations": tcase{
+			str: "let id = fn(x) { return x }\n" + `id(1) + id("s")`,
+			errs: []string{
+				`test:2:1: invalid operation: id(1) + id("s") (mismatched types int and string)` +
+					"\n\tx of id at test:2:1 is int because of 1 at test:2:4" +
+					"\n\tx of id at test:2:9 is string because of \"s\" at test:2:12",
+			},
+		},
+		"conflicting uses": tcase{
+			str: `fn(a) { let b = a + "x"; return a - 1 }`,
+			errs: []string{
+				"test:1:33: invalid operation: a - 1 (mismatched types string and int)" +
+					"\n\ta is string because of a + \"x\" at test:1:17",
+			},
+		},
+		"conflicting classes": tcase{
+			str: "fn(a) { return a && (a - 1) }",
+			errs: []string{
+				"test:1:16: invalid operation: operator && not defined on a (int)" +
+					"\n\ta is int because of a - 1 at test:1:22",
+			},
+		},
+		"occurs check": tcase{
+			str: "fn(f) { return f(f) }",
+			errs: []string{
+				"test:1:18: cannot use f (fn(t3) t4) as t3 value in argument to f " +
+					"(infinite type t3 = fn(t3) t4)",
+			},
+		},
+		"wrong number of arguments": tcase{
+			str: "let f = fn() {}\nf(1)",
+			errs: []string{
+				"test:2:1: wrong number of arguments in call to f: have 1, want 0",
+			},
+		},
+		"conflicting returns": tcase{
+			str: `fn(x) { return 1; return "a" }`,
+			errs: []string{
+				`test:1:26: cannot use "a" (string) as int value in return statement`,
+			},
+		},
+		"missing return value": tcase{
+			str: "fn() { return 1; return }",
+			errs: []string{
+				"test:1:18: missing return value, want int",
+			},
+		},
+		"recursive use": tcase{
+			str: `let f = fn(x) { return f("a") + x + 1 }`,
+			errs: []string{
+				"test:1:5: invalid recursive use of f " +
+					"(used as fn(string) int, defined as fn(int) int)" +
+					"\n\tx is int because of f(\"a\") + x + 1 at test:1:24",
+			},
+		},
+		"params are monomorphic": tcase{
+			str: "fn(f) { let a = f(1); return f(true) }",
+			errs: []string{
+				"test:1:32: cannot use true (bool) as int value in argument to f",
+			},
+		},
+	}
+
+	for k, v := range tcases {
+		t.Run(k, fn(v))
+	}
+}
+
+func TestCheckDiagnostics(t *testing.T) {
+	type tcase struct {
+		str string
+		d   *diag.Diagnostic
+	}
+
+	fi := func(line, col int64) parser.FileInfo {
+		return parser.FileInfo{Name: "test", Line: line, Col: col}
+	}
+
+	span := func(line, col, endLine, endCol int64) diag.Span {
+		return diag.Span{Start: fi(line, col), End: fi(endLine, endCol)}
+	}
+
+	fn := func(tc tcase) func(t *testing.T) {
+		return func(t *testing.T) {
+			f, err := ast.ParseFile("test", tc.str)
+			assert.Eq(t, nil, err)
+
+			_, err = types.Check(f, nil)
+			assert.Eq(t, diag.List{tc.d}, err)
+		}
+	}
+
+	tcases := map[string]tcase{
+		"binary": tcase{
+			str: `"a" + 1`,
+			d: &diag.Diagnostic{
+				Span:    span(1, 1, 1, 8),
+				Message: `invalid operation: "a" + 1 (mismatched types string and int)`,
+			},
+		},
+		"notes": tcase{
+			str: "let f = fn(x) { return x + 1 }\n" + `f("a")`,
+			d: &diag.Diagnostic{
+				Span:    span(2, 3, 2, 6),
+				Message: `cannot use "a" (string) as int value in argument to f`,
+				Notes: []diag.Note{{
+					Span:    span(1, 24, 1, 29),
+					Message: "x is int because of x + 1",
+				}},
+			},
+		},
+		"undefined": tcase{
+			str: "let count = 1\ncout",
+			d: &diag.Diagnostic{
+				Span:    span(2, 1, 2, 5),
+				Message: "undefined: cout",
+				Suggestions: []diag.Suggestion{{
+					Span:    span(2, 1, 2, 5),
+					Message: "did you mean count?",
+					Text:    "count",
+				}},
+			},
+		},
+	}
+
+	for k, v := range tcases {
+		t.Run(k, fn(v))
+	}
+}
+
+func TestCheckTypes(t *testing.T) {
+	f, err := ast.ParseFile("test", "(1 + 2) < 3")
+	assert.Eq(t, nil, err)
+
+	m, err := types.Check(f, nil)
+	assert.Eq(t, nil, err)
+
+	cmp := f.Stmts[0].(*ast.ExprStmt).X.(*ast.BinaryExpr)
+	add := cmp.Left.(*ast.BinaryExpr)
+
+	assert.Eq(t, types.Typ[types.Bool], m[cmp])
+	assert.Eq(t, types.Typ[types.Int], m[add])
+	assert.Eq(t, types.Typ[types.Int], m[add.Left.(ast.Node)])
+	assert.Eq(t, types.Typ[types.Int], m[cmp.Right.(ast.Node)])
+	assert.Eq(t, 5, len(m))
+}
+
+func TestIdentical(t *testing.T) {
+	type tcase struct {
+		a, b types.Type
+		out  bool
+	}
+
+	fn := func(tc tcase) func(t *testing.T) {
+		return func(t *testing.T) {
+			assert.Eq(t, tc.out, types.Identical(tc.a, tc.b))
+		}
+	}
+
+	i, s := types.Typ[types.Int], types.Typ[types.String]
+
+	tcases := map[string]tcase{
+		"basic": tcase{
+			a:   i,
+			b:   i,
+			out: true,
+		},
+		"different basic": tcase{
+			a: i,
+			b: s,
+		},
+		"pointers": tcase{
+			a:   &types.Pointer{Elem: i},
+			b:   &types.Pointer{Elem: i},
+			out: true,
+		},
+		"different pointers": tcase{
+			a: &types.Pointer{Elem: i},
+			b: &types.Pointer{Elem: s},
+		},
+		"pointer and basic": tcase{
+			a: &types.Pointer{Elem: i},
+			b: i,
+		},
+	}
+
+	for k, v := range tcases {
+		t.Run(k, fn(v))
+	}
+}
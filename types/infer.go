@@ -0,0 +1,357 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ear7h/lang/ast"
+	"github.com/ear7h/lang/diag"
+)
+
+// scheme is a type which is polymorphic in vars, each use of a let
+// binding instantiates the variables with new ones
+type scheme struct {
+	vars []*Var
+	t    Type
+}
+
+// unifyError is a failed unification, the caller reports it in the
+// terms of the expression it was checking
+type unifyError struct {
+	msg   string      // what went wrong beyond the types, or empty
+	notes []diag.Note // where the conflicting types came from
+}
+
+func (c *checker) fresh(from ast.Node) *Var {
+	c.vars++
+	return &Var{id: c.vars, from: from}
+}
+
+// unify makes a and b the same type, binding their variables. The
+// expression at needs them to be the same.
+func (c *checker) unify(at ast.Node, a, b Type) *unifyError {
+	pa, pb := prune(a), prune(b)
+	if isInvalid(pa) || isInvalid(pb) {
+		return nil
+	}
+
+	if v, ok := pa.(*Var); ok {
+		return c.bind(at, v, pb)
+	}
+
+	if v, ok := pb.(*Var); ok {
+		return c.bind(at, v, pa)
+	}
+
+	switch pa := pa.(type) {
+	case *Basic:
+		if pb, ok := pb.(*Basic); ok && pa.Kind == pb.Kind {
+			return nil
+		}
+	case *Pointer:
+		if pb, ok := pb.(*Pointer); ok {
+			return c.unify(at, pa.Elem, pb.Elem)
+		}
+	case *Func:
+		pb, ok := pb.(*Func)
+		if !ok || len(pa.Params) != len(pb.Params) {
+			break
+		}
+
+		for i := range pa.Params {
+			if err := c.unify(at, pa.Params[i], pb.Params[i]); err != nil {
+				return err
+			}
+		}
+
+		return c.unify(at, pa.Result, pb.Result)
+	}
+
+	return &unifyError{notes: append(boundNotes(a), boundNotes(b)...)}
+}
+
+// bind binds the free variable v to t
+func (c *checker) bind(at ast.Node, v *Var, t Type) *unifyError {
+	if w, ok := t.(*Var); ok {
+		if w == v {
+			return nil
+		}
+
+		cl, ok := intersect(v.class, w.class)
+		if !ok {
+			return &unifyError{
+				notes: append(classNotes(v), classNotes(w)...),
+			}
+		}
+
+		if cl != w.class {
+			w.class = cl
+			w.restricted = at
+			if v.class == cl {
+				w.restricted = v.restricted
+			}
+		}
+
+		v.inst, v.bound = w, at
+		settle(w)
+
+		return nil
+	}
+
+	if occurs(v, t) {
+		return &unifyError{
+			msg: fmt.Sprintf("infinite type %s = %s", v, resolve(t)),
+		}
+	}
+
+	if v.class != nil {
+		b, ok := t.(*Basic)
+		if !ok || !v.class.has(b.Kind) {
+			return &unifyError{notes: classNotes(v)}
+		}
+	}
+
+	v.inst, v.bound = t, at
+
+	return nil
+}
+
+// restrict restricts t to the class cl, for the expression at
+func (c *checker) restrict(at ast.Node, t Type, cl *class) *unifyError {
+	switch pt := prune(t).(type) {
+	case *Var:
+		next, ok := intersect(pt.class, cl)
+		if !ok {
+			return &unifyError{notes: classNotes(pt)}
+		}
+
+		if next != pt.class {
+			pt.class, pt.restricted = next, at
+		}
+
+		settle(pt)
+
+		return nil
+	case *Basic:
+		if pt.Kind == Invalid || cl.has(pt.Kind) {
+			return nil
+		}
+	}
+
+	return &unifyError{notes: boundNotes(t)}
+}
+
+// settle binds the free variable v to the only type its class allows,
+// if there is one
+func settle(v *Var) {
+	if v.class != nil && len(v.class.kinds) == 1 {
+		v.inst, v.bound = Typ[v.class.kinds[0]], v.restricted
+	}
+}
+
+// intersect returns the types in both a and b, a nil class is all the
+// types. It's not ok if there are none.
+func intersect(a, b *class) (*class, bool) {
+	if a == nil || b == nil {
+		if a == nil {
+			return b, true
+		}
+
+		return a, true
+	}
+
+	var kinds []BasicKind
+	var names []string
+	for _, v := range a.kinds {
+		if b.has(v) {
+			kinds = append(kinds, v)
+			names = append(names, Typ[v].Name)
+		}
+	}
+
+	switch len(kinds) {
+	case 0:
+		return nil, false
+	case len(a.kinds):
+		return a, true
+	case len(b.kinds):
+		return b, true
+	}
+
+	return &class{strings.Join(names, " or "), kinds}, true
+}
+
+func occurs(v *Var, t Type) bool {
+	switch t := prune(t).(type) {
+	case *Var:
+		return t == v
+	case *Pointer:
+		return occurs(v, t.Elem)
+	case *Func:
+		for _, p := range t.Params {
+			if occurs(v, p) {
+				return true
+			}
+		}
+
+		return occurs(v, t.Result)
+	}
+
+	return false
+}
+
+// boundNotes explains where the type t came from, if it is a variable
+// bound by an expression
+func boundNotes(t Type) []diag.Note {
+	v, ok := t.(*Var)
+	if !ok || v.inst == nil || v.from == nil {
+		return nil
+	}
+
+	last := v
+	for {
+		next, ok := last.inst.(*Var)
+		if !ok || next.inst == nil {
+			break
+		}
+
+		last = next
+	}
+
+	if last.bound == nil || last.bound == v.from {
+		return nil
+	}
+
+	return []diag.Note{{
+		Span: diag.NodeSpan(last.bound),
+		Message: fmt.Sprintf("%s is %s because of %s",
+			subject(v), resolve(v), exprString(last.bound)),
+	}}
+}
+
+// classNotes explains why the free variable v is restricted
+func classNotes(v *Var) []diag.Note {
+	if v.class == nil || v.from == nil || v.restricted == nil {
+		return nil
+	}
+
+	return []diag.Note{{
+		Span: diag.NodeSpan(v.restricted),
+		Message: fmt.Sprintf("%s is %s because of %s",
+			subject(v), v.class.name, exprString(v.restricted)),
+	}}
+}
+
+// subject returns what v is the type of for notes, a variable of a
+// generic let binding is named along with the use it was instantiated
+// for, since each use may have a different type
+func subject(v *Var) string {
+	if v.use == nil {
+		return exprString(v.from)
+	}
+
+	return fmt.Sprintf("%s of %s at %v", exprString(v.from),
+		exprString(v.use), v.use.FileInfo())
+}
+
+// display returns t for error messages, free variables restricted to a
+// class are shown as the class
+func display(t Type) string {
+	if v, ok := prune(t).(*Var); ok && v.class != nil {
+		return v.class.name
+	}
+
+	return resolve(t).String()
+}
+
+// instantiate returns the type of s for the use of its let binding, with
+// new variables in place of those s is polymorphic in
+func (c *checker) instantiate(s *scheme, use ast.Node) Type {
+	if len(s.vars) == 0 {
+		return s.t
+	}
+
+	m := map[*Var]Type{}
+	for _, v := range s.vars {
+		w := c.fresh(v.from)
+		w.class, w.restricted, w.use = v.class, v.restricted, use
+		m[v] = w
+	}
+
+	return subst(s.t, m)
+}
+
+func subst(t Type, m map[*Var]Type) Type {
+	switch t := prune(t).(type) {
+	case *Var:
+		if w, ok := m[t]; ok {
+			return w
+		}
+
+		return t
+	case *Pointer:
+		return &Pointer{Elem: subst(t.Elem, m)}
+	case *Func:
+		params := make([]Type, len(t.Params))
+		for i, v := range t.Params {
+			params[i] = subst(v, m)
+		}
+
+		return &Func{Params: params, Result: subst(t.Result, m)}
+	default:
+		return t
+	}
+}
+
+// generalize returns the scheme of t, polymorphic in the variables of t
+// which are free in none of the scopes
+func (c *checker) generalize(t Type) *scheme {
+	env := map[*Var]bool{}
+	for s := c.scope; s != nil; s = s.parent {
+		for _, v := range s.names {
+			bound := map[*Var]bool{}
+			for _, w := range v.vars {
+				bound[w] = true
+			}
+
+			for _, w := range freeVars(v.t, nil) {
+				if !bound[w] {
+					env[w] = true
+				}
+			}
+		}
+	}
+
+	var vars []*Var
+	for _, v := range freeVars(t, nil) {
+		if !env[v] {
+			vars = append(vars, v)
+		}
+	}
+
+	return &scheme{vars: vars, t: t}
+}
+
+// freeVars appends the free variables of t to vars, once each
+func freeVars(t Type, vars []*Var) []*Var {
+	switch t := prune(t).(type) {
+	case *Var:
+		for _, v := range vars {
+			if v == t {
+				return vars
+			}
+		}
+
+		return append(vars, t)
+	case *Pointer:
+		return freeVars(t.Elem, vars)
+	case *Func:
+		for _, v := range t.Params {
+			vars = freeVars(v, vars)
+		}
+
+		return freeVars(t.Result, vars)
+	}
+
+	return vars
+}
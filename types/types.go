@@ -0,0 +1,209 @@
+// Package types infers and checks the types of syntax trees.
+//
+// The basic types are int, float, string and bool, and unit is the
+// result of functions which return nothing. Number literals are ints,
+// string literals strings, and true and false bools, the types of other
+// free identifiers come from the environment passed to Check.
+// There are no conversions between the basic types, the operands of a
+// binary operator have the same type.
+//
+// The types of function parameters and of let bindings are inferred,
+// Hindley-Milner style: unknown types are type variables which are
+// bound by unification as the tree is checked. The type of a let is
+// generalized over the variables the enclosing scopes don't mention, so
+// let id = fn(x) { return x } can be used at any type. The operands of
+// overloaded operators get variables restricted to the types the
+// operator is defined on, like int or float for -.
+package types
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ear7h/lang/ast"
+)
+
+// Type is the type of an expression
+type Type interface {
+	String() string
+}
+
+// BasicKind is the kind of a basic type
+type BasicKind int
+
+const (
+	Invalid BasicKind = iota // the type of expressions with errors
+	Int
+	Float
+	String
+	Bool
+	Unit
+)
+
+// Basic is a basic type
+type Basic struct {
+	Kind BasicKind
+	Name string
+}
+
+func (t *Basic) String() string {
+	return t.Name
+}
+
+// Typ are the basic types, by kind
+var Typ = []*Basic{
+	Invalid: {Invalid, "invalid type"},
+	Int:     {Int, "int"},
+	Float:   {Float, "float"},
+	String:  {String, "string"},
+	Bool:    {Bool, "bool"},
+	Unit:    {Unit, "unit"},
+}
+
+// Pointer is the type of a pointer to Elem
+type Pointer struct {
+	Elem Type
+}
+
+func (t *Pointer) String() string {
+	return "*" + t.Elem.String()
+}
+
+// Func is the type of a function
+type Func struct {
+	Params []Type
+	Result Type
+}
+
+func (t *Func) String() string {
+	params := make([]string, len(t.Params))
+	for i, v := range t.Params {
+		params[i] = v.String()
+	}
+
+	return "fn(" + strings.Join(params, ", ") + ") " + t.Result.String()
+}
+
+// Var is a type variable, a type which is not known yet. Once a
+// variable is bound to a type it stands for that type.
+type Var struct {
+	id    int
+	class *class   // the types the variable may be bound to, nil for any
+	inst  Type     // the type the variable is bound to
+	from  ast.Node // the expression the variable is the type of, or nil
+	use   ast.Node // the use of the let binding it was instantiated for
+
+	// the expressions which bound or restricted the variable, for
+	// error messages
+	bound, restricted ast.Node
+}
+
+func (t *Var) String() string {
+	if t.inst != nil {
+		return t.inst.String()
+	}
+
+	return fmt.Sprintf("t%d", t.id)
+}
+
+// class is a set of basic types
+type class struct {
+	name  string
+	kinds []BasicKind
+}
+
+func (cl *class) has(kind BasicKind) bool {
+	for _, v := range cl.kinds {
+		if v == kind {
+			return true
+		}
+	}
+
+	return false
+}
+
+var (
+	numeric  = &class{"numeric", []BasicKind{Int, Float}}
+	ordered  = &class{"int, float or string", []BasicKind{Int, Float, String}}
+	intOnly  = &class{"int", []BasicKind{Int}}
+	boolOnly = &class{"bool", []BasicKind{Bool}}
+)
+
+// Identical reports whether a and b are the same type
+func Identical(a, b Type) bool {
+	a, b = prune(a), prune(b)
+
+	switch a := a.(type) {
+	case *Basic:
+		b, ok := b.(*Basic)
+		return ok && a.Kind == b.Kind
+	case *Pointer:
+		b, ok := b.(*Pointer)
+		return ok && Identical(a.Elem, b.Elem)
+	case *Func:
+		b, ok := b.(*Func)
+		if !ok || len(a.Params) != len(b.Params) {
+			return false
+		}
+
+		for i := range a.Params {
+			if !Identical(a.Params[i], b.Params[i]) {
+				return false
+			}
+		}
+
+		return Identical(a.Result, b.Result)
+	case *Var:
+		return a == b
+	}
+
+	return false
+}
+
+// prune returns the type t stands for, following bound variables
+func prune(t Type) Type {
+	for {
+		v, ok := t.(*Var)
+		if !ok || v.inst == nil {
+			return t
+		}
+
+		t = v.inst
+	}
+}
+
+// resolve returns t with the bound variables replaced by their types
+func resolve(t Type) Type {
+	switch t := prune(t).(type) {
+	case *Pointer:
+		return &Pointer{Elem: resolve(t.Elem)}
+	case *Func:
+		params := make([]Type, len(t.Params))
+		for i, v := range t.Params {
+			params[i] = resolve(v)
+		}
+
+		return &Func{Params: params, Result: resolve(t.Result)}
+	default:
+		return t
+	}
+}
+
+func isBasic(t Type, kinds ...BasicKind) bool {
+	b, ok := prune(t).(*Basic)
+	if !ok {
+		return false
+	}
+
+	for _, v := range kinds {
+		if b.Kind == v {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isInvalid(t Type) bool {
+	return isBasic(t, Invalid)
+}
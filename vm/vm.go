@@ -0,0 +1,310 @@
+// Package vm runs the bytecode of the compile package on a stack
+// machine.
+//
+// Values are those of the eval package, with *Closure for compiled
+// functions, and programs give the same results and errors as they do
+// with eval.Eval.
+package vm
+
+import (
+	"fmt"
+
+	"github.com/ear7h/lang/compile"
+	"github.com/ear7h/lang/diag"
+	"github.com/ear7h/lang/eval"
+)
+
+// MaxDepth is the limit of nested calls
+const MaxDepth = eval.MaxDepth
+
+// Closure is a compiled function with the values of the variables it
+// captured
+type Closure struct {
+	Func     *compile.Func
+	Captures []interface{}
+}
+
+// TypeName implements eval.TypeNamer
+func (*Closure) TypeName() string {
+	return "func"
+}
+
+func (*Closure) String() string {
+	return "func"
+}
+
+type frame struct {
+	cl *Closure
+	pc int
+	// base is the index of the first local slot in the stack, the
+	// closure is just below it
+	base int
+}
+
+// Run runs the top level function of a program, fn, with its globals
+// in env, or in a new environment nested in eval.Universe if env is nil.
+// The error is a *diag.Diagnostic about the source of the instruction
+// which failed.
+func Run(fn *compile.Func, env *eval.Env) (interface{}, error) {
+	if env == nil {
+		env = eval.NewEnv(eval.Universe)
+	}
+
+	m := &machine{
+		env:   env,
+		stack: make([]interface{}, 0, 64),
+	}
+
+	return m.run(&Closure{Func: fn})
+}
+
+type machine struct {
+	env    *eval.Env
+	stack  []interface{}
+	frames []frame
+}
+
+func (m *machine) push(v interface{}) {
+	m.stack = append(m.stack, v)
+}
+
+func (m *machine) pop() interface{} {
+	v := m.stack[len(m.stack)-1]
+	m.stack = m.stack[:len(m.stack)-1]
+	return v
+}
+
+// enter pushes a frame for cl, whose arguments are the top of the stack
+func (m *machine) enter(cl *Closure) {
+	fn := cl.Func
+	base := len(m.stack) - fn.Params
+
+	for i := fn.Params; i < fn.Locals; i++ {
+		m.push(nil)
+	}
+
+	if fn.Self >= 0 {
+		m.stack[base+fn.Self] = cl
+	}
+
+	m.frames = append(m.frames, frame{cl: cl, base: base})
+}
+
+func (f *frame) errorf(format string, args ...interface{}) error {
+	return &diag.Diagnostic{
+		Span:    f.cl.Func.Span(f.pc),
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+func (m *machine) run(cl *Closure) (interface{}, error) {
+	m.push(cl)
+	m.enter(cl)
+
+	f := &m.frames[0]
+	fn := f.cl.Func
+	code := fn.Code
+
+	for {
+		op := compile.Op(code[f.pc])
+		a := 0
+		if op.Size() > 1 {
+			a = int(code[f.pc+1])<<8 | int(code[f.pc+2])
+		}
+
+		switch op {
+		case compile.OpConst:
+			m.push(fn.Consts[a])
+		case compile.OpUnit:
+			m.push(nil)
+		case compile.OpPop:
+			m.pop()
+		case compile.OpLocal:
+			m.push(m.stack[f.base+a])
+		case compile.OpSetLocal:
+			m.stack[f.base+a] = m.pop()
+		case compile.OpCapture:
+			m.push(f.cl.Captures[a])
+		case compile.OpGlobal:
+			name := fn.Consts[a].(string)
+			v, ok := m.env.Lookup(name)
+			if !ok {
+				return nil, f.errorf("%v", eval.UndefinedError(name))
+			}
+
+			m.push(v)
+		case compile.OpSetGlobal:
+			m.env.Define(fn.Consts[a].(string), m.pop())
+		case compile.OpClosure:
+			inner := fn.Funcs[a]
+			caps := make([]interface{}, len(inner.Captures))
+			for i, v := range inner.Captures {
+				if v.Local {
+					caps[i] = m.stack[f.base+v.Index]
+				} else {
+					caps[i] = f.cl.Captures[v.Index]
+				}
+			}
+
+			m.push(&Closure{Func: inner, Captures: caps})
+
+		case compile.OpPos, compile.OpNeg, compile.OpNot, compile.OpAddr,
+			compile.OpDeref:
+
+			top := len(m.stack) - 1
+			v, err := eval.UnaryOp(rune(op.Operator()[0]), m.stack[top])
+			if err != nil {
+				return nil, f.errorf("%v", err)
+			}
+
+			m.stack[top] = v
+
+		case compile.OpAdd, compile.OpSub, compile.OpMul, compile.OpDiv,
+			compile.OpMod, compile.OpShl, compile.OpShr, compile.OpAnd,
+			compile.OpOr, compile.OpXor, compile.OpLt, compile.OpGt,
+			compile.OpLte, compile.OpGte, compile.OpEq, compile.OpNeq:
+
+			top := len(m.stack) - 1
+			x, y := m.stack[top-1], m.stack[top]
+			m.stack = m.stack[:top]
+
+			v, ok := intOp(x, op, y)
+			if !ok {
+				var err error
+				v, err = eval.BinaryOp(x, op.Operator(), y)
+				if err != nil {
+					return nil, f.errorf("%v", err)
+				}
+			}
+
+			m.stack[top-1] = v
+
+		case compile.OpJumpAnd, compile.OpJumpOr:
+			b, ok := m.stack[len(m.stack)-1].(bool)
+			if !ok {
+				return nil, f.errorf("%v", eval.NotDefinedError(op.Operator(),
+					m.stack[len(m.stack)-1]))
+			}
+
+			// short circuit
+			if b == (op == compile.OpJumpOr) {
+				f.pc = a
+				continue
+			}
+
+			m.pop()
+		case compile.OpBoolAnd, compile.OpBoolOr:
+			if _, ok := m.stack[len(m.stack)-1].(bool); !ok {
+				return nil, f.errorf("%v", eval.NotDefinedError(op.Operator(),
+					m.stack[len(m.stack)-1]))
+			}
+
+		case compile.OpField:
+			top := len(m.stack) - 1
+			v, err := eval.Field(m.stack[top], fn.Consts[a].(string))
+			if err != nil {
+				return nil, f.errorf("%v", err)
+			}
+
+			m.stack[top] = v
+		case compile.OpCall:
+			callee := m.stack[len(m.stack)-1-a]
+
+			switch callee := callee.(type) {
+			case eval.GoFunc:
+				args := make([]interface{}, a)
+				copy(args, m.stack[len(m.stack)-a:])
+
+				v, err := callee(args)
+				if err != nil {
+					return nil, f.errorf("%v", err)
+				}
+
+				m.stack = m.stack[:len(m.stack)-1-a]
+				m.push(v)
+			case *Closure:
+				if callee.Func.Params != a {
+					return nil, f.errorf("%v",
+						eval.ArgumentsError(a, callee.Func.Params))
+				}
+
+				if len(m.frames) > MaxDepth {
+					return nil, f.errorf("%v", eval.ErrStackOverflow)
+				}
+
+				f.pc += op.Size()
+				m.enter(callee)
+
+				f = &m.frames[len(m.frames)-1]
+				fn = f.cl.Func
+				code = fn.Code
+				continue
+			default:
+				return nil, f.errorf("%v", eval.NotFunctionError(callee))
+			}
+
+		case compile.OpReturn:
+			v := m.pop()
+			m.stack = m.stack[:f.base-1]
+			m.frames = m.frames[:len(m.frames)-1]
+
+			if len(m.frames) == 0 {
+				return v, nil
+			}
+
+			m.push(v)
+
+			f = &m.frames[len(m.frames)-1]
+			fn = f.cl.Func
+			code = fn.Code
+			continue
+		default:
+			return nil, f.errorf("invalid instruction %s", op)
+		}
+
+		f.pc += op.Size()
+	}
+}
+
+// intOp is the fast path of binary operators on ints, ok is false if
+// either operand isn't an int or op can fail
+func intOp(x interface{}, op compile.Op, y interface{}) (interface{}, bool) {
+	a, ok := x.(int64)
+	if !ok {
+		return nil, false
+	}
+
+	b, ok := y.(int64)
+	if !ok {
+		return nil, false
+	}
+
+	switch op {
+	case compile.OpAdd:
+		return a + b, true
+	case compile.OpSub:
+		return a - b, true
+	case compile.OpMul:
+		return a * b, true
+	case compile.OpAnd:
+		return a & b, true
+	case compile.OpOr:
+		return a | b, true
+	case compile.OpXor:
+		return a ^ b, true
+	case compile.OpLt:
+		return a < b, true
+	case compile.OpGt:
+		return a > b, true
+	case compile.OpLte:
+		return a <= b, true
+	case compile.OpGte:
+		return a >= b, true
+	case compile.OpEq:
+		return a == b, true
+	case compile.OpNeq:
+		return a != b, true
+	}
+
+	return nil, false
+}
@@ -0,0 +1,388 @@
+package vm_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ear7h/lang/ast"
+	"github.com/ear7h/lang/compile"
+	"github.com/ear7h/lang/diag"
+	"github.com/ear7h/lang/eval"
+	"github.com/ear7h/lang/internal/assert"
+	"github.com/ear7h/lang/vm"
+)
+
+func TestRun(t *testing.T) {
+	type tcase struct {
+		str string
+		env map[string]interface{}
+		out interface{}
+		err string
+	}
+
+	fn := func(tc tcase) func(t *testing.T) {
+		return func(t *testing.T) {
+			f, err := ast.ParseFile("test", tc.str)
+			assert.Eq(t, nil, err)
+
+			code, err := compile.Compile(f)
+			if err != nil {
+				// the tree walker folds the same constants
+				_, evalErr := eval.Eval(f, nil)
+				assert.Eq(t, err, evalErr)
+				assert.Eq(t, tc.err, err.Error())
+				return
+			}
+
+			newEnv := func() *eval.Env {
+				env := eval.NewEnv(eval.Universe)
+				for k, v := range tc.env {
+					env.Define(k, v)
+				}
+
+				return env
+			}
+
+			out, err := vm.Run(code, newEnv())
+
+			// the tree walker agrees
+			evalOut, evalErr := eval.Eval(f, newEnv())
+			assert.Eq(t, eval.String(evalOut), eval.String(out))
+			assert.Eq(t, evalErr == nil, err == nil)
+			if err != nil {
+				assert.Eq(t, evalErr.Error(), err.Error())
+				assert.Eq(t, diag.FromError(evalErr)[0].Span,
+					diag.FromError(err)[0].Span)
+			}
+
+			if tc.err != "" {
+				if err == nil {
+					t.Fatalf("expected error %q, got %v", tc.err, out)
+				}
+
+				assert.Eq(t, tc.err, err.Error())
+				return
+			}
+
+			assert.Eq(t, nil, err)
+			assert.Eq(t, tc.out, out)
+		}
+	}
+
+	point := eval.Record{"x": int64(1), "y": int64(2)}
+
+	tcases := map[string]tcase{
+		"empty": tcase{
+			str: "",
+			out: nil,
+		},
+		"literals": tcase{
+			str: `1; "a"`,
+			out: "a",
+		},
+		"arith": tcase{
+			str: "1 + 2 * 3 - 8 / 2 % 3",
+			out: int64(6),
+		},
+		"wrap around": tcase{
+			str: "x + 1",
+			env: map[string]interface{}{"x": int64(9223372036854775807)},
+			out: int64(-9223372036854775808),
+		},
+		"folded": tcase{
+			str: "1 << 100 >> 98",
+			out: int64(4),
+		},
+		"folded overflow": tcase{
+			str: "5000000000 * 5000000000",
+			err: "test:1:1: constant 25000000000000000000 overflows int",
+		},
+		"folded shift overflow": tcase{
+			str: "1 << 1000",
+			err: "test:1:1: constant shift overflow",
+		},
+		"min int": tcase{
+			str: "0 - 9223372036854775807 - 1",
+			out: int64(-9223372036854775808),
+		},
+		"bits": tcase{
+			str: "1 << 4 | 1 ^ 3 & 6 >> 1",
+			out: int64(1),
+		},
+		"floats": tcase{
+			str: "x * x - x / (x + x)",
+			env: map[string]interface{}{"x": 1.5},
+			out: 1.75,
+		},
+		"float division by zero": tcase{
+			str: "(x / (x - x)) > x",
+			env: map[string]interface{}{"x": 1.5},
+			out: true,
+		},
+		"strings": tcase{
+			str: `"a" + "b" < "b"`,
+			out: true,
+		},
+		"unary": tcase{
+			str: "-(1 + (+2))",
+			out: int64(-3),
+		},
+		"not": tcase{
+			str: "!(1 == 2)",
+			out: true,
+		},
+		"pointers": tcase{
+			str: "let a = 1\nlet p = &a\n(*p) + 1",
+			out: int64(2),
+		},
+		"short circuit and": tcase{
+			str: "false && undefined",
+			out: false,
+		},
+		"short circuit or": tcase{
+			str: "1 < 2 || 1 / x",
+			env: map[string]interface{}{"x": int64(0)},
+			out: true,
+		},
+		"let": tcase{
+			str: "let a = 2\nlet b = a * a\nb + a",
+			out: int64(6),
+		},
+		"blocks": tcase{
+			str: "let a = 1\n{ let a = 2; a }",
+			out: int64(2),
+		},
+		"block scope": tcase{
+			str: "let a = 1\n{ let a = 2 }\na",
+			out: int64(1),
+		},
+		"top level return": tcase{
+			str: "1\nreturn 2\n3",
+			out: int64(2),
+		},
+		"return from block": tcase{
+			str: "{ return 2 }\n3",
+			out: int64(2),
+		},
+		"calls": tcase{
+			str: "let add = fn(a, b) { return a + b }\nadd(1, 2)",
+			out: int64(3),
+		},
+		"closures": tcase{
+			str: "let adder = fn(a) { return fn(b) { return a + b } }\n" +
+				"let inc = adder(1)\ninc(inc(1))",
+			out: int64(3),
+		},
+		"recursion": tcase{
+			str: "let down = fn(n) { return n < 1 || down(n - 1) }\ndown(100)",
+			out: true,
+		},
+		"unit result": tcase{
+			str: "let f = fn() { 1 }\nf()",
+			out: nil,
+		},
+		"return in nested block": tcase{
+			str: "let f = fn() { { return 1 }; return 2 }\nf()",
+			out: int64(1),
+		},
+		"fields": tcase{
+			str: "p.x + p.y",
+			env: map[string]interface{}{"p": point},
+			out: int64(3),
+		},
+		"nested fields": tcase{
+			str: "r.p.y",
+			env: map[string]interface{}{"r": eval.Record{"p": point}},
+			out: int64(2),
+		},
+		"go func": tcase{
+			str: "double(3) + 1",
+			env: map[string]interface{}{
+				"double": eval.GoFunc(func(args []interface{}) (interface{}, error) {
+					return args[0].(int64) * 2, nil
+				}),
+			},
+			out: int64(7),
+		},
+		"call result fields": tcase{
+			str: "let f = fn() { return p }\nf().x",
+			env: map[string]interface{}{"p": point},
+			out: int64(1),
+		},
+		"local recursion": tcase{
+			str: "{ let down = fn(n) { return n < 1 || down(n - 1) }; down(3) }",
+			out: true,
+		},
+		"captured recursion": tcase{
+			str: "let f = fn() {\n" +
+				"\tlet g = fn(n) { return n < 1 || fn() { return g(n - 1) }() }\n" +
+				"\treturn g\n" +
+				"}\n" +
+				"f()(3)",
+			out: true,
+		},
+		"shadowed parameter": tcase{
+			str: "{ let f = fn(f) { return f }; f(1) }",
+			out: int64(1),
+		},
+		"function equality": tcase{
+			str: "let f = fn() {}\nlet g = f\nf == g",
+			out: true,
+		},
+		"undefined": tcase{
+			str: "1 + a",
+			err: "test:1:5: undefined: a",
+		},
+		"divide by zero": tcase{
+			str: "let a = 0\n1 / a",
+			err: "test:2:1: division by zero",
+		},
+		"negative shift": tcase{
+			str: "1 << x",
+			env: map[string]interface{}{"x": int64(-1)},
+			err: "test:1:1: negative shift amount",
+		},
+		"mismatched": tcase{
+			str: `1 + "a"`,
+			err: "test:1:1: mismatched types int and string",
+		},
+		"operator not defined": tcase{
+			str: `"a" - "b"`,
+			err: "test:1:1: operator - not defined on string",
+		},
+		"float mod": tcase{
+			str: "x % x",
+			env: map[string]interface{}{"x": 1.5},
+			err: "test:1:1: operator % not defined on float",
+		},
+		"unary not defined": tcase{
+			str: `-"a"`,
+			err: "test:1:1: operator - not defined on string",
+		},
+		"bool operands": tcase{
+			str: "true && 1",
+			err: "test:1:1: operator && not defined on int",
+		},
+		"indirect": tcase{
+			str: "*1",
+			err: "test:1:1: invalid indirect of int",
+		},
+		"not a record": tcase{
+			str: "let a = 1\na.b",
+			err: "test:2:1: int has no field b",
+		},
+		"missing field": tcase{
+			str: "p.z",
+			env: map[string]interface{}{"p": point},
+			err: "test:1:1: record has no field z",
+		},
+		"record equality": tcase{
+			str: "p == p",
+			env: map[string]interface{}{"p": point},
+			err: "test:1:1: record values cannot be compared",
+		},
+		"not a function": tcase{
+			str: "let a = 1\na(1)",
+			err: "test:2:1: cannot call non-function int",
+		},
+		"calling a result": tcase{
+			str: "let f = fn(a) { return a }\nf(1)(2)",
+			err: "test:2:5: cannot call non-function int",
+		},
+		"arity": tcase{
+			str: "let f = fn(a) { return a }\nf(1, 2)",
+			err: "test:2:1: wrong number of arguments: have 2, want 1",
+		},
+		"go func error": tcase{
+			str: "\nfail()",
+			env: map[string]interface{}{
+				"fail": eval.GoFunc(func(args []interface{}) (interface{}, error) {
+					return nil, errors.New("failed")
+				}),
+			},
+			err: "test:2:1: failed",
+		},
+		"error in function": tcase{
+			str: "let f = fn(a) {\n\treturn a / 0\n}\nf(1)",
+			err: "test:2:9: division by zero",
+		},
+		"stack overflow": tcase{
+			str: "let f = fn(n) { return f(n + 1) }\nf(0)",
+			err: "test:1:24: stack overflow",
+		},
+	}
+
+	for k, v := range tcases {
+		t.Run(k, fn(v))
+	}
+}
+
+// programs heavy on binary expressions, for comparing the vm to the tree
+// walker
+var benchmarks = []struct {
+	name string
+	str  string
+}{
+	{
+		name: "arith",
+		str: "let x = 7\n" +
+			"let y = (x * x + x * 2 - 1) * (x + 7) / 3 % 1000 ^ (x << 4 | x & 5)\n" +
+			"let z = (y - x) * (y + x) / (x * x + 1) + (y >> 2) - (x << 3)\n" +
+			"(z * y + x) % 65521 == (y * z + x) % 65521 && z > y || x < 0",
+	},
+	{
+		name: "recursion",
+		str: "let f = fn(n, acc) {\n" +
+			"\treturn n < 1 && acc != 0 || f(n - 1, (acc * 31 + n) % 1000003 ^ n << 2)\n" +
+			"}\n" +
+			"f(1000, 1)",
+	},
+	{
+		name: "closures",
+		str: "let poly = fn(a, b) { return fn(x) { return (a * x + b) * x - a % 7 } }\n" +
+			"let p = poly(3, 2)\n" +
+			"let f = fn(n, acc) { return n < 1 || f(n - 1, p(acc % 1024) + n) }\n" +
+			"f(1000, 1)",
+	},
+}
+
+func BenchmarkRun(b *testing.B) {
+	for _, v := range benchmarks {
+		f, err := ast.ParseFile("bench", v.str)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		code, err := compile.Compile(f)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		b.Run(v.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_, err := vm.Run(code, nil)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkEval(b *testing.B) {
+	for _, v := range benchmarks {
+		f, err := ast.ParseFile("bench", v.str)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		b.Run(v.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_, err := eval.Eval(f, nil)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}